@@ -0,0 +1,113 @@
+"use client";
+
+import { useState } from "react";
+import Wrap from "@/components/wrap";
+
+const AUTH_BASE =
+  process.env.NEXT_PUBLIC_AUTH_URL?.replace(/\/$/, "") ??
+  "http://localhost:8083";
+
+export default function ForgotPasswordPage() {
+  const [email, setEmail] = useState("");
+  const [loading, setLoading] = useState(false);
+  const [msg, setMsg] = useState<{ kind: "error" | "ok"; text: string } | null>(
+    null
+  );
+
+  async function handleSubmit(e: React.FormEvent) {
+    e.preventDefault();
+    setMsg(null);
+
+    const trimmedEmail = email.trim();
+    if (!trimmedEmail) {
+      setMsg({ kind: "error", text: "Email je obavezan." });
+      return;
+    }
+
+    setLoading(true);
+    try {
+      const res = await fetch(`${AUTH_BASE}/api/v1/auth/password/forgot`, {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ email: trimmedEmail }),
+      });
+
+      if (!res.ok) {
+        const data = (await res.json().catch(() => ({}))) as { error?: string };
+        setMsg({ kind: "error", text: data?.error || "Zahtjev nije uspio." });
+        return;
+      }
+
+      setMsg({
+        kind: "ok",
+        text: "Ako nalog postoji, link za promjenu lozinke je poslat na email.",
+      });
+    } catch {
+      setMsg({ kind: "error", text: "Greška u mreži. Pokušaj ponovo." });
+    } finally {
+      setLoading(false);
+    }
+  }
+
+  return (
+    <Wrap withGoBack={false}>
+      <div className="flex items-center min-h-[70vh] justify-center !bg-gray-600 p-6">
+        <div className="w-full max-w-md bg-gray-50 rounded-2xl shadow-xl p-8">
+          <h1 className="text-3xl font-bold text-gray-900 mb-6 text-center">
+            Zaboravljena lozinka
+          </h1>
+
+          <form onSubmit={handleSubmit} className="space-y-5">
+            <div>
+              <label
+                className="block text-sm font-medium text-gray-700 mb-1"
+                htmlFor="email"
+              >
+                Email
+              </label>
+              <input
+                id="email"
+                type="email"
+                autoComplete="email"
+                className="text-black w-full rounded-xl border border-gray-300 px-4 py-2 focus:border-blue-500 focus:ring-1 focus:ring-blue-500 outline-none transition"
+                value={email}
+                onChange={(e) => setEmail(e.target.value)}
+                disabled={loading}
+                required
+              />
+            </div>
+
+            {msg && (
+              <div
+                className={`rounded-lg px-4 py-2 text-sm font-medium ${
+                  msg.kind === "error"
+                    ? "bg-red-50 text-red-700 border border-red-100"
+                    : "bg-green-50 text-green-700 border border-green-100"
+                }`}
+              >
+                {msg.text}
+              </div>
+            )}
+
+            <button
+              type="submit"
+              disabled={loading}
+              className="w-full rounded-xl bg-gradient-to-r from-blue-600 to-blue-500 px-4 py-2 text-white font-semibold hover:from-blue-700 hover:to-blue-600 transition disabled:opacity-60"
+            >
+              {loading ? "Slanje…" : "Pošalji link"}
+            </button>
+          </form>
+
+          <p className="mt-6 text-center text-gray-600 text-sm">
+            <a
+              href="/auth/login"
+              className="text-blue-600 font-medium underline hover:text-blue-800"
+            >
+              Nazad na prijavu
+            </a>
+          </p>
+        </div>
+      </div>
+    </Wrap>
+  );
+}
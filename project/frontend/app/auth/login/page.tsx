@@ -1,14 +1,26 @@
 "use client";
 
-import { useState } from "react";
+import { useEffect, useState } from "react";
 import { useRouter } from "next/navigation";
 import Wrap from "@/components/wrap";
+import { storeSession } from "@/utils/auth";
 
 type LoginResponse = {
   token: string;
+  refreshToken: string;
   user: { id: string; fullName: string; email: string; role: string };
+  recoveryCodes?: string[];
 };
 
+type MfaChallenge = {
+  mfaRequired: true;
+  enrollmentRequired: boolean;
+  challengeToken: string;
+};
+
+// state zapocete SSO prijave, provjerava se po povratku sa providera
+const OIDC_STATE_KEY = "oidc_state";
+
 const AUTH_BASE =
   process.env.NEXT_PUBLIC_AUTH_URL?.replace(/\/$/, "") ??
   "http://localhost:8083";
@@ -22,24 +34,159 @@ export default function LoginPage() {
   const [msg, setMsg] = useState<{ kind: "error" | "ok"; text: string } | null>(
     null
   );
+  const [challenge, setChallenge] = useState<MfaChallenge | null>(null);
+  const [enrollment, setEnrollment] = useState<{
+    secret: string;
+    otpauthUri: string;
+  } | null>(null);
+  const [code, setCode] = useState("");
+  const [useRecovery, setUseRecovery] = useState(false);
+  const [session, setSession] = useState<LoginResponse | null>(null);
+  const [unverified, setUnverified] = useState(false);
+  const [ssoEnabled, setSsoEnabled] = useState(false);
 
-  async function handleSubmit(e: React.FormEvent) {
+  useEffect(() => {
+    fetch(`${AUTH_BASE}/api/v1/auth/oidc`)
+      .then((res) => (res.ok ? res.json() : null))
+      .then((data) => setSsoEnabled(!!data?.enabled))
+      .catch(() => setSsoEnabled(false));
+
+    // povratak sa SSO providera: ?code=&state= (ili ?error=)
+    const params = new URLSearchParams(window.location.search);
+    const code = params.get("code");
+    const state = params.get("state");
+    const error = params.get("error");
+    if (!code && !error) return;
+
+    window.history.replaceState(null, "", window.location.pathname);
+    const expected = sessionStorage.getItem(OIDC_STATE_KEY);
+    sessionStorage.removeItem(OIDC_STATE_KEY);
+
+    if (error || !code || !state || state !== expected) {
+      setMsg({
+        kind: "error",
+        text: "Prijava preko fakultetskog naloga nije uspela.",
+      });
+      return;
+    }
+    completeSso(code, state);
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, []);
+
+  async function startSso() {
+    setMsg(null);
+    setLoading(true);
+    try {
+      const res = await fetch(`${AUTH_BASE}/api/v1/auth/oidc/start`, {
+        method: "POST",
+      });
+      const data = await res.json().catch(() => ({}));
+      if (!res.ok || !data?.authorizationUrl) {
+        setMsg({
+          kind: "error",
+          text: data?.error || "Prijava preko fakultetskog naloga nije dostupna.",
+        });
+        setLoading(false);
+        return;
+      }
+      sessionStorage.setItem(OIDC_STATE_KEY, data.state);
+      window.location.assign(data.authorizationUrl);
+    } catch {
+      setMsg({ kind: "error", text: "Greška u mreži. Pokušaj ponovo." });
+      setLoading(false);
+    }
+  }
+
+  async function completeSso(code: string, state: string) {
+    setLoading(true);
+    try {
+      const res = await fetch(`${AUTH_BASE}/api/v1/auth/oidc/callback`, {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ code, state }),
+      });
+      await handleLoginResponse(res);
+    } catch {
+      setMsg({ kind: "error", text: "Greška u mreži. Pokušaj ponovo." });
+    } finally {
+      setLoading(false);
+    }
+  }
+
+  async function resendVerification() {
+    setLoading(true);
+    try {
+      await fetch(`${AUTH_BASE}/api/v1/auth/email/verify/resend`, {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ email: email.trim() }),
+      });
+      setUnverified(false);
+      setMsg({
+        kind: "ok",
+        text: "Ako nalog čeka potvrdu, novi link je poslat na email.",
+      });
+    } catch {
+      setMsg({ kind: "error", text: "Greška u mreži. Pokušaj ponovo." });
+    } finally {
+      setLoading(false);
+    }
+  }
+
+  function finishLogin(data: LoginResponse) {
+    storeSession(data);
+    window.dispatchEvent(new Event("auth:changed"));
+
+    setMsg({ kind: "ok", text: "Uspešno logovanje. Preusmeravam…" });
+
+    const _role = data?.user?.role;
+    const isFaculty = ["professor", "student", "facultyadmin"].includes(
+      _role!
+    );
+    if (isFaculty) {
+      router.push("/fakultet");
+    } else {
+      router.push("/sluzba-za-zaposljavanje");
+    }
+  }
+
+  // upis TOTP-a u toku prijave, kada je 2FA obavezan za ulogu
+  async function startEnrollment(challengeToken: string) {
+    const res = await fetch(`${AUTH_BASE}/api/v1/auth/mfa/totp/enroll`, {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ challengeToken }),
+    });
+    const data = await res.json().catch(() => ({}));
+    if (!res.ok) {
+      setMsg({ kind: "error", text: data?.error || "Neuspešan upis 2FA." });
+      return;
+    }
+    setEnrollment(data);
+  }
+
+  async function handleMfaSubmit(e: React.FormEvent) {
     e.preventDefault();
+    if (!challenge) return;
     setMsg(null);
 
-    const trimmedEmail = email.trim();
-    const trimmedPwd = password.trim();
-    if (!trimmedEmail || !trimmedPwd) {
-      setMsg({ kind: "error", text: "Email i lozinka su obavezni." });
+    const trimmedCode = code.trim();
+    if (!trimmedCode) {
+      setMsg({ kind: "error", text: "Kod je obavezan." });
       return;
     }
 
     setLoading(true);
     try {
-      const res = await fetch(`${AUTH_BASE}/api/v1/auth/login`, {
+      const res = await fetch(`${AUTH_BASE}/api/v1/auth/login/mfa`, {
         method: "POST",
         headers: { "Content-Type": "application/json" },
-        body: JSON.stringify({ email: trimmedEmail, password: trimmedPwd }),
+        body: JSON.stringify({
+          challengeToken: challenge.challengeToken,
+          ...(useRecovery
+            ? { recoveryCode: trimmedCode }
+            : { code: trimmedCode }),
+        }),
       });
 
       const data = (await res
@@ -47,30 +194,70 @@ export default function LoginPage() {
         .catch(() => ({}))) as Partial<LoginResponse> & { error?: string };
 
       if (!res.ok) {
-        setMsg({ kind: "error", text: data?.error || "Neuspešna prijava." });
+        setMsg({ kind: "error", text: data?.error || "Neispravan kod." });
         return;
       }
 
-      if (!data?.token) {
-        setMsg({ kind: "error", text: "Nedostaje token u odgovoru servera." });
+      if (data.recoveryCodes?.length) {
+        // recovery kodovi se prikazuju samo jednom, prije preusmjeravanja
+        setSession(data as LoginResponse);
         return;
       }
+      finishLogin(data as LoginResponse);
+    } catch {
+      setMsg({ kind: "error", text: "Greška u mreži. Pokušaj ponovo." });
+    } finally {
+      setLoading(false);
+    }
+  }
 
-      localStorage.setItem("auth.token", data.token);
-      localStorage.setItem("auth.user", JSON.stringify(data.user ?? {}));
-      window.dispatchEvent(new Event("auth:changed"));
+  // odgovor prijave (lozinkom ili SSO-om): tokeni ili 2FA challenge
+  async function handleLoginResponse(res: Response) {
+    const data = (await res.json().catch(() => ({}))) as Partial<
+      LoginResponse & MfaChallenge
+    > & { error?: string };
 
-      setMsg({ kind: "ok", text: "Uspešno logovanje. Preusmeravam…" });
+    if (!res.ok) {
+      setUnverified(data?.error === "email not verified");
+      setMsg({ kind: "error", text: data?.error || "Neuspešna prijava." });
+      return;
+    }
 
-      const _role = data?.user?.role;
-      const isFaculty = ["professor", "student", "facultyadmin"].includes(
-        _role!
-      );
-      if (isFaculty) {
-        router.push("/fakultet");
-      } else {
-        router.push("/sluzba-za-zaposljavanje");
+    if (data?.mfaRequired && data.challengeToken) {
+      setChallenge(data as MfaChallenge);
+      if (data.enrollmentRequired) {
+        await startEnrollment(data.challengeToken);
       }
+      return;
+    }
+
+    if (!data?.token) {
+      setMsg({ kind: "error", text: "Nedostaje token u odgovoru servera." });
+      return;
+    }
+
+    finishLogin(data as LoginResponse);
+  }
+
+  async function handleSubmit(e: React.FormEvent) {
+    e.preventDefault();
+    setMsg(null);
+
+    // lozinka se salje tacno kako je unesena (razmaci su dio lozinke)
+    const trimmedEmail = email.trim();
+    if (!trimmedEmail || !password) {
+      setMsg({ kind: "error", text: "Email i lozinka su obavezni." });
+      return;
+    }
+
+    setLoading(true);
+    try {
+      const res = await fetch(`${AUTH_BASE}/api/v1/auth/login`, {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ email: trimmedEmail, password }),
+      });
+      await handleLoginResponse(res);
     } catch {
       setMsg({ kind: "error", text: "Greška u mreži. Pokušaj ponovo." });
     } finally {
@@ -86,75 +273,208 @@ export default function LoginPage() {
             Prijava
           </h1>
 
-          <form onSubmit={handleSubmit} className="space-y-5">
-            <div>
-              <label
-                className="block text-sm font-medium text-gray-700 mb-1"
-                htmlFor="email"
+          {session ? (
+            <div className="space-y-5">
+              <p className="text-sm text-gray-700">
+                Dvofaktorska autentifikacija je uključena. Sačuvaj rezervne
+                kodove na sigurnom mestu – svaki se može iskoristiti samo
+                jednom i neće biti ponovo prikazani.
+              </p>
+              <ul className="grid grid-cols-2 gap-2 font-mono text-sm text-gray-900">
+                {session.recoveryCodes?.map((c) => (
+                  <li key={c} className="rounded-lg bg-gray-100 px-3 py-1">
+                    {c}
+                  </li>
+                ))}
+              </ul>
+              <button
+                type="button"
+                onClick={() => finishLogin(session)}
+                className="w-full rounded-xl bg-gradient-to-r from-blue-600 to-blue-500 px-4 py-2 text-white font-semibold hover:from-blue-700 hover:to-blue-600 transition"
               >
-                Email
-              </label>
-              <input
-                id="email"
-                type="email"
-                autoComplete="email"
-                className="text-black w-full rounded-xl border border-gray-300 px-4 py-2 focus:border-blue-500 focus:ring-1 focus:ring-blue-500 outline-none transition"
-                value={email}
-                onChange={(e) => setEmail(e.target.value)}
-                disabled={loading}
-                required
-              />
+                Sačuvao sam kodove, nastavi
+              </button>
             </div>
+          ) : challenge ? (
+            <form onSubmit={handleMfaSubmit} className="space-y-5">
+              {challenge.enrollmentRequired && enrollment && (
+                <div className="space-y-2 text-sm text-gray-700">
+                  <p>
+                    Za tvoju ulogu dvofaktorska autentifikacija je obavezna.
+                    Dodaj nalog u authenticator aplikaciju pomoću ključa ili
+                    linka, pa unesi prikazani kod.
+                  </p>
+                  <p className="break-all font-mono text-gray-900">
+                    {enrollment.secret}
+                  </p>
+                  <a
+                    href={enrollment.otpauthUri}
+                    className="text-blue-600 font-medium underline hover:text-blue-800"
+                  >
+                    Otvori u authenticator aplikaciji
+                  </a>
+                </div>
+              )}
 
-            <div>
-              <label
-                className="block text-sm font-medium text-gray-700 mb-1"
-                htmlFor="password"
-              >
-                Lozinka
-              </label>
-              <div className="relative">
+              <div>
+                <label
+                  className="block text-sm font-medium text-gray-700 mb-1"
+                  htmlFor="code"
+                >
+                  {useRecovery
+                    ? "Rezervni kod"
+                    : "Kod iz authenticator aplikacije"}
+                </label>
                 <input
-                  id="password"
-                  type={showPwd ? "text" : "password"}
-                  autoComplete="current-password"
-                  className="text-black w-full rounded-xl border border-gray-300 px-4 py-2 pr-24 focus:border-blue-500 focus:ring-1 focus:ring-blue-500 outline-none transition"
-                  value={password}
-                  onChange={(e) => setPassword(e.target.value)}
+                  id="code"
+                  type="text"
+                  inputMode={useRecovery ? "text" : "numeric"}
+                  autoComplete="one-time-code"
+                  className="text-black w-full rounded-xl border border-gray-300 px-4 py-2 focus:border-blue-500 focus:ring-1 focus:ring-blue-500 outline-none transition"
+                  value={code}
+                  onChange={(e) => setCode(e.target.value)}
                   disabled={loading}
                   required
                 />
+              </div>
+
+              {!challenge.enrollmentRequired && (
                 <button
                   type="button"
-                  onClick={() => setShowPwd((s) => !s)}
-                  className="absolute right-4 top-1/2 -translate-y-1/2 text-sm font-medium text-blue-600 hover:text-blue-800"
-                  tabIndex={-1}
+                  onClick={() => {
+                    setUseRecovery((s) => !s);
+                    setCode("");
+                  }}
+                  className="text-sm font-medium text-blue-600 hover:text-blue-800"
                 >
-                  {showPwd ? "Sakrij" : "Prikaži"}
+                  {useRecovery
+                    ? "Koristi kod iz aplikacije"
+                    : "Koristi rezervni kod"}
                 </button>
-              </div>
-            </div>
+              )}
 
-            {msg && (
-              <div
-                className={`rounded-lg px-4 py-2 text-sm font-medium ${
-                  msg.kind === "error"
-                    ? "bg-red-50 text-red-700 border border-red-100"
-                    : "bg-green-50 text-green-700 border border-green-100"
-                }`}
+              {msg && (
+                <div
+                  className={`rounded-lg px-4 py-2 text-sm font-medium ${
+                    msg.kind === "error"
+                      ? "bg-red-50 text-red-700 border border-red-100"
+                      : "bg-green-50 text-green-700 border border-green-100"
+                  }`}
+                >
+                  {msg.text}
+                </div>
+              )}
+
+              <button
+                type="submit"
+                disabled={loading}
+                className="w-full rounded-xl bg-gradient-to-r from-blue-600 to-blue-500 px-4 py-2 text-white font-semibold hover:from-blue-700 hover:to-blue-600 transition disabled:opacity-60"
               >
-                {msg.text}
+                {loading ? "Provera…" : "Potvrdi"}
+              </button>
+            </form>
+          ) : (
+            <form onSubmit={handleSubmit} className="space-y-5">
+              <div>
+                <label
+                  className="block text-sm font-medium text-gray-700 mb-1"
+                  htmlFor="email"
+                >
+                  Email
+                </label>
+                <input
+                  id="email"
+                  type="email"
+                  autoComplete="email"
+                  className="text-black w-full rounded-xl border border-gray-300 px-4 py-2 focus:border-blue-500 focus:ring-1 focus:ring-blue-500 outline-none transition"
+                  value={email}
+                  onChange={(e) => setEmail(e.target.value)}
+                  disabled={loading}
+                  required
+                />
               </div>
-            )}
 
-            <button
-              type="submit"
-              disabled={loading}
-              className="w-full rounded-xl bg-gradient-to-r from-blue-600 to-blue-500 px-4 py-2 text-white font-semibold hover:from-blue-700 hover:to-blue-600 transition disabled:opacity-60"
+              <div>
+                <label
+                  className="block text-sm font-medium text-gray-700 mb-1"
+                  htmlFor="password"
+                >
+                  Lozinka
+                </label>
+                <div className="relative">
+                  <input
+                    id="password"
+                    type={showPwd ? "text" : "password"}
+                    autoComplete="current-password"
+                    className="text-black w-full rounded-xl border border-gray-300 px-4 py-2 pr-24 focus:border-blue-500 focus:ring-1 focus:ring-blue-500 outline-none transition"
+                    value={password}
+                    onChange={(e) => setPassword(e.target.value)}
+                    disabled={loading}
+                    required
+                  />
+                  <button
+                    type="button"
+                    onClick={() => setShowPwd((s) => !s)}
+                    className="absolute right-4 top-1/2 -translate-y-1/2 text-sm font-medium text-blue-600 hover:text-blue-800"
+                    tabIndex={-1}
+                  >
+                    {showPwd ? "Sakrij" : "Prikaži"}
+                  </button>
+                </div>
+              </div>
+
+              {msg && (
+                <div
+                  className={`rounded-lg px-4 py-2 text-sm font-medium ${
+                    msg.kind === "error"
+                      ? "bg-red-50 text-red-700 border border-red-100"
+                      : "bg-green-50 text-green-700 border border-green-100"
+                  }`}
+                >
+                  {msg.text}
+                </div>
+              )}
+
+              {unverified && (
+                <button
+                  type="button"
+                  onClick={resendVerification}
+                  disabled={loading}
+                  className="text-sm font-medium text-blue-600 hover:text-blue-800"
+                >
+                  Pošalji ponovo link za potvrdu naloga
+                </button>
+              )}
+
+              <button
+                type="submit"
+                disabled={loading}
+                className="w-full rounded-xl bg-gradient-to-r from-blue-600 to-blue-500 px-4 py-2 text-white font-semibold hover:from-blue-700 hover:to-blue-600 transition disabled:opacity-60"
+              >
+                {loading ? "Prijavljivanje…" : "Prijavi se"}
+              </button>
+
+              {ssoEnabled && (
+                <button
+                  type="button"
+                  onClick={startSso}
+                  disabled={loading}
+                  className="w-full rounded-xl border border-blue-600 px-4 py-2 text-blue-600 font-semibold hover:bg-blue-50 transition disabled:opacity-60"
+                >
+                  Prijava preko fakultetskog naloga
+                </button>
+              )}
+            </form>
+          )}
+
+          <p className="mt-4 text-center text-sm">
+            <a
+              href="/auth/forgot-password"
+              className="text-blue-600 font-medium underline hover:text-blue-800"
             >
-              {loading ? "Prijavljivanje…" : "Prijavi se"}
-            </button>
-          </form>
+              Zaboravljena lozinka?
+            </a>
+          </p>
 
           <p className="mt-6 text-center text-gray-600 text-sm">
             Nemaš nalog?{" "}
@@ -1,12 +1,16 @@
 "use client";
 
-import { useState } from "react";
-import { useRouter } from "next/navigation";
+import { useEffect, useState } from "react";
 import Wrap from "@/components/wrap";
 
 type RegisterResponse = {
-  token: string;
   user: { id: string; fullName: string; email: string; role: string };
+  message: string;
+};
+
+type PasswordPolicy = {
+  minLength: number;
+  rules: string[];
 };
 
 const AUTH_BASE =
@@ -14,16 +18,23 @@ const AUTH_BASE =
   "http://localhost:8083";
 
 export default function RegisterPage() {
-  const router = useRouter();
   const [fullName, setFullName] = useState("");
   const [email, setEmail] = useState("");
   const [password, setPassword] = useState("");
-  const [role, setRole] = useState("sszadmin");
   const [showPwd, setShowPwd] = useState(false);
   const [loading, setLoading] = useState(false);
   const [msg, setMsg] = useState<{ kind: "error" | "ok"; text: string } | null>(
     null
   );
+  const [policy, setPolicy] = useState<PasswordPolicy | null>(null);
+
+  // pravila za lozinku dolaze sa auth servisa, da bi prikaz pratio podesavanja
+  useEffect(() => {
+    fetch(`${AUTH_BASE}/api/v1/auth/password/policy`)
+      .then((res) => (res.ok ? res.json() : null))
+      .then((data) => setPolicy(data))
+      .catch(() => setPolicy(null));
+  }, []);
 
   async function handleSubmit(e: React.FormEvent) {
     e.preventDefault();
@@ -31,8 +42,7 @@ export default function RegisterPage() {
 
     const trimmedName = fullName.trim();
     const trimmedEmail = email.trim();
-    const trimmedPwd = password.trim();
-    if (!trimmedName || !trimmedEmail || !trimmedPwd) {
+    if (!trimmedName || !trimmedEmail || !password) {
       setMsg({ kind: "error", text: "Ime, email i lozinka su obavezni." });
       return;
     }
@@ -45,34 +55,34 @@ export default function RegisterPage() {
         body: JSON.stringify({
           fullName: trimmedName,
           email: trimmedEmail,
-          password: trimmedPwd,
-          role,
+          password,
         }),
       });
 
       const data = (await res
         .json()
-        .catch(() => ({}))) as Partial<RegisterResponse> & { error?: string };
+        .catch(() => ({}))) as Partial<RegisterResponse> & {
+        error?: string;
+        violations?: string[];
+      };
 
       if (!res.ok) {
         setMsg({
           kind: "error",
-          text: data?.error || "Neuspešna registracija.",
+          text: data?.violations?.length
+            ? `Lozinka ne ispunjava pravila: ${data.violations.join("; ")}.`
+            : data?.error || "Neuspešna registracija.",
         });
         return;
       }
 
-      if (!data?.token) {
-        setMsg({ kind: "error", text: "Nedostaje token u odgovoru servera." });
-        return;
-      }
-
-      localStorage.setItem("auth.token", data.token);
-      localStorage.setItem("auth.user", JSON.stringify(data.user ?? {}));
-      window.dispatchEvent(new Event("auth:changed"));
-
-      setMsg({ kind: "ok", text: "Uspešna registracija. Preusmeravam…" });
-      router.push("/");
+      // nalog se aktivira tek potvrdom linka poslatog na email adresu
+      setMsg({
+        kind: "ok",
+        text: `Uspešna registracija. Link za potvrdu naloga je poslat na ${trimmedEmail}.`,
+      });
+      setFullName("");
+      setPassword("");
     } catch (err) {
       setMsg({ kind: "error", text: "Greška u mreži. Pokušaj ponovo." });
     } finally {
@@ -141,21 +151,13 @@ export default function RegisterPage() {
                   {showPwd ? "Sakrij" : "Prikaži"}
                 </button>
               </div>
-            </div>
-
-            <div>
-              <label className="block text-sm font-medium text-gray-700 mb-1 !text-black">
-                Uloga
-              </label>
-              <select
-                className="w-full rounded-lg border border-gray-300 px-4 py-2 focus:ring-2 focus:ring-blue-400 outline-none !text-black"
-                value={role}
-                onChange={(e) => setRole(e.target.value)}
-                disabled={loading}
-              >
-                <option value="sszadmin">SSZ Admin</option>
-                <option value="facultyadmin">Fakultet Admin</option>
-              </select>
+              {policy?.rules?.length ? (
+                <ul className="mt-2 list-disc pl-5 text-xs text-gray-500">
+                  {policy.rules.map((rule) => (
+                    <li key={rule}>{rule}</li>
+                  ))}
+                </ul>
+              ) : null}
             </div>
 
             {msg && (
@@ -0,0 +1,143 @@
+"use client";
+
+import { Suspense, useState } from "react";
+import { useRouter, useSearchParams } from "next/navigation";
+import Wrap from "@/components/wrap";
+
+const AUTH_BASE =
+  process.env.NEXT_PUBLIC_AUTH_URL?.replace(/\/$/, "") ??
+  "http://localhost:8083";
+
+function ResetPasswordForm() {
+  const router = useRouter();
+  const token = useSearchParams().get("token") ?? "";
+  const [password, setPassword] = useState("");
+  const [confirm, setConfirm] = useState("");
+  const [loading, setLoading] = useState(false);
+  const [msg, setMsg] = useState<{ kind: "error" | "ok"; text: string } | null>(
+    null
+  );
+
+  async function handleSubmit(e: React.FormEvent) {
+    e.preventDefault();
+    setMsg(null);
+
+    if (!token) {
+      setMsg({ kind: "error", text: "Link za promjenu lozinke nije ispravan." });
+      return;
+    }
+    if (password !== confirm) {
+      setMsg({ kind: "error", text: "Lozinke se ne poklapaju." });
+      return;
+    }
+
+    setLoading(true);
+    try {
+      const res = await fetch(`${AUTH_BASE}/api/v1/auth/password/reset`, {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ token, newPassword: password }),
+      });
+
+      if (!res.ok) {
+        const data = (await res.json().catch(() => ({}))) as {
+          error?: string;
+          violations?: string[];
+        };
+        setMsg({
+          kind: "error",
+          text: data?.violations?.length
+            ? `Lozinka ne ispunjava pravila: ${data.violations.join("; ")}.`
+            : data?.error || "Promjena lozinke nije uspjela.",
+        });
+        return;
+      }
+
+      setMsg({ kind: "ok", text: "Lozinka je promijenjena. Preusmeravam…" });
+      setTimeout(() => router.push("/auth/login"), 1500);
+    } catch {
+      setMsg({ kind: "error", text: "Greška u mreži. Pokušaj ponovo." });
+    } finally {
+      setLoading(false);
+    }
+  }
+
+  return (
+    <form onSubmit={handleSubmit} className="space-y-5">
+      <div>
+        <label
+          className="block text-sm font-medium text-gray-700 mb-1"
+          htmlFor="password"
+        >
+          Nova lozinka
+        </label>
+        <input
+          id="password"
+          type="password"
+          autoComplete="new-password"
+          className="text-black w-full rounded-xl border border-gray-300 px-4 py-2 focus:border-blue-500 focus:ring-1 focus:ring-blue-500 outline-none transition"
+          value={password}
+          onChange={(e) => setPassword(e.target.value)}
+          disabled={loading}
+          required
+        />
+      </div>
+
+      <div>
+        <label
+          className="block text-sm font-medium text-gray-700 mb-1"
+          htmlFor="confirm"
+        >
+          Potvrda lozinke
+        </label>
+        <input
+          id="confirm"
+          type="password"
+          autoComplete="new-password"
+          className="text-black w-full rounded-xl border border-gray-300 px-4 py-2 focus:border-blue-500 focus:ring-1 focus:ring-blue-500 outline-none transition"
+          value={confirm}
+          onChange={(e) => setConfirm(e.target.value)}
+          disabled={loading}
+          required
+        />
+      </div>
+
+      {msg && (
+        <div
+          className={`rounded-lg px-4 py-2 text-sm font-medium ${
+            msg.kind === "error"
+              ? "bg-red-50 text-red-700 border border-red-100"
+              : "bg-green-50 text-green-700 border border-green-100"
+          }`}
+        >
+          {msg.text}
+        </div>
+      )}
+
+      <button
+        type="submit"
+        disabled={loading}
+        className="w-full rounded-xl bg-gradient-to-r from-blue-600 to-blue-500 px-4 py-2 text-white font-semibold hover:from-blue-700 hover:to-blue-600 transition disabled:opacity-60"
+      >
+        {loading ? "Čuvanje…" : "Promijeni lozinku"}
+      </button>
+    </form>
+  );
+}
+
+export default function ResetPasswordPage() {
+  return (
+    <Wrap withGoBack={false}>
+      <div className="flex items-center min-h-[70vh] justify-center !bg-gray-600 p-6">
+        <div className="w-full max-w-md bg-gray-50 rounded-2xl shadow-xl p-8">
+          <h1 className="text-3xl font-bold text-gray-900 mb-6 text-center">
+            Nova lozinka
+          </h1>
+          <Suspense>
+            <ResetPasswordForm />
+          </Suspense>
+        </div>
+      </div>
+    </Wrap>
+  );
+}
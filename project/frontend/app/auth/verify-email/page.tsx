@@ -0,0 +1,91 @@
+"use client";
+
+import { Suspense, useState } from "react";
+import { useRouter, useSearchParams } from "next/navigation";
+import Wrap from "@/components/wrap";
+
+const AUTH_BASE =
+  process.env.NEXT_PUBLIC_AUTH_URL?.replace(/\/$/, "") ??
+  "http://localhost:8083";
+
+function VerifyEmailForm() {
+  const router = useRouter();
+  const token = useSearchParams().get("token") ?? "";
+  const [loading, setLoading] = useState(false);
+  const [msg, setMsg] = useState<{ kind: "error" | "ok"; text: string } | null>(
+    null
+  );
+
+  async function handleConfirm() {
+    setMsg(null);
+
+    if (!token) {
+      setMsg({ kind: "error", text: "Link za potvrdu naloga nije ispravan." });
+      return;
+    }
+
+    setLoading(true);
+    try {
+      const res = await fetch(`${AUTH_BASE}/api/v1/auth/email/verify`, {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ token }),
+      });
+
+      if (!res.ok) {
+        const data = (await res.json().catch(() => ({}))) as { error?: string };
+        setMsg({ kind: "error", text: data?.error || "Potvrda naloga nije uspjela." });
+        return;
+      }
+
+      setMsg({ kind: "ok", text: "Nalog je potvrđen. Preusmeravam…" });
+      setTimeout(() => router.push("/auth/login"), 1500);
+    } catch {
+      setMsg({ kind: "error", text: "Greška u mreži. Pokušaj ponovo." });
+    } finally {
+      setLoading(false);
+    }
+  }
+
+  return (
+    <div className="space-y-5">
+      {msg && (
+        <div
+          className={`rounded-lg px-4 py-2 text-sm font-medium ${
+            msg.kind === "error"
+              ? "bg-red-50 text-red-700 border border-red-100"
+              : "bg-green-50 text-green-700 border border-green-100"
+          }`}
+        >
+          {msg.text}
+        </div>
+      )}
+
+      <button
+        type="button"
+        onClick={handleConfirm}
+        disabled={loading}
+        className="w-full rounded-xl bg-gradient-to-r from-blue-600 to-blue-500 px-4 py-2 text-white font-semibold hover:from-blue-700 hover:to-blue-600 transition disabled:opacity-60"
+      >
+        {loading ? "Potvrđivanje…" : "Potvrdi nalog"}
+      </button>
+    </div>
+  );
+}
+
+export default function VerifyEmailPage() {
+  return (
+    <Wrap withGoBack={false}>
+      <div className="flex items-center min-h-[70vh] justify-center !bg-gray-600 p-6">
+        <div className="w-full max-w-md bg-gray-50 rounded-2xl shadow-xl p-8">
+          <h1 className="text-3xl font-bold text-gray-900 mb-6 text-center">
+            Potvrda naloga
+          </h1>
+          <Suspense>
+            <VerifyEmailForm />
+          </Suspense>
+        </div>
+      </div>
+    </Wrap>
+  );
+}
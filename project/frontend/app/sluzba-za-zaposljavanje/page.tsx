@@ -5,6 +5,7 @@ import LinkButton from "@/components/link-button";
 import useLocalStorage, { AuthUser } from "@/hooks/useLocalStorage";
 import Button from "@/components/button";
 import useEmployer from "@/hooks/useEmployer";
+import { logout } from "@/utils/auth";
 
 export default function SluzbaZaZaposljavanje() {
   const [user] = useLocalStorage<AuthUser | null>("auth.user", null);
@@ -79,8 +80,7 @@ export default function SluzbaZaZaposljavanje() {
               <Button
                 onClick={async () => {
                   await quitJob();
-                  localStorage.removeItem("auth.token");
-                  localStorage.removeItem("auth.user");
+                  await logout();
                   window.location.href = "/auth/login";
                 }}
                 className="group relative flex items-center justify-center p-4 rounded-xl bg-red-500 text-white text-lg font-semibold shadow-lg transform transition duration-300 hover:scale-105 hover:shadow-2xl"
@@ -4,6 +4,7 @@ import { useEffect, useState } from "react";
 import { usePathname } from "next/navigation";
 import { FontAwesomeIcon } from "@fortawesome/react-fontawesome";
 import { faRightFromBracket } from "@fortawesome/free-solid-svg-icons";
+import { logout } from "@/utils/auth";
 
 export default function LogoutButton() {
   const pathname = usePathname();
@@ -21,10 +22,9 @@ export default function LogoutButton() {
     return () => window.removeEventListener("storage", onStorage);
   }, []);
 
-  function handleClick() {
+  async function handleClick() {
     if (hasToken) {
-      localStorage.removeItem("auth.token");
-      localStorage.removeItem("auth.user");
+      await logout();
       setHasToken(false);
     }
     window.location.href = "/auth/login";
@@ -0,0 +1,74 @@
+const AUTH_BASE =
+  process.env.NEXT_PUBLIC_AUTH_URL?.replace(/\/$/, "") ??
+  "http://localhost:8083";
+
+type TokenResponse = {
+  token: string;
+  refreshToken: string;
+  user: { id: string; fullName: string; email: string; role: string };
+};
+
+export function storeSession(data: TokenResponse) {
+  localStorage.setItem("auth.token", data.token);
+  localStorage.setItem("auth.refreshToken", data.refreshToken);
+  localStorage.setItem("auth.user", JSON.stringify(data.user ?? {}));
+}
+
+export function clearSession() {
+  localStorage.removeItem("auth.token");
+  localStorage.removeItem("auth.refreshToken");
+  localStorage.removeItem("auth.user");
+}
+
+let refreshing: Promise<string | null> | null = null;
+
+// Zamjenjuje refresh token za novi access token; paralelni pozivi dijele isti zahtjev
+export function refreshAccessToken(): Promise<string | null> {
+  if (refreshing) return refreshing;
+
+  refreshing = (async () => {
+    const refreshToken = localStorage.getItem("auth.refreshToken");
+    if (!refreshToken) return null;
+
+    try {
+      const res = await fetch(`${AUTH_BASE}/api/v1/auth/refresh`, {
+        method: "POST",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ refreshToken }),
+      });
+      if (!res.ok) {
+        clearSession();
+        return null;
+      }
+      const data = (await res.json()) as TokenResponse;
+      storeSession(data);
+      return data.token;
+    } catch {
+      return null;
+    } finally {
+      refreshing = null;
+    }
+  })();
+
+  return refreshing;
+}
+
+export async function logout() {
+  const token = localStorage.getItem("auth.token");
+  const refreshToken = localStorage.getItem("auth.refreshToken");
+
+  try {
+    await fetch(`${AUTH_BASE}/api/v1/auth/logout`, {
+      method: "POST",
+      headers: {
+        "Content-Type": "application/json",
+        ...(token ? { Authorization: `Bearer ${token}` } : {}),
+      },
+      body: JSON.stringify({ refreshToken: refreshToken ?? "" }),
+    });
+  } catch {
+    // sesija se brise lokalno i ako auth servis nije dostupan
+  }
+
+  clearSession();
+}
@@ -1,4 +1,5 @@
-import axios from "axios";
+import axios, { AxiosError, AxiosInstance, InternalAxiosRequestConfig } from "axios";
+import { refreshAccessToken } from "./auth";
 
 export const axiosEmploymentOfficeInstance = axios.create({
   baseURL: "http://localhost:8082/api/v1",
@@ -8,28 +9,49 @@ export const axiosUniversityInstance = axios.create({
   baseURL: "http://localhost:8081/api/v1",
 });
 
-axiosEmploymentOfficeInstance.interceptors.request.use(
-  (config) => {
-    if (typeof window !== "undefined") {
-      const token = localStorage.getItem("auth.token");
-      if (token) {
-        config.headers.Authorization = `Bearer ${token}`;
+function withAuth(instance: AxiosInstance) {
+  instance.interceptors.request.use(
+    (config) => {
+      if (typeof window !== "undefined") {
+        const token = localStorage.getItem("auth.token");
+        if (token) {
+          config.headers.Authorization = `Bearer ${token}`;
+        }
       }
-    }
-    return config;
-  },
-  (error) => Promise.reject(error)
-);
-
-axiosUniversityInstance.interceptors.request.use(
-  (config) => {
-    if (typeof window !== "undefined") {
-      const token = localStorage.getItem("auth.token");
-      if (token) {
-        config.headers.Authorization = `Bearer ${token}`;
+      return config;
+    },
+    (error) => Promise.reject(error)
+  );
+
+  // access token je kratkotrajan: na 401 probaj jednom osvjeziti token i ponovi zahtjev
+  instance.interceptors.response.use(
+    (response) => response,
+    async (error: AxiosError) => {
+      const original = error.config as
+        | (InternalAxiosRequestConfig & { _retried?: boolean })
+        | undefined;
+
+      if (
+        typeof window === "undefined" ||
+        !original ||
+        original._retried ||
+        error.response?.status !== 401
+      ) {
+        return Promise.reject(error);
       }
+
+      original._retried = true;
+      const token = await refreshAccessToken();
+      if (!token) {
+        window.location.href = "/auth/login";
+        return Promise.reject(error);
+      }
+
+      original.headers.Authorization = `Bearer ${token}`;
+      return instance(original);
     }
-    return config;
-  },
-  (error) => Promise.reject(error)
-);
+  );
+}
+
+withAuth(axiosEmploymentOfficeInstance);
+withAuth(axiosUniversityInstance);
@@ -3,30 +3,137 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
-	Host          string
-	Port          string
-	SecretKeyAuth string
-	DBHost        string
-	DBPort        string
-	DBUser        string
-	DBName        string
-	DBPass        string
+	Host              string
+	Port              string
+	JWTPrivateKeyFile string
+	DBHost            string
+	DBPort            string
+	DBUser            string
+	DBName            string
+	DBPass            string
+	AccessTokenTTL    time.Duration
+	RefreshTokenTTL   time.Duration
+	PasswordResetTTL  time.Duration
+	PasswordResetURL  string
+	EmailChangeTTL    time.Duration
+	EmailChangeURL    string
+	EmailVerifyTTL    time.Duration
+	EmailVerifyURL    string
+	EmailVerifySecret string
+	NotifierFile      string
+	SMTPHost          string
+	SMTPPort          string
+	SMTPUsername      string
+	SMTPPassword      string
+	SMTPFrom          string
+	SelfRegisterRoles []string
+	BootstrapAdmins   string
+
+	// zastita od brute-force napada na login
+	LoginMaxAccountAttempts int
+	LoginMaxIPAttempts      int
+	LoginAttemptWindow      time.Duration
+	LoginLockoutBase        time.Duration
+	LoginLockoutMax         time.Duration
+	TrustProxyHeaders       bool
+
+	// dvofaktorska autentifikacija (TOTP)
+	MFARequiredRoles []string
+	MFAIssuer        string
+	MFAChallengeTTL  time.Duration
+
+	// jedinstvena prijava (OpenID Connect) za osoblje i studente fakulteta
+	OIDCIssuer               string
+	OIDCClientID             string
+	OIDCClientSecret         string
+	OIDCRedirectURL          string
+	OIDCScopes               []string
+	OIDCRoles                []string
+	OIDCRequireEmailVerified bool
+	OIDCStateTTL             time.Duration
+	OIDCDevProvider          bool
+
+	// klijenti za service tokene (client credentials) izmedju servisa
+	ServiceClients  []string
+	ServiceTokenTTL time.Duration
+
+	// administrator moze privremeno gledati aplikaciju kao drugi korisnik
+	ImpersonationTTL time.Duration
+
+	// politika lozinki
+	PasswordMinLength        int
+	PasswordMaxLength        int
+	PasswordRequiredClasses  []string
+	PasswordDisallowPersonal bool
+	PasswordBlocklistFile    string
 }
 
 func GetConfig() Config {
 
 	return Config{
-		Host:          os.Getenv("HOST_PORT"),
-		Port:          os.Getenv("AUTH_PORT"),
-		SecretKeyAuth: os.Getenv("SECRET_KEY_AUTH"),
-		DBHost:        os.Getenv("DB_HOST"),
-		DBUser:        os.Getenv("DB_USER"),
-		DBPort:        os.Getenv("DB_PORT"),
-		DBPass:        os.Getenv("DB_PASS"),
-		DBName:        os.Getenv("DB_NAME"),
+		Host:              os.Getenv("HOST_PORT"),
+		Port:              os.Getenv("AUTH_PORT"),
+		JWTPrivateKeyFile: os.Getenv("JWT_PRIVATE_KEY_FILE"),
+		DBHost:            os.Getenv("DB_HOST"),
+		DBUser:            os.Getenv("DB_USER"),
+		DBPort:            os.Getenv("DB_PORT"),
+		DBPass:            os.Getenv("DB_PASS"),
+		DBName:            os.Getenv("DB_NAME"),
+		AccessTokenTTL:    getDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL:   getDuration("REFRESH_TOKEN_TTL", 7*24*time.Hour),
+		PasswordResetTTL:  getDuration("PASSWORD_RESET_TTL", 30*time.Minute),
+		PasswordResetURL:  getEnv("PASSWORD_RESET_URL", "http://localhost:3000/auth/reset-password"),
+		EmailChangeTTL:    getDuration("EMAIL_CHANGE_TTL", 24*time.Hour),
+		EmailChangeURL:    getEnv("EMAIL_CHANGE_URL", "http://localhost:3000/auth/confirm-email"),
+		EmailVerifyTTL:    getDuration("EMAIL_VERIFICATION_TTL", 48*time.Hour),
+		EmailVerifyURL:    getEnv("EMAIL_VERIFICATION_URL", "http://localhost:3000/auth/verify-email"),
+		EmailVerifySecret: os.Getenv("EMAIL_VERIFICATION_SECRET"),
+		NotifierFile:      os.Getenv("NOTIFIER_FILE"),
+		SMTPHost:          os.Getenv("SMTP_HOST"),
+		SMTPPort:          getEnv("SMTP_PORT", "587"),
+		SMTPUsername:      os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:      os.Getenv("SMTP_PASSWORD"),
+		SMTPFrom:          getEnv("SMTP_FROM", "no-reply@eadministration.local"),
+		SelfRegisterRoles: getList("SELF_REGISTER_ROLES", []string{"candidate"}),
+		BootstrapAdmins:   os.Getenv("BOOTSTRAP_ADMINS"),
+
+		LoginMaxAccountAttempts: getInt("LOGIN_MAX_ACCOUNT_ATTEMPTS", 5),
+		LoginMaxIPAttempts:      getInt("LOGIN_MAX_IP_ATTEMPTS", 20),
+		LoginAttemptWindow:      getDuration("LOGIN_ATTEMPT_WINDOW", 15*time.Minute),
+		LoginLockoutBase:        getDuration("LOGIN_LOCKOUT_BASE", time.Minute),
+		LoginLockoutMax:         getDuration("LOGIN_LOCKOUT_MAX", time.Hour),
+		TrustProxyHeaders:       os.Getenv("TRUST_PROXY_HEADERS") == "true",
+
+		MFARequiredRoles: getList("MFA_REQUIRED_ROLES", []string{"facultyadmin", "sszadmin"}),
+		MFAIssuer:        getEnv("MFA_ISSUER", "eAdministration"),
+		MFAChallengeTTL:  getDuration("MFA_CHALLENGE_TTL", 5*time.Minute),
+
+		OIDCIssuer:               os.Getenv("OIDC_ISSUER"),
+		OIDCClientID:             os.Getenv("OIDC_CLIENT_ID"),
+		OIDCClientSecret:         os.Getenv("OIDC_CLIENT_SECRET"),
+		OIDCRedirectURL:          getEnv("OIDC_REDIRECT_URL", "http://localhost:3000/auth/login"),
+		OIDCScopes:               getList("OIDC_SCOPES", []string{"openid", "email", "profile"}),
+		OIDCRoles:                getList("OIDC_ROLES", []string{"student", "professor", "facultyadmin"}),
+		OIDCRequireEmailVerified: os.Getenv("OIDC_REQUIRE_EMAIL_VERIFIED") != "false",
+		OIDCStateTTL:             getDuration("OIDC_STATE_TTL", 10*time.Minute),
+		OIDCDevProvider:          os.Getenv("OIDC_DEV_PROVIDER") == "true",
+
+		ServiceClients:  getList("SERVICE_CLIENTS", nil),
+		ServiceTokenTTL: getDuration("SERVICE_TOKEN_TTL", 5*time.Minute),
+
+		ImpersonationTTL: getDuration("IMPERSONATION_TTL", 15*time.Minute),
+
+		PasswordMinLength:        getInt("PASSWORD_MIN_LENGTH", 8),
+		PasswordMaxLength:        getInt("PASSWORD_MAX_LENGTH", 72),
+		PasswordRequiredClasses:  getList("PASSWORD_REQUIRED_CLASSES", nil),
+		PasswordDisallowPersonal: os.Getenv("PASSWORD_DISALLOW_PERSONAL") != "false",
+		PasswordBlocklistFile:    os.Getenv("PASSWORD_BLOCKLIST_FILE"),
 	}
 }
 
@@ -36,3 +143,47 @@ func (c Config) DatabaseURL() string {
 		c.DBUser, c.DBPass, c.DBHost, c.DBPort, c.DBName,
 	)
 }
+
+func getEnv(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func getInt(key string, def int) int {
+	n, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || n <= 0 {
+		return def
+	}
+	return n
+}
+
+// getList cita listu vrijednosti odvojenih zarezom
+func getList(key string, def []string) []string {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	var out []string
+	for _, part := range strings.Split(v, ",") {
+		if p := strings.TrimSpace(part); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// getDuration cita trajanje (npr. "15m", "168h") iz env varijable,
+// a ako nije postavljeno ili nije validno vraca default vrijednost
+func getDuration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return def
+	}
+	return d
+}
@@ -15,6 +15,7 @@ require (
 )
 
 require (
+	github.com/Bijelic03/eAdministration/project/microservices/common v0.0.0
 	github.com/felixge/httpsnoop v1.0.3 // indirect
 	github.com/jackc/chunkreader/v2 v2.0.1 // indirect
 	github.com/jackc/pgio v1.0.0 // indirect
@@ -25,3 +26,5 @@ require (
 	golang.org/x/sync v0.13.0 // indirect
 	golang.org/x/text v0.24.0 // indirect
 )
+
+replace github.com/Bijelic03/eAdministration/project/microservices/common => ../common
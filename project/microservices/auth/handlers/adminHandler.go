@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	repo "github.com/Bijelic03/eAdministration/project/microservices/auth/repositories"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// manageableRoles odredjuje koje uloge administrator moze dodijeliti:
+// facultyadmin upravlja nalozima fakulteta, sszadmin nalozima sluzbe za zaposljavanje
+var manageableRoles = map[repo.UserRole][]repo.UserRole{
+	repo.RoleFacultyAdmin: {repo.RoleStudent, repo.RoleProfessor, repo.RoleFacultyAdmin},
+	repo.RoleSSZAdmin:     {repo.RoleEmployee, repo.RoleCandidate, repo.RoleSSZAdmin},
+}
+
+func canManage(admin string, role repo.UserRole) bool {
+	for _, r := range manageableRoles[repo.UserRole(admin)] {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *UserHandler) canSelfRegister(role repo.UserRole) bool {
+	for _, r := range h.cfg.SelfRegisterRoles {
+		if repo.UserRole(r) == role {
+			return true
+		}
+	}
+	return false
+}
+
+type provisionReq struct {
+	FullName string        `json:"fullName"`
+	Email    string        `json:"email"`
+	Password string        `json:"password"`
+	Role     repo.UserRole `json:"role"`
+}
+
+type changeRoleReq struct {
+	Role repo.UserRole `json:"role"`
+}
+
+type changeStatusReq struct {
+	Status repo.UserStatus `json:"status"`
+}
+
+func userResp(u *repo.User) map[string]any {
+	return map[string]any{
+		"id":       u.ID,
+		"fullName": u.FullName,
+		"email":    u.Email,
+		"role":     u.Role,
+		"status":   u.Status,
+	}
+}
+
+// POST /api/v1/auth/users
+// Administrator otvara nalog osoblja (ili studenta/kandidata) iz svog domena
+func (h *UserHandler) ProvisionUser(w http.ResponseWriter, r *http.Request) {
+	tc := claimsFromContext(r.Context())
+
+	var req provisionReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, "invalid JSON body")
+		return
+	}
+	req.FullName = strings.TrimSpace(req.FullName)
+	req.Email = strings.TrimSpace(req.Email)
+	if req.FullName == "" || req.Email == "" || req.Password == "" || req.Role == "" {
+		badRequest(w, "fullName, email, password and role are required")
+		return
+	}
+	if !req.Role.Valid() {
+		badRequest(w, repo.ErrInvalidRole.Error())
+		return
+	}
+	if !canManage(tc.Role, req.Role) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "cannot assign role " + string(req.Role)})
+		return
+	}
+	if !h.checkPassword(w, req.Password, req.Email, req.FullName) {
+		return
+	}
+
+	actorID, err := uuid.Parse(tc.UserID)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+		return
+	}
+
+	created, err := h.repo.Provision(r.Context(), repo.User{
+		FullName: req.FullName,
+		Email:    req.Email,
+		Password: req.Password,
+		Role:     req.Role,
+	}, &actorID)
+	if err != nil {
+		if errors.Is(err, repo.ErrEmailExists) {
+			writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to create user"})
+		return
+	}
+
+	record(r, claimsActor(tc), "user.provision", "user", created.ID.String(), nil, userResp(created))
+
+	writeJSON(w, http.StatusCreated, userResp(created))
+}
+
+// PUT /api/v1/auth/users/{id}/role
+// Mijenja ulogu korisnika; i trenutna i nova uloga moraju biti u domenu
+// administratora. Sesije korisnika se opozivaju kako bi nova uloga vazila odmah
+// nakon isteka postojeceg access tokena.
+func (h *UserHandler) ChangeRole(w http.ResponseWriter, r *http.Request) {
+	tc := claimsFromContext(r.Context())
+
+	userID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		badRequest(w, "invalid id")
+		return
+	}
+	if userID.String() == tc.UserID {
+		badRequest(w, "cannot change own role")
+		return
+	}
+
+	var req changeRoleReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, "invalid JSON body")
+		return
+	}
+	if !req.Role.Valid() {
+		badRequest(w, repo.ErrInvalidRole.Error())
+		return
+	}
+
+	target, err := h.repo.GetByID(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, repo.ErrUserNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to change role"})
+		return
+	}
+	if !canManage(tc.Role, target.Role) || !canManage(tc.Role, req.Role) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "cannot assign role " + string(req.Role)})
+		return
+	}
+
+	actorID, err := uuid.Parse(tc.UserID)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+		return
+	}
+
+	updated, err := h.repo.ChangeRole(r.Context(), userID, req.Role, actorID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to change role"})
+		return
+	}
+	if err := h.tokens.RevokeAllForUser(r.Context(), userID); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to revoke sessions"})
+		return
+	}
+
+	record(r, claimsActor(tc), "user.role_change", "user", userID.String(), userResp(target), userResp(updated))
+
+	writeJSON(w, http.StatusOK, userResp(updated))
+}
+
+// GET /api/v1/auth/users/{id}/role-changes
+// Istorija promjena uloga korisnika
+func (h *UserHandler) ListRoleChanges(w http.ResponseWriter, r *http.Request) {
+	tc := claimsFromContext(r.Context())
+
+	userID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		badRequest(w, "invalid id")
+		return
+	}
+
+	target, err := h.repo.GetByID(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, repo.ErrUserNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to list role changes"})
+		return
+	}
+	if !canManage(tc.Role, target.Role) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+		return
+	}
+
+	changes, err := h.repo.ListRoleChanges(r.Context(), userID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to list role changes"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"changes": changes})
+}
+
+// PUT /api/v1/auth/users/{id}/status
+// Aktivira ili deaktivira nalog iz domena administratora; deaktiviranom nalogu
+// se opozivaju sesije, a servisi odbijaju i njegove access tokene
+func (h *UserHandler) ChangeStatus(w http.ResponseWriter, r *http.Request) {
+	tc := claimsFromContext(r.Context())
+
+	userID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		badRequest(w, "invalid id")
+		return
+	}
+	if userID.String() == tc.UserID {
+		badRequest(w, "cannot change own status")
+		return
+	}
+
+	var req changeStatusReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, "invalid JSON body")
+		return
+	}
+	if !req.Status.Valid() {
+		badRequest(w, repo.ErrInvalidStatus.Error())
+		return
+	}
+
+	target, err := h.repo.GetByID(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, repo.ErrUserNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to change status"})
+		return
+	}
+	if !canManage(tc.Role, target.Role) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+		return
+	}
+
+	updated, err := h.repo.SetStatus(r.Context(), userID, req.Status)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to change status"})
+		return
+	}
+	if !updated.Active() {
+		if err := h.tokens.RevokeAllForUser(r.Context(), userID); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to revoke sessions"})
+			return
+		}
+	}
+
+	record(r, claimsActor(tc), "user.status_change", "user", userID.String(), userResp(target), userResp(updated))
+
+	writeJSON(w, http.StatusOK, userResp(updated))
+}
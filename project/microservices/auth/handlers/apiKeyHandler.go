@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Bijelic03/eAdministration/project/microservices/auth/policy"
+	repo "github.com/Bijelic03/eAdministration/project/microservices/auth/repositories"
+	"github.com/Bijelic03/eAdministration/project/microservices/common/audit"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// apiKeyPrefix oznacava API kljuc poslodavca ("eak_<prefix>_<tajna>")
+const apiKeyPrefix = "eak_"
+
+// maxUsageDays ogranicava period dnevne statistike upotrebe kljuca
+const maxUsageDays = 366
+
+type APIKeyRepo interface {
+	Create(ctx context.Context, k repo.APIKey, keyHash string) (*repo.APIKey, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*repo.APIKey, error)
+	List(ctx context.Context, employerID *uuid.UUID) ([]repo.APIKey, error)
+	Revoke(ctx context.Context, id uuid.UUID) (*repo.APIKey, error)
+	Rotate(ctx context.Context, id uuid.UUID, prefix, keyHash string, rotatedBy *uuid.UUID) (*repo.APIKey, error)
+	Use(ctx context.Context, keyHash string) (*repo.APIKey, error)
+	Usage(ctx context.Context, id uuid.UUID, since time.Time) ([]repo.APIKeyUsage, error)
+}
+
+// APIKeyHandler upravlja API kljucevima poslodavaca kojima vanjski sistemi
+// rade sa berzom poslova (employmentOffice) bez posredovanja sszadmin-a
+type APIKeyHandler struct {
+	keys APIKeyRepo
+}
+
+func NewAPIKeyHandler(keys APIKeyRepo) *APIKeyHandler {
+	return &APIKeyHandler{keys: keys}
+}
+
+type createAPIKeyReq struct {
+	EmployerID  uuid.UUID  `json:"employerId"`
+	Name        string     `json:"name"`
+	Permissions []string   `json:"permissions"`
+	ExpiresAt   *time.Time `json:"expiresAt"`
+}
+
+// apiKeyResp je kljuc zajedno sa tajnom; vraca se samo pri kreiranju i rotaciji
+type apiKeyResp struct {
+	*repo.APIKey
+	Key string `json:"key"`
+}
+
+// POST /api/v1/auth/api-keys
+// Izdaje kljuc poslodavcu sa datim dozvolama; kljuc se prikazuje samo jednom
+func (h *APIKeyHandler) Create(w http.ResponseWriter, r *http.Request) {
+	tc := claimsFromContext(r.Context())
+
+	var req createAPIKeyReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, "invalid JSON body")
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.EmployerID == uuid.Nil || req.Name == "" || len(req.Permissions) == 0 {
+		badRequest(w, "employerId, name and permissions are required")
+		return
+	}
+	for _, p := range req.Permissions {
+		if !hasScope(policy.APIKeyPermissions, p) {
+			badRequest(w, "unknown permission "+p+", allowed: "+strings.Join(policy.APIKeyPermissions, ", "))
+			return
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		badRequest(w, "expiresAt must be in the future")
+		return
+	}
+
+	key, prefix, err := newAPIKey()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to create api key"})
+		return
+	}
+	created, err := h.keys.Create(r.Context(), repo.APIKey{
+		EmployerID:  req.EmployerID,
+		Name:        req.Name,
+		Prefix:      prefix,
+		Permissions: req.Permissions,
+		CreatedBy:   actorUUID(tc),
+		ExpiresAt:   req.ExpiresAt,
+	}, hashToken(key))
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to create api key"})
+		return
+	}
+
+	record(r, claimsActor(tc), "apikey.create", "api_key", created.ID.String(), nil, created)
+
+	writeJSON(w, http.StatusCreated, apiKeyResp{APIKey: created, Key: key})
+}
+
+// GET /api/v1/auth/api-keys?employerId=
+func (h *APIKeyHandler) List(w http.ResponseWriter, r *http.Request) {
+	var employerID *uuid.UUID
+	if s := r.URL.Query().Get("employerId"); s != "" {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			badRequest(w, "invalid employerId")
+			return
+		}
+		employerID = &id
+	}
+
+	keys, err := h.keys.List(r.Context(), employerID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to list api keys"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"keys": keys})
+}
+
+// POST /api/v1/auth/api-keys/{id}/rotate
+// Opoziva kljuc i izdaje novi sa istim dozvolama
+func (h *APIKeyHandler) Rotate(w http.ResponseWriter, r *http.Request) {
+	tc := claimsFromContext(r.Context())
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		badRequest(w, "invalid id")
+		return
+	}
+
+	key, prefix, err := newAPIKey()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to rotate api key"})
+		return
+	}
+	created, err := h.keys.Rotate(r.Context(), id, prefix, hashToken(key), actorUUID(tc))
+	if err != nil {
+		if errors.Is(err, repo.ErrAPIKeyNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "active api key not found"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to rotate api key"})
+		return
+	}
+
+	record(r, claimsActor(tc), "apikey.rotate", "api_key", id.String(), nil, created)
+
+	writeJSON(w, http.StatusCreated, apiKeyResp{APIKey: created, Key: key})
+}
+
+// DELETE /api/v1/auth/api-keys/{id}
+func (h *APIKeyHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	tc := claimsFromContext(r.Context())
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		badRequest(w, "invalid id")
+		return
+	}
+
+	revoked, err := h.keys.Revoke(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repo.ErrAPIKeyNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "active api key not found"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to revoke api key"})
+		return
+	}
+
+	record(r, claimsActor(tc), "apikey.revoke", "api_key", id.String(), nil, revoked)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /api/v1/auth/api-keys/{id}/usage?days=30
+// Ukupan broj zahtjeva kljuca i dnevni brojaci za poslednjih days dana
+func (h *APIKeyHandler) Usage(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		badRequest(w, "invalid id")
+		return
+	}
+	days := 30
+	if d, err := strconv.Atoi(r.URL.Query().Get("days")); err == nil && d > 0 {
+		days = min(d, maxUsageDays)
+	}
+
+	k, err := h.keys.GetByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repo.ErrAPIKeyNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to load usage"})
+		return
+	}
+	daily, err := h.keys.Usage(r.Context(), id, time.Now().AddDate(0, 0, -(days-1)))
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to load usage"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"id":         k.ID,
+		"usageCount": k.UsageCount,
+		"lastUsedAt": k.LastUsedAt,
+		"daily":      daily,
+	})
+}
+
+type introspectReq struct {
+	Key string `json:"key"`
+}
+
+// POST /api/v1/auth/internal/api-keys/introspect  (samo service token)
+// employmentOffice provjerava kljuc iz X-API-Key header-a; svaka uspjesna
+// provjera se broji kao upotreba kljuca
+func (h *APIKeyHandler) Introspect(w http.ResponseWriter, r *http.Request) {
+	var req introspectReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, "invalid JSON body")
+		return
+	}
+	if !strings.HasPrefix(req.Key, apiKeyPrefix) {
+		writeJSON(w, http.StatusOK, map[string]any{"active": false})
+		return
+	}
+
+	k, err := h.keys.Use(r.Context(), hashToken(req.Key))
+	if err != nil {
+		if errors.Is(err, repo.ErrInvalidAPIKey) {
+			record(r, audit.Actor{ID: apiKeyPrefix + apiKeyDisplayPrefix(req.Key), Role: policy.EmployerRole},
+				"apikey.auth_failed", "api_key", "", nil, nil)
+			writeJSON(w, http.StatusOK, map[string]any{"active": false})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to verify api key"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"active":      true,
+		"id":          k.ID,
+		"employerId":  k.EmployerID,
+		"name":        k.Name,
+		"permissions": k.Permissions,
+	})
+}
+
+// newAPIKey vraca novi kljuc i njegov javni prefix (za prepoznavanje u listi)
+func newAPIKey() (string, string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	secret, err := newOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+	prefix := hex.EncodeToString(b)
+	return apiKeyPrefix + prefix + "_" + secret, prefix, nil
+}
+
+// apiKeyDisplayPrefix izdvaja javni dio kljuca, tajna se nikad ne loguje
+func apiKeyDisplayPrefix(key string) string {
+	prefix, _, _ := strings.Cut(strings.TrimPrefix(key, apiKeyPrefix), "_")
+	if len(prefix) > 12 {
+		prefix = prefix[:12]
+	}
+	return prefix
+}
+
+func actorUUID(tc *TokenClaims) *uuid.UUID {
+	id, err := uuid.Parse(tc.UserID)
+	if err != nil {
+		return nil
+	}
+	return &id
+}
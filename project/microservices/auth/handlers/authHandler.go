@@ -1,29 +1,96 @@
 package handlers
 
 import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
 	"fmt"
+	"net/http"
+	"strings"
 	"time"
 
+	"github.com/Bijelic03/eAdministration/project/microservices/auth/policy"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
+// Issuer se upisuje u svaki token; ostali servisi ga provjeravaju pri lokalnoj verifikaciji
+const Issuer = "eadministration-auth"
+
 type TokenClaims struct {
+	ID        string    `json:"jti"`
+	UserID    string    `json:"sub"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	SessionID string    `json:"sid"`
+	Perms     []string  `json:"perms"`
+	Scopes    []string  `json:"scope"`
+	ExpiresAt time.Time `json:"exp"`
+
+	// Actor je administrator koji impersonira korisnika (claim "act", RFC 8693)
+	Actor *TokenActor `json:"act,omitempty"`
+}
+
+// TokenActor je stvarni korisnik iza impersonacionog tokena
+type TokenActor struct {
+	ID    string `json:"sub"`
 	Email string `json:"email"`
 	Role  string `json:"role"`
 }
 
+// ServiceSubjectPrefix oznacava sub service tokena (npr. "service:university")
+const ServiceSubjectPrefix = "service:"
+
+// Auth potpisuje tokene Ed25519 (EdDSA) kljucem; javni kljuc se objavljuje
+// kroz JWKS endpoint pa university i employmentOffice verifikuju tokene lokalno
 type Auth struct {
-	SecretKey []byte
+	privateKey ed25519.PrivateKey
+	publicKey  ed25519.PublicKey
+	keyID      string
+}
+
+func NewAuth(privateKey ed25519.PrivateKey) *Auth {
+	pub := privateKey.Public().(ed25519.PublicKey)
+	sum := sha256.Sum256(pub)
+	return &Auth{
+		privateKey: privateKey,
+		publicKey:  pub,
+		keyID:      base64.RawURLEncoding.EncodeToString(sum[:12]),
+	}
+}
+
+// ParsePrivateKey cita Ed25519 privatni kljuc iz PEM (PKCS#8) formata
+func ParsePrivateKey(pemBytes []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	edKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is not an Ed25519 key")
+	}
+	return edKey, nil
 }
 
-func NewAuth(secretKey []byte) *Auth {
-	return &Auth{SecretKey: secretKey}
+// GenerateKey pravi privremeni kljuc kada JWT_PRIVATE_KEY_FILE nije postavljen
+func GenerateKey() (ed25519.PrivateKey, error) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	return priv, err
 }
 
 func (a *Auth) VerifyToken(tokenString string) (*TokenClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &jwt.MapClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return a.SecretKey, nil
-	})
+		return a.publicKey, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg()}), jwt.WithIssuer(Issuer))
 	if err != nil {
 		return nil, err
 	}
@@ -37,28 +104,160 @@ func (a *Auth) VerifyToken(tokenString string) (*TokenClaims, error) {
 	}
 
 	tc := &TokenClaims{}
+	if jti, ok := (*claims)["jti"].(string); ok {
+		tc.ID = jti
+	}
+	if sub, ok := (*claims)["sub"].(string); ok {
+		tc.UserID = sub
+	}
 	if email, ok := (*claims)["email"].(string); ok {
 		tc.Email = email
 	}
 	if role, ok := (*claims)["role"].(string); ok {
 		tc.Role = role
 	}
+	if sid, ok := (*claims)["sid"].(string); ok {
+		tc.SessionID = sid
+	}
+	if perms, ok := (*claims)["perms"].([]any); ok {
+		for _, p := range perms {
+			if s, ok := p.(string); ok {
+				tc.Perms = append(tc.Perms, s)
+			}
+		}
+	}
+	if scope, ok := (*claims)["scope"].(string); ok {
+		tc.Scopes = strings.Fields(scope)
+	}
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		tc.ExpiresAt = exp.Time
+	}
+	if act, ok := (*claims)["act"].(map[string]any); ok {
+		tc.Actor = &TokenActor{}
+		tc.Actor.ID, _ = act["sub"].(string)
+		tc.Actor.Email, _ = act["email"].(string)
+		tc.Actor.Role, _ = act["role"].(string)
+	}
 	return tc, nil
 }
 
-// NEW: generisanje JWT-a (HS256) sa exp
-func (a *Auth) GenerateToken(email string, role string, ttl time.Duration) (string, time.Time, error) {
+// generisanje access tokena (EdDSA) sa exp i jti, kako bi se mogao opozvati;
+// sid vezuje token za sesiju, pa opoziv sesije opoziva i njene tokene, a perms
+// su efektivne dozvole korisnika u trenutku izdavanja
+func (a *Auth) GenerateToken(userID, email, role, sessionID string, perms []string, ttl time.Duration) (string, time.Time, error) {
+	now := time.Now().UTC()
+	exp := now.Add(ttl)
+
+	mc := jwt.MapClaims{
+		"iss":   Issuer,
+		"jti":   uuid.NewString(),
+		"sub":   userID,
+		"email": email,
+		"role":  role,
+		"sid":   sessionID,
+		"perms": perms,
+		"iat":   now.Unix(),
+		"exp":   exp.Unix(),
+	}
+
+	t := jwt.NewWithClaims(jwt.SigningMethodEdDSA, mc)
+	t.Header["kid"] = a.keyID
+	signed, err := t.SignedString(a.privateKey)
+	return signed, exp, err
+}
+
+// GenerateImpersonationToken izdaje token kojim administrator (actor) gleda
+// aplikaciju kao korisnik userID; sid je sesija administratora, pa odjava
+// administratora prekida i impersonaciju. Refresh token se ne izdaje.
+func (a *Auth) GenerateImpersonationToken(userID, email, role string, perms []string, actor TokenActor, sessionID string, ttl time.Duration) (string, time.Time, error) {
 	now := time.Now().UTC()
 	exp := now.Add(ttl)
 
 	mc := jwt.MapClaims{
+		"iss":   Issuer,
+		"jti":   uuid.NewString(),
+		"sub":   userID,
 		"email": email,
 		"role":  role,
+		"sid":   sessionID,
+		"perms": perms,
+		"act":   actor,
+		"iat":   now.Unix(),
+		"exp":   exp.Unix(),
+	}
+
+	t := jwt.NewWithClaims(jwt.SigningMethodEdDSA, mc)
+	t.Header["kid"] = a.keyID
+	signed, err := t.SignedString(a.privateKey)
+	return signed, exp, err
+}
+
+// GenerateServiceToken izdaje access token servisu (client credentials):
+// uloga je policy.ServiceRole, a dozvole su scope-ovi (claim "scope")
+func (a *Auth) GenerateServiceToken(clientID string, scopes []string, ttl time.Duration) (string, time.Time, error) {
+	now := time.Now().UTC()
+	exp := now.Add(ttl)
+
+	mc := jwt.MapClaims{
+		"iss":   Issuer,
+		"jti":   uuid.NewString(),
+		"sub":   ServiceSubjectPrefix + clientID,
+		"role":  policy.ServiceRole,
+		"scope": strings.Join(scopes, " "),
 		"iat":   now.Unix(),
 		"exp":   exp.Unix(),
 	}
 
-	t := jwt.NewWithClaims(jwt.SigningMethodHS256, mc)
-	signed, err := t.SignedString(a.SecretKey)
+	t := jwt.NewWithClaims(jwt.SigningMethodEdDSA, mc)
+	t.Header["kid"] = a.keyID
+	signed, err := t.SignedString(a.privateKey)
 	return signed, exp, err
 }
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Kid string `json:"kid"`
+	X   string `json:"x"`
+}
+
+// GET /api/v1/auth/jwks
+// Javni kljucevi za verifikaciju tokena (RFC 8037, OKP/Ed25519)
+func (a *Auth) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	writeJSON(w, http.StatusOK, map[string]any{
+		"keys": []jwk{{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			Alg: jwt.SigningMethodEdDSA.Alg(),
+			Use: "sig",
+			Kid: a.keyID,
+			X:   base64.RawURLEncoding.EncodeToString(a.publicKey),
+		}},
+	})
+}
+
+// newOpaqueToken vraca nasumican token koji se salje klijentu;
+// u bazi se cuva samo njegov hash (hashToken)
+func newOpaqueToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// deriveKey izvodi simetricni kljuc za datu namjenu iz kljuca za potpisivanje,
+// kako bi potpisani linkovi prestali da vaze zajedno sa rotacijom kljuca
+func (a *Auth) deriveKey(purpose string) []byte {
+	mac := hmac.New(sha256.New, a.privateKey.Seed())
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
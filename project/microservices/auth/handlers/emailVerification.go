@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	repo "github.com/Bijelic03/eAdministration/project/microservices/auth/repositories"
+	"github.com/Bijelic03/eAdministration/project/microservices/auth/services"
+	"github.com/google/uuid"
+)
+
+var errInvalidVerificationToken = errors.New("invalid or expired verification token")
+
+// verifikacioni email se moze poslati najvise 3 puta na sat po adresi
+var verificationEmailLimit = repo.LockoutPolicy{
+	MaxAttempts: 3,
+	Window:      time.Hour,
+	BaseLockout: time.Hour,
+	MaxLockout:  time.Hour,
+}
+
+type verificationClaims struct {
+	UserID    string `json:"sub"`
+	Email     string `json:"email"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+type verifyEmailReq struct {
+	Token string `json:"token"`
+}
+
+type resendVerificationReq struct {
+	Email string `json:"email"`
+}
+
+// checkLoginStatus odbija prijavu naloga koji nije aktivan
+func (h *UserHandler) checkLoginStatus(w http.ResponseWriter, u *repo.User) bool {
+	switch u.Status {
+	case repo.StatusActive:
+		return true
+	case repo.StatusPendingVerification:
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "email not verified"})
+	default:
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "account disabled"})
+	}
+	return false
+}
+
+func (h *UserHandler) verificationKey() []byte {
+	if h.cfg.EmailVerifySecret != "" {
+		return []byte(h.cfg.EmailVerifySecret)
+	}
+	return h.auth.deriveKey("email-verification")
+}
+
+// signVerificationToken pravi potpisan (HMAC-SHA256) token sa rokom vazenja;
+// token je vezan za email adresu, pa promjena adrese ponistava stare linkove
+func (h *UserHandler) signVerificationToken(u *repo.User, expiresAt time.Time) (string, error) {
+	payload, err := json.Marshal(verificationClaims{
+		UserID:    u.ID.String(),
+		Email:     strings.ToLower(u.Email),
+		ExpiresAt: expiresAt.Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, h.verificationKey())
+	mac.Write(payload)
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+func (h *UserHandler) parseVerificationToken(token string) (*verificationClaims, error) {
+	payloadPart, sigPart, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, errInvalidVerificationToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(payloadPart)
+	if err != nil {
+		return nil, errInvalidVerificationToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(sigPart)
+	if err != nil {
+		return nil, errInvalidVerificationToken
+	}
+
+	mac := hmac.New(sha256.New, h.verificationKey())
+	mac.Write(payload)
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return nil, errInvalidVerificationToken
+	}
+
+	var c verificationClaims
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return nil, errInvalidVerificationToken
+	}
+	if time.Now().Unix() > c.ExpiresAt {
+		return nil, errInvalidVerificationToken
+	}
+	return &c, nil
+}
+
+func (h *UserHandler) sendVerificationEmail(ctx context.Context, u *repo.User) error {
+	expiresAt := time.Now().Add(h.cfg.EmailVerifyTTL)
+	token, err := h.signVerificationToken(u, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	link := h.cfg.EmailVerifyURL + "?token=" + url.QueryEscape(token)
+	return h.notifier.Notify(ctx, services.Message{
+		To:      u.Email,
+		Subject: "Potvrda email adrese",
+		Body: fmt.Sprintf("Zdravo %s,\n\nnalog aktivirajte potvrdom email adrese na linku:\n%s\n\nLink vazi do %s.",
+			u.FullName, link, expiresAt.Format(time.RFC1123)),
+	})
+}
+
+// POST /api/v1/auth/email/verify
+// Aktivira samoregistrovan nalog tokenom iz verifikacionog linka; ponovljena
+// potvrda vec aktivnog naloga nije greska
+func (h *UserHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req verifyEmailReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, "invalid JSON body")
+		return
+	}
+
+	c, err := h.parseVerificationToken(strings.TrimSpace(req.Token))
+	if err != nil {
+		badRequest(w, err.Error())
+		return
+	}
+	userID, err := uuid.Parse(c.UserID)
+	if err != nil {
+		badRequest(w, errInvalidVerificationToken.Error())
+		return
+	}
+
+	u, err := h.repo.VerifyEmail(r.Context(), userID, c.Email)
+	if err == nil {
+		record(r, userActor(u), "user.email_verify", "user", u.ID.String(), nil, userResp(u))
+	} else {
+		if !errors.Is(err, repo.ErrUserNotFound) {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to verify email"})
+			return
+		}
+		// nalog je vec potvrdjen, ili je u medjuvremenu promijenjen email
+		u, err = h.repo.GetByID(r.Context(), userID)
+		if err != nil || u.Status == repo.StatusPendingVerification || !strings.EqualFold(u.Email, c.Email) {
+			badRequest(w, errInvalidVerificationToken.Error())
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, userResp(u))
+}
+
+// POST /api/v1/auth/email/verify/resend
+// Ponovo salje verifikacioni link. Odgovor je uvijek 202, kako endpoint ne bi
+// otkrivao registrovane adrese.
+func (h *UserHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	var req resendVerificationReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, "invalid JSON body")
+		return
+	}
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	if email == "" {
+		badRequest(w, "email is required")
+		return
+	}
+
+	accepted := map[string]string{"message": "if the account is awaiting verification, a new link has been sent"}
+
+	until, err := h.throttles.LockedUntil(r.Context(), repo.ThrottleVerificationEmail, email)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to resend verification"})
+		return
+	}
+	if !until.IsZero() {
+		writeJSON(w, http.StatusAccepted, accepted)
+		return
+	}
+
+	u, err := h.repo.GetByEmail(r.Context(), email)
+	if err != nil {
+		if !errors.Is(err, repo.ErrUserNotFound) {
+			log.Println("verification resend lookup failed:", err)
+		}
+		writeJSON(w, http.StatusAccepted, accepted)
+		return
+	}
+	if u.Status != repo.StatusPendingVerification {
+		writeJSON(w, http.StatusAccepted, accepted)
+		return
+	}
+
+	if _, err := h.throttles.RegisterFailure(r.Context(), repo.ThrottleVerificationEmail, email, verificationEmailLimit); err != nil {
+		log.Println("verification resend throttle failed:", err)
+	}
+	if err := h.sendVerificationEmail(r.Context(), u); err != nil {
+		log.Println("verification email failed:", err)
+	}
+
+	writeJSON(w, http.StatusAccepted, accepted)
+}
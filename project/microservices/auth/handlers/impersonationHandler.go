@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/Bijelic03/eAdministration/project/microservices/auth/policy"
+	repo "github.com/Bijelic03/eAdministration/project/microservices/auth/repositories"
+)
+
+// maksimalna duzina obrazlozenja impersonacije
+const maxImpersonationReason = 500
+
+type impersonateReq struct {
+	Reason string `json:"reason"`
+}
+
+// POST /api/v1/auth/users/{id}/impersonate
+// Administrator dobija kratkotrajan token kojim vidi aplikaciju kao korisnik
+// iz svog domena (npr. da reprodukuje prijavu studenta). Token nosi i stvarnog
+// aktera (claim "act"), samo je za citanje i ne moze se osvjeziti; prekida se
+// odjavom sa tim tokenom ili odjavom administratora.
+func (h *UserHandler) Impersonate(w http.ResponseWriter, r *http.Request) {
+	tc := claimsFromContext(r.Context())
+
+	var req impersonateReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, "invalid JSON body")
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" || len(req.Reason) > maxImpersonationReason {
+		badRequest(w, "reason is required (at most 500 characters)")
+		return
+	}
+
+	u, ok := h.manageableUser(w, r)
+	if !ok {
+		return
+	}
+	if u.ID.String() == tc.UserID {
+		badRequest(w, "cannot impersonate yourself")
+		return
+	}
+	if u.Role == repo.RoleFacultyAdmin || u.Role == repo.RoleSSZAdmin {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "cannot impersonate administrators"})
+		return
+	}
+	if !u.Active() {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": "account is not active"})
+		return
+	}
+
+	extra, err := h.roles.UserPermissions(r.Context(), u.ID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to impersonate user"})
+		return
+	}
+	perms := policy.EffectivePermissions(string(u.Role), extra)
+	actor := TokenActor{ID: tc.UserID, Email: tc.Email, Role: tc.Role}
+
+	token, exp, err := h.auth.GenerateImpersonationToken(u.ID.String(), u.Email, string(u.Role), perms, actor, tc.SessionID, h.cfg.ImpersonationTTL)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to impersonate user"})
+		return
+	}
+
+	record(r, claimsActor(tc), "user.impersonate", "user", u.ID.String(), nil, map[string]any{
+		"reason":    req.Reason,
+		"expiresAt": exp,
+	})
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"token":     token,
+		"expiresAt": exp,
+		"readOnly":  true,
+		"user": map[string]any{
+			"id":       u.ID,
+			"fullName": u.FullName,
+			"email":    u.Email,
+			"role":     u.Role,
+			"perms":    perms,
+		},
+		"actor": actor,
+	})
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	repo "github.com/Bijelic03/eAdministration/project/microservices/auth/repositories"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func (h *UserHandler) accountLockout() repo.LockoutPolicy {
+	return repo.LockoutPolicy{
+		MaxAttempts: h.cfg.LoginMaxAccountAttempts,
+		Window:      h.cfg.LoginAttemptWindow,
+		BaseLockout: h.cfg.LoginLockoutBase,
+		MaxLockout:  h.cfg.LoginLockoutMax,
+	}
+}
+
+func (h *UserHandler) ipLockout() repo.LockoutPolicy {
+	return repo.LockoutPolicy{
+		MaxAttempts: h.cfg.LoginMaxIPAttempts,
+		Window:      h.cfg.LoginAttemptWindow,
+		BaseLockout: h.cfg.LoginLockoutBase,
+		MaxLockout:  h.cfg.LoginLockoutMax,
+	}
+}
+
+// checkLoginThrottle odbija prijavu dok je IP adresa (429) ili nalog (423)
+// privremeno zakljucan; oba odgovora nose Retry-After header
+func (h *UserHandler) checkLoginThrottle(w http.ResponseWriter, r *http.Request, account, ip string) bool {
+	return h.checkIPThrottle(w, r, ip) && h.checkAccountThrottle(w, r, account)
+}
+
+func (h *UserHandler) checkIPThrottle(w http.ResponseWriter, r *http.Request, ip string) bool {
+	until, err := h.throttles.LockedUntil(r.Context(), repo.ThrottleIP, ip)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to login"})
+		return false
+	}
+	if !until.IsZero() {
+		writeLocked(w, http.StatusTooManyRequests, "too many login attempts", until)
+		return false
+	}
+	return true
+}
+
+func (h *UserHandler) checkAccountThrottle(w http.ResponseWriter, r *http.Request, account string) bool {
+	until, err := h.throttles.LockedUntil(r.Context(), repo.ThrottleAccount, account)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to login"})
+		return false
+	}
+	if !until.IsZero() {
+		writeLocked(w, http.StatusLocked, "account temporarily locked", until)
+		return false
+	}
+	return true
+}
+
+// registerLoginFailure uvecava brojace za nalog i IP adresu; brojac naloga se
+// vodi i za nepostojece emailove, kako odgovor ne bi otkrivao da li nalog postoji
+func (h *UserHandler) registerLoginFailure(r *http.Request, account, ip string) {
+	if _, err := h.throttles.RegisterFailure(r.Context(), repo.ThrottleAccount, account, h.accountLockout()); err != nil {
+		log.Println("login throttle (account) failed:", err)
+	}
+	if _, err := h.throttles.RegisterFailure(r.Context(), repo.ThrottleIP, ip, h.ipLockout()); err != nil {
+		log.Println("login throttle (ip) failed:", err)
+	}
+}
+
+// resetLoginThrottle brise brojac naloga tek kada je prijava potpuna (lozinka
+// i, ako je ukljucen, 2FA kod)
+func (h *UserHandler) resetLoginThrottle(r *http.Request, account string) {
+	if err := h.throttles.Reset(r.Context(), repo.ThrottleAccount, account); err != nil {
+		log.Println("login throttle reset failed:", err)
+	}
+}
+
+func writeLocked(w http.ResponseWriter, status int, msg string, until time.Time) {
+	retryAfter := int(math.Ceil(time.Until(until).Seconds()))
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	writeJSON(w, status, map[string]any{
+		"error":      msg,
+		"retryAfter": retryAfter,
+	})
+}
+
+// clientIP vraca adresu klijenta; X-Forwarded-For se koristi samo iza
+// proxy-ja kome se vjeruje (TRUST_PROXY_HEADERS=true)
+func (h *UserHandler) clientIP(r *http.Request) string {
+	if h.cfg.TrustProxyHeaders {
+		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+			return strings.TrimSpace(strings.Split(xff, ",")[0])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// POST /api/v1/auth/users/{id}/unlock
+// Administrator otkljucava nalog iz svog domena prije isteka zakljucavanja
+func (h *UserHandler) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	tc := claimsFromContext(r.Context())
+
+	userID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		badRequest(w, "invalid id")
+		return
+	}
+
+	target, err := h.repo.GetByID(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, repo.ErrUserNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to unlock user"})
+		return
+	}
+	if !canManage(tc.Role, target.Role) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+		return
+	}
+
+	if err := h.throttles.Reset(r.Context(), repo.ThrottleAccount, strings.ToLower(target.Email)); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to unlock user"})
+		return
+	}
+
+	record(r, claimsActor(tc), "user.unlock", "user", userID.String(), nil, nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
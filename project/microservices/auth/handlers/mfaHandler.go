@@ -0,0 +1,509 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	repo "github.com/Bijelic03/eAdministration/project/microservices/auth/repositories"
+	"github.com/Bijelic03/eAdministration/project/microservices/auth/services"
+	"github.com/Bijelic03/eAdministration/project/microservices/common/audit"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+const recoveryCodeCount = 10
+
+type mfaCodeReq struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recoveryCode"`
+}
+
+type mfaLoginReq struct {
+	ChallengeToken string `json:"challengeToken"`
+	Code           string `json:"code"`
+	RecoveryCode   string `json:"recoveryCode"`
+}
+
+type mfaEnrollReq struct {
+	ChallengeToken string `json:"challengeToken"`
+}
+
+func (h *UserHandler) mfaRequired(role repo.UserRole) bool {
+	for _, r := range h.cfg.MFARequiredRoles {
+		if repo.UserRole(r) == role {
+			return true
+		}
+	}
+	return false
+}
+
+// startMFAChallenge zavrsava prvi korak prijave: umjesto tokena vraca
+// kratkotrajni challenge token; enrollmentRequired znaci da korisnik cija uloga
+// zahtijeva 2FA jos nije upisao TOTP i da to mora uraditi prije prijave
+func (h *UserHandler) startMFAChallenge(w http.ResponseWriter, r *http.Request, u *repo.User, enrollmentRequired bool) {
+	challenge, err := newOpaqueToken()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to login"})
+		return
+	}
+	expiresAt := time.Now().Add(h.cfg.MFAChallengeTTL)
+	if err := h.mfa.CreateChallenge(r.Context(), u.ID, hashToken(challenge), expiresAt); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to login"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"mfaRequired":        true,
+		"enrollmentRequired": enrollmentRequired,
+		"challengeToken":     challenge,
+		"expiresAt":          expiresAt,
+	})
+}
+
+// POST /api/v1/auth/login/mfa
+// Drugi korak prijave: challenge token + TOTP kod (ili recovery kod).
+// Ako je upis bio obavezan, prvi ispravan kod ujedno aktivira TOTP i odgovor
+// sadrzi recovery kodove.
+func (h *UserHandler) LoginMFA(w http.ResponseWriter, r *http.Request) {
+	var req mfaLoginReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, "invalid JSON body")
+		return
+	}
+	req.ChallengeToken = strings.TrimSpace(req.ChallengeToken)
+	if req.ChallengeToken == "" || (req.Code == "" && req.RecoveryCode == "") {
+		badRequest(w, "challengeToken and code or recoveryCode are required")
+		return
+	}
+	challengeHash := hashToken(req.ChallengeToken)
+
+	// pogresni kodovi se broje kao neuspjele prijave, pa zakljucavanje IP
+	// adrese i naloga ogranicava i pogadjanje TOTP koda
+	ip := h.clientIP(r)
+	if !h.checkIPThrottle(w, r, ip) {
+		return
+	}
+
+	userID, err := h.mfa.GetChallenge(r.Context(), challengeHash)
+	if err != nil {
+		if errors.Is(err, repo.ErrInvalidMFAChallenge) {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to login"})
+		return
+	}
+
+	u, err := h.repo.GetByID(r.Context(), userID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to login"})
+		return
+	}
+	account := strings.ToLower(u.Email)
+	if !h.checkAccountThrottle(w, r, account) {
+		return
+	}
+
+	enrollment, err := h.mfa.GetTOTP(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, repo.ErrMFANotEnrolled) {
+			badRequest(w, "totp enrollment required")
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to login"})
+		return
+	}
+
+	var recoveryCodes []string
+	ok := false
+	if enrollment.Enabled() {
+		ok, err = h.verifyMFA(r.Context(), enrollment, req.Code, req.RecoveryCode)
+	} else if step, valid := services.ValidateTOTP(enrollment.Secret, req.Code, time.Now()); valid {
+		recoveryCodes, err = h.enableTOTP(r.Context(), userID, step)
+		ok = err == nil
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to login"})
+		return
+	}
+	if !ok {
+		if err := h.mfa.FailChallenge(r.Context(), challengeHash); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to login"})
+			return
+		}
+		h.registerLoginFailure(r, account, ip)
+		record(r, h.actorByID(r.Context(), userID), "auth.mfa_failed", "user", userID.String(), nil,
+			map[string]string{"ip": ip})
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid code"})
+		return
+	}
+
+	if err := h.mfa.ConsumeChallenge(r.Context(), challengeHash); err != nil {
+		if errors.Is(err, repo.ErrInvalidMFAChallenge) {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to login"})
+		return
+	}
+
+	if !h.checkLoginStatus(w, u) {
+		return
+	}
+	resp, err := h.issueTokens(r, u)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to issue token"})
+		return
+	}
+	h.resetLoginThrottle(r, account)
+
+	writeJSON(w, http.StatusOK, struct {
+		*authResp
+		RecoveryCodes []string `json:"recoveryCodes,omitempty"`
+	}{resp, recoveryCodes})
+}
+
+// GET /api/v1/auth/mfa
+// Stanje 2FA prijavljenog korisnika
+func (h *UserHandler) MFAStatus(w http.ResponseWriter, r *http.Request) {
+	tc, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+	userID, err := uuid.Parse(tc.UserID)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+		return
+	}
+
+	enrollment, err := h.mfa.GetTOTP(r.Context(), userID)
+	if err != nil && !errors.Is(err, repo.ErrMFANotEnrolled) {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to load mfa status"})
+		return
+	}
+	remaining := 0
+	if enrollment.Enabled() {
+		if remaining, err = h.mfa.CountRecoveryCodes(r.Context(), userID); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to load mfa status"})
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"enabled":                enrollment.Enabled(),
+		"required":               h.mfaRequired(repo.UserRole(tc.Role)),
+		"recoveryCodesRemaining": remaining,
+	})
+}
+
+// POST /api/v1/auth/mfa/totp/enroll
+// Generise novi TOTP secret i otpauth URI (za QR kod). Poziva se sa access
+// tokenom, ili sa challengeToken-om iz prijave kada je upis obavezan.
+func (h *UserHandler) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	var req mfaEnrollReq
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			badRequest(w, "invalid JSON body")
+			return
+		}
+	}
+
+	var userID uuid.UUID
+	if challenge := strings.TrimSpace(req.ChallengeToken); challenge != "" {
+		id, err := h.mfa.GetChallenge(r.Context(), hashToken(challenge))
+		if err != nil {
+			if errors.Is(err, repo.ErrInvalidMFAChallenge) {
+				writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+				return
+			}
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to enroll"})
+			return
+		}
+		userID = id
+	} else {
+		tc, ok := h.authenticate(w, r)
+		if !ok {
+			return
+		}
+		id, err := uuid.Parse(tc.UserID)
+		if err != nil {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+			return
+		}
+		userID = id
+	}
+
+	u, err := h.repo.GetByID(r.Context(), userID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to enroll"})
+		return
+	}
+
+	secret, err := services.NewTOTPSecret()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to enroll"})
+		return
+	}
+	if err := h.mfa.SaveTOTPSecret(r.Context(), userID, secret); err != nil {
+		if errors.Is(err, repo.ErrMFAAlreadyEnabled) {
+			writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to enroll"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"secret":     secret,
+		"otpauthUri": services.TOTPURI(h.cfg.MFAIssuer, u.Email, secret),
+	})
+}
+
+// POST /api/v1/auth/mfa/totp/confirm
+// Aktivira upisani secret prvim ispravnim kodom i vraca recovery kodove
+// (prikazuju se samo jednom)
+func (h *UserHandler) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	userID, req, ok := h.mfaRequest(w, r)
+	if !ok {
+		return
+	}
+
+	enrollment, err := h.mfa.GetTOTP(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, repo.ErrMFANotEnrolled) {
+			badRequest(w, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to confirm totp"})
+		return
+	}
+	if enrollment.Enabled() {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": repo.ErrMFAAlreadyEnabled.Error()})
+		return
+	}
+
+	step, valid := services.ValidateTOTP(enrollment.Secret, req.Code, time.Now())
+	if !valid {
+		badRequest(w, "invalid code")
+		return
+	}
+	codes, err := h.enableTOTP(r.Context(), userID, step)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to confirm totp"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"recoveryCodes": codes})
+}
+
+// POST /api/v1/auth/mfa/totp/disable
+// Iskljucuje 2FA uz vazeci kod; nije dozvoljeno ulogama za koje je 2FA obavezan
+func (h *UserHandler) DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	tc, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+	if h.mfaRequired(repo.UserRole(tc.Role)) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "two-factor authentication is mandatory for this role"})
+		return
+	}
+
+	userID, req, ok := h.mfaRequestFor(w, r, tc)
+	if !ok {
+		return
+	}
+	enrollment, ok := h.enabledTOTP(w, r, userID)
+	if !ok {
+		return
+	}
+
+	valid, err := h.verifyMFA(r.Context(), enrollment, req.Code, req.RecoveryCode)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to disable totp"})
+		return
+	}
+	if !valid {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid code"})
+		return
+	}
+
+	if err := h.mfa.DisableTOTP(r.Context(), userID); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to disable totp"})
+		return
+	}
+
+	record(r, claimsActor(tc), "mfa.disable", "user", userID.String(), nil, nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST /api/v1/auth/mfa/recovery-codes
+// Generise nove recovery kodove uz vazeci TOTP kod; stari kodovi prestaju da vaze
+func (h *UserHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	userID, req, ok := h.mfaRequest(w, r)
+	if !ok {
+		return
+	}
+	enrollment, ok := h.enabledTOTP(w, r, userID)
+	if !ok {
+		return
+	}
+
+	valid, err := h.verifyMFA(r.Context(), enrollment, req.Code, "")
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to regenerate recovery codes"})
+		return
+	}
+	if !valid {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid code"})
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to regenerate recovery codes"})
+		return
+	}
+	if err := h.mfa.ReplaceRecoveryCodes(r.Context(), userID, hashes); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to regenerate recovery codes"})
+		return
+	}
+
+	record(r, h.actorByID(r.Context(), userID), "mfa.recovery_codes", "user", userID.String(), nil, nil)
+
+	writeJSON(w, http.StatusOK, map[string]any{"recoveryCodes": codes})
+}
+
+// DELETE /api/v1/auth/users/{id}/mfa
+// Administrator ponistava 2FA korisniku iz svog domena (izgubljen uredjaj i
+// recovery kodovi); korisnik ponovo upisuje TOTP pri sljedecoj prijavi
+func (h *UserHandler) ResetUserMFA(w http.ResponseWriter, r *http.Request) {
+	tc := claimsFromContext(r.Context())
+
+	userID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		badRequest(w, "invalid id")
+		return
+	}
+
+	target, err := h.repo.GetByID(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, repo.ErrUserNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to reset mfa"})
+		return
+	}
+	if !canManage(tc.Role, target.Role) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden"})
+		return
+	}
+
+	if err := h.mfa.DisableTOTP(r.Context(), userID); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to reset mfa"})
+		return
+	}
+	if err := h.tokens.RevokeAllForUser(r.Context(), userID); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to revoke sessions"})
+		return
+	}
+
+	record(r, claimsActor(tc), "mfa.reset", "user", userID.String(), nil, nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// mfaRequest autentifikuje korisnika i cita body sa kodom
+func (h *UserHandler) mfaRequest(w http.ResponseWriter, r *http.Request) (uuid.UUID, mfaCodeReq, bool) {
+	tc, ok := h.authenticate(w, r)
+	if !ok {
+		return uuid.Nil, mfaCodeReq{}, false
+	}
+	return h.mfaRequestFor(w, r, tc)
+}
+
+func (h *UserHandler) mfaRequestFor(w http.ResponseWriter, r *http.Request, tc *TokenClaims) (uuid.UUID, mfaCodeReq, bool) {
+	userID, err := uuid.Parse(tc.UserID)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+		return uuid.Nil, mfaCodeReq{}, false
+	}
+
+	var req mfaCodeReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, "invalid JSON body")
+		return uuid.Nil, mfaCodeReq{}, false
+	}
+	if req.Code == "" && req.RecoveryCode == "" {
+		badRequest(w, "code is required")
+		return uuid.Nil, mfaCodeReq{}, false
+	}
+	return userID, req, true
+}
+
+func (h *UserHandler) enabledTOTP(w http.ResponseWriter, r *http.Request, userID uuid.UUID) (*repo.TOTPEnrollment, bool) {
+	enrollment, err := h.mfa.GetTOTP(r.Context(), userID)
+	if err != nil && !errors.Is(err, repo.ErrMFANotEnrolled) {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to load mfa status"})
+		return nil, false
+	}
+	if !enrollment.Enabled() {
+		badRequest(w, repo.ErrMFANotEnrolled.Error())
+		return nil, false
+	}
+	return enrollment, true
+}
+
+// verifyMFA provjerava TOTP kod (koji se ne moze iskoristiti dva puta) ili
+// jednokratni recovery kod
+func (h *UserHandler) verifyMFA(ctx context.Context, e *repo.TOTPEnrollment, code, recoveryCode string) (bool, error) {
+	if recoveryCode != "" {
+		return h.mfa.UseRecoveryCode(ctx, e.UserID, hashToken(normalizeRecoveryCode(recoveryCode)))
+	}
+	step, ok := services.ValidateTOTP(e.Secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+	return h.mfa.UseTOTPStep(ctx, e.UserID, step)
+}
+
+func (h *UserHandler) enableTOTP(ctx context.Context, userID uuid.UUID, step int64) ([]string, error) {
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := h.mfa.EnableTOTP(ctx, userID, step, hashes); err != nil {
+		return nil, err
+	}
+	audit.Record(audit.WithActor(ctx, h.actorByID(ctx, userID)), "mfa.enable", "user", userID.String(), nil, nil)
+	return codes, nil
+}
+
+// newRecoveryCodes vraca recovery kodove (xxxxx-xxxxx) i njihove hash-eve za bazu
+func newRecoveryCodes() ([]string, []string, error) {
+	enc := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		c := strings.ToLower(enc.EncodeToString(b)[:10])
+		codes = append(codes, c[:5]+"-"+c[5:])
+		hashes = append(hashes, hashToken(c))
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
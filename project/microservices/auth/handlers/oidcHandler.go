@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	repo "github.com/Bijelic03/eAdministration/project/microservices/auth/repositories"
+	"github.com/Bijelic03/eAdministration/project/microservices/auth/services"
+	"github.com/Bijelic03/eAdministration/project/microservices/common/audit"
+)
+
+type oidcCallbackReq struct {
+	Code  string `json:"code"`
+	State string `json:"state"`
+}
+
+// GET /api/v1/auth/oidc
+// Da li je SSO prijava ukljucena (frontend prema tome prikazuje dugme)
+func (h *UserHandler) OIDCConfig(w http.ResponseWriter, r *http.Request) {
+	if h.oidc == nil {
+		writeJSON(w, http.StatusOK, map[string]any{"enabled": false})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"enabled": true,
+		"issuer":  h.oidc.Issuer(),
+	})
+}
+
+// POST /api/v1/auth/oidc/start
+// Zapocinje SSO prijavu: vraca adresu providera na koju frontend preusmjerava
+// korisnika i state koji frontend cuva do povratka (authorization code + PKCE)
+func (h *UserHandler) StartOIDC(w http.ResponseWriter, r *http.Request) {
+	if h.oidc == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "single sign-on is not configured"})
+		return
+	}
+
+	state, err := newOpaqueToken()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to start sign-on"})
+		return
+	}
+	nonce, err := newOpaqueToken()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to start sign-on"})
+		return
+	}
+	verifier, err := services.NewPKCEVerifier()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to start sign-on"})
+		return
+	}
+
+	authURL, err := h.oidc.AuthorizationURL(r.Context(), state, nonce, verifier)
+	if err != nil {
+		log.Println("oidc start failed:", err)
+		writeJSON(w, http.StatusBadGateway, map[string]string{"error": "identity provider unavailable"})
+		return
+	}
+	expiresAt := time.Now().Add(h.cfg.OIDCStateTTL)
+	if err := h.identities.CreateOIDCState(r.Context(), hashToken(state), verifier, nonce, expiresAt); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to start sign-on"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"authorizationUrl": authURL,
+		"state":            state,
+		"expiresAt":        expiresAt,
+	})
+}
+
+// POST /api/v1/auth/oidc/callback
+// Zavrsava SSO prijavu kodom koji je provider vratio; vanjski identitet se
+// mapira na postojeci nalog (povezani identitet ili potvrdjena email adresa),
+// a dalje je prijava ista kao lozinkom (2FA, sesija, tokeni)
+func (h *UserHandler) OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	if h.oidc == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "single sign-on is not configured"})
+		return
+	}
+
+	var req oidcCallbackReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, "invalid JSON body")
+		return
+	}
+	req.Code = strings.TrimSpace(req.Code)
+	req.State = strings.TrimSpace(req.State)
+	if req.Code == "" || req.State == "" {
+		badRequest(w, "code and state are required")
+		return
+	}
+
+	verifier, nonce, err := h.identities.ConsumeOIDCState(r.Context(), hashToken(req.State))
+	if err != nil {
+		if errors.Is(err, repo.ErrInvalidOIDCState) {
+			badRequest(w, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to login"})
+		return
+	}
+
+	id, err := h.oidc.Exchange(r.Context(), req.Code, verifier, nonce)
+	if err != nil {
+		log.Println("oidc exchange failed:", err)
+		record(r, audit.Actor{}, "auth.sso_failed", "user", "", nil, map[string]string{"reason": "exchange failed"})
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "sign-on failed"})
+		return
+	}
+
+	u, status, msg := h.oidcUser(r, id)
+	if u == nil {
+		if status != http.StatusInternalServerError {
+			record(r, audit.Actor{Email: id.Email}, "auth.sso_failed", "user", "", nil, map[string]string{
+				"issuer": id.Issuer,
+				"reason": msg,
+			})
+		}
+		writeJSON(w, status, map[string]string{"error": msg})
+		return
+	}
+	if !h.checkLoginStatus(w, u) {
+		return
+	}
+	h.completeLogin(w, r, u)
+}
+
+// oidcUser pronalazi nalog za vanjski identitet; identitet bez veze se
+// povezuje sa nalogom iste (potvrdjene) email adrese. Nalozi se ne otvaraju
+// automatski, a SSO je dozvoljen samo ulogama iz OIDC_ROLES.
+func (h *UserHandler) oidcUser(r *http.Request, id *services.OIDCIdentity) (*repo.User, int, string) {
+	ctx := r.Context()
+
+	var u *repo.User
+	userID, err := h.identities.TouchIdentity(ctx, id.Issuer, id.Subject)
+	switch {
+	case err == nil:
+		if u, err = h.repo.GetByID(ctx, userID); err != nil {
+			return nil, http.StatusInternalServerError, "failed to login"
+		}
+	case errors.Is(err, repo.ErrIdentityNotFound):
+		if id.Email == "" {
+			return nil, http.StatusForbidden, "identity provider did not return an email address"
+		}
+		if h.cfg.OIDCRequireEmailVerified && !id.EmailVerified {
+			return nil, http.StatusForbidden, "email address is not verified by the identity provider"
+		}
+		if u, err = h.repo.GetByEmail(ctx, id.Email); err != nil {
+			if errors.Is(err, repo.ErrUserNotFound) {
+				return nil, http.StatusForbidden, "no account for this identity"
+			}
+			return nil, http.StatusInternalServerError, "failed to login"
+		}
+		if !h.oidcRoleAllowed(u.Role) {
+			return nil, http.StatusForbidden, "single sign-on is not available for this role"
+		}
+		if err := h.identities.LinkIdentity(ctx, u.ID, id.Issuer, id.Subject, id.Email); err != nil {
+			if errors.Is(err, repo.ErrIdentityConflict) {
+				return nil, http.StatusConflict, err.Error()
+			}
+			return nil, http.StatusInternalServerError, "failed to login"
+		}
+		record(r, userActor(u), "user.identity_link", "user", u.ID.String(), nil, map[string]string{
+			"issuer":  id.Issuer,
+			"subject": id.Subject,
+			"email":   id.Email,
+		})
+	default:
+		return nil, http.StatusInternalServerError, "failed to login"
+	}
+
+	if !h.oidcRoleAllowed(u.Role) {
+		return nil, http.StatusForbidden, "single sign-on is not available for this role"
+	}
+	return u, http.StatusOK, ""
+}
+
+func (h *UserHandler) oidcRoleAllowed(role repo.UserRole) bool {
+	for _, r := range h.cfg.OIDCRoles {
+		if repo.UserRole(r) == role {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	repo "github.com/Bijelic03/eAdministration/project/microservices/auth/repositories"
+	"github.com/Bijelic03/eAdministration/project/microservices/auth/services"
+	"github.com/google/uuid"
+)
+
+type changePasswordReq struct {
+	OldPassword string `json:"oldPassword"`
+	NewPassword string `json:"newPassword"`
+}
+
+type forgotPasswordReq struct {
+	Email string `json:"email"`
+}
+
+type resetPasswordReq struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"newPassword"`
+}
+
+// POST /api/v1/auth/password/change
+// Mijenja lozinku prijavljenog korisnika; sve sesije (refresh tokeni) se opozivaju,
+// a trenutni access token prestaje da vazi
+func (h *UserHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	tc, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	var req changePasswordReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, "invalid JSON body")
+		return
+	}
+	if req.OldPassword == "" || req.NewPassword == "" {
+		badRequest(w, "oldPassword and newPassword are required")
+		return
+	}
+
+	userID, err := uuid.Parse(tc.UserID)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+		return
+	}
+	u, err := h.repo.GetByID(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, repo.ErrUserNotFound) {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to change password"})
+		return
+	}
+	if !h.checkPassword(w, req.NewPassword, u.Email, u.FullName) {
+		return
+	}
+
+	if err := h.repo.ChangePassword(r.Context(), userID, req.OldPassword, req.NewPassword); err != nil {
+		switch {
+		case errors.Is(err, repo.ErrInvalidCredentials):
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid old password"})
+		case errors.Is(err, repo.ErrUserNotFound):
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		default:
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to change password"})
+		}
+		return
+	}
+
+	if err := h.tokens.RevokeAllForUser(r.Context(), userID); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to revoke sessions"})
+		return
+	}
+	if jti, err := uuid.Parse(tc.ID); err == nil {
+		if err := h.tokens.RevokeAccessToken(r.Context(), jti, tc.ExpiresAt); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to revoke sessions"})
+			return
+		}
+	}
+
+	record(r, claimsActor(tc), "password.change", "user", userID.String(), nil, nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST /api/v1/auth/password/forgot
+// Salje link za reset lozinke. Odgovor je uvijek 202, bez obzira da li nalog
+// postoji, kako endpoint ne bi otkrivao registrovane email adrese.
+func (h *UserHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req forgotPasswordReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, "invalid JSON body")
+		return
+	}
+	req.Email = strings.TrimSpace(req.Email)
+	if req.Email == "" {
+		badRequest(w, "email is required")
+		return
+	}
+
+	accepted := map[string]string{"message": "if the account exists, a reset link has been sent"}
+
+	u, err := h.repo.GetByEmail(r.Context(), req.Email)
+	if err != nil {
+		if !errors.Is(err, repo.ErrUserNotFound) {
+			log.Println("password reset lookup failed:", err)
+		}
+		writeJSON(w, http.StatusAccepted, accepted)
+		return
+	}
+
+	token, err := newOpaqueToken()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to create reset token"})
+		return
+	}
+	expiresAt := time.Now().Add(h.cfg.PasswordResetTTL)
+	if err := h.resets.CreateResetToken(r.Context(), u.ID, hashToken(token), expiresAt); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to create reset token"})
+		return
+	}
+
+	link := h.cfg.PasswordResetURL + "?token=" + url.QueryEscape(token)
+	msg := services.Message{
+		To:      u.Email,
+		Subject: "Reset lozinke",
+		Body: fmt.Sprintf("Zdravo %s,\n\nlozinku mozete promijeniti na linku:\n%s\n\nLink vazi do %s i moze se iskoristiti samo jednom.",
+			u.FullName, link, expiresAt.Format(time.RFC1123)),
+	}
+	if err := h.notifier.Notify(r.Context(), msg); err != nil {
+		log.Println("password reset notification failed:", err)
+	}
+
+	writeJSON(w, http.StatusAccepted, accepted)
+}
+
+// POST /api/v1/auth/password/reset
+// Postavlja novu lozinku uz reset token; token je jednokratan, a sve postojece
+// sesije korisnika se opozivaju
+func (h *UserHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req resetPasswordReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, "invalid JSON body")
+		return
+	}
+	req.Token = strings.TrimSpace(req.Token)
+	if req.Token == "" || req.NewPassword == "" {
+		badRequest(w, "token and newPassword are required")
+		return
+	}
+
+	// politika se provjerava prije nego sto se token iskoristi, da korisnik
+	// moze pokusati ponovo sa istim linkom
+	owner, err := h.resets.ResetTokenUser(r.Context(), hashToken(req.Token))
+	if err != nil {
+		if errors.Is(err, repo.ErrInvalidResetToken) {
+			badRequest(w, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to reset password"})
+		return
+	}
+	u, err := h.repo.GetByID(r.Context(), owner)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to reset password"})
+		return
+	}
+	if !h.checkPassword(w, req.NewPassword, u.Email, u.FullName) {
+		return
+	}
+
+	userID, err := h.resets.ResetPassword(r.Context(), hashToken(req.Token), req.NewPassword)
+	if err != nil {
+		if errors.Is(err, repo.ErrInvalidResetToken) {
+			badRequest(w, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to reset password"})
+		return
+	}
+
+	if err := h.tokens.RevokeAllForUser(r.Context(), userID); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to revoke sessions"})
+		return
+	}
+
+	record(r, h.actorByID(r.Context(), userID), "password.reset", "user", userID.String(), nil, nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /api/v1/auth/password/policy
+// Pravila za nove lozinke, da ih frontend prikaze prije slanja forme
+func (h *UserHandler) PasswordPolicy(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.passwords.Describe())
+}
+
+// checkPassword provjerava novu lozinku prema politici; na gresku odgovara
+// sa 400 i listom prekrsenih pravila
+func (h *UserHandler) checkPassword(w http.ResponseWriter, password, email, fullName string) bool {
+	err := h.passwords.Validate(password, email, fullName)
+	if err == nil {
+		return true
+	}
+	var pe *services.PasswordPolicyError
+	if !errors.As(err, &pe) {
+		badRequest(w, err.Error())
+		return false
+	}
+	writeJSON(w, http.StatusBadRequest, map[string]any{
+		"error":      "password does not meet policy",
+		"violations": pe.Violations,
+	})
+	return false
+}
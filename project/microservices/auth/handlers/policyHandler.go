@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Bijelic03/eAdministration/project/microservices/auth/policy"
+)
+
+// GET /api/v1/auth/policy?service=university
+// Vraca pravila autorizacije servisa; servisi ih kesiraju zajedno sa JWKS-om
+func (h *UserHandler) Policy(w http.ResponseWriter, r *http.Request) {
+	p := policy.For(r.URL.Query().Get("service"))
+	if p == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown service"})
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
+
+// authorizeRoute odlucuje o pristupu ruti servisa prema centralnoj politici
+// (GET /auth/authorize?service=university&method=GET&path=/api/v1/university/students/{id})
+func (h *UserHandler) authorizeRoute(w http.ResponseWriter, r *http.Request, tc *TokenClaims) {
+	q := r.URL.Query()
+	p := policy.For(q.Get("service"))
+	if p == nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown service"})
+		return
+	}
+	method := q.Get("method")
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	d := p.Authorize(method, q.Get("path"), policy.Subject{
+		UserID: tc.UserID,
+		Email:  tc.Email,
+		Role:   tc.Role,
+		Perms:  tc.Perms,
+		Scopes: tc.Scopes,
+	})
+
+	status := http.StatusOK
+	if !d.Allowed {
+		status = http.StatusForbidden
+	}
+	writeJSON(w, status, map[string]any{
+		"allowed": d.Allowed,
+		"reason":  d.Reason,
+		"email":   tc.Email,
+		"role":    tc.Role,
+	})
+}
+
+type claimsKey struct{}
+
+// RequirePolicy stiti rute samog auth servisa: zahtijeva validan token i
+// pristup prema politici "auth", a claims upisuje u kontekst zahtjeva
+func (h *UserHandler) RequirePolicy(next http.HandlerFunc) http.Handler {
+	p := policy.For(policy.ServiceAuth)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tc, ok := h.authenticate(w, r)
+		if !ok {
+			return
+		}
+
+		d := p.Authorize(r.Method, r.URL.Path, policy.Subject{
+			UserID: tc.UserID,
+			Email:  tc.Email,
+			Role:   tc.Role,
+			Perms:  tc.Perms,
+			Scopes: tc.Scopes,
+		})
+		if !d.Allowed {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden", "reason": d.Reason})
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), claimsKey{}, tc)))
+	})
+}
+
+// claimsFromContext vraca claims koje je upisao RequirePolicy
+func claimsFromContext(ctx context.Context) *TokenClaims {
+	if tc, ok := ctx.Value(claimsKey{}).(*TokenClaims); ok {
+		return tc
+	}
+	return &TokenClaims{}
+}
+
+// RequireServicePolicy stiti interne rute auth servisa: zahtijeva service
+// token (client credentials) sa scope-om koji politika "auth" trazi za rutu
+func (a *Auth) RequireServicePolicy(next http.HandlerFunc) http.Handler {
+	p := policy.For(policy.ServiceAuth)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tc, err := a.VerifyToken(parseBearerToken(r.Header.Get("Authorization")))
+		if err != nil || tc.Role != policy.ServiceRole {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "service token required"})
+			return
+		}
+
+		d := p.Authorize(r.Method, r.URL.Path, policy.Subject{
+			UserID: tc.UserID,
+			Role:   tc.Role,
+			Scopes: tc.Scopes,
+		})
+		if !d.Allowed {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "forbidden", "reason": d.Reason})
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), claimsKey{}, tc)))
+	})
+}
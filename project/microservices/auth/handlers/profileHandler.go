@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	repo "github.com/Bijelic03/eAdministration/project/microservices/auth/repositories"
+	"github.com/Bijelic03/eAdministration/project/microservices/auth/services"
+	"github.com/google/uuid"
+)
+
+type updateMeReq struct {
+	FullName        string `json:"fullName"`
+	Email           string `json:"email"`
+	CurrentPassword string `json:"currentPassword"`
+}
+
+type confirmEmailReq struct {
+	Token string `json:"token"`
+}
+
+type deactivateReq struct {
+	Password string `json:"password"`
+}
+
+// GET /api/v1/auth/me
+// Profil prijavljenog korisnika
+func (h *UserHandler) Me(w http.ResponseWriter, r *http.Request) {
+	tc, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+	userID, err := uuid.Parse(tc.UserID)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+		return
+	}
+
+	u, err := h.repo.GetByID(r.Context(), userID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to load profile"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, userResp(u))
+}
+
+// PUT /api/v1/auth/me
+// Mijenja ime odmah; nova email adresa (uz trenutnu lozinku) vazi tek kada se
+// potvrdi link poslat na tu adresu
+func (h *UserHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	tc, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+	userID, err := uuid.Parse(tc.UserID)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+		return
+	}
+
+	var req updateMeReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, "invalid JSON body")
+		return
+	}
+	req.FullName = strings.TrimSpace(req.FullName)
+	req.Email = strings.ToLower(strings.TrimSpace(req.Email))
+
+	u, err := h.repo.GetByID(r.Context(), userID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to update profile"})
+		return
+	}
+	before := userResp(u)
+
+	emailChange := req.Email != "" && req.Email != strings.ToLower(u.Email)
+	if emailChange {
+		if req.CurrentPassword == "" {
+			badRequest(w, "currentPassword is required to change email")
+			return
+		}
+		if err := h.repo.VerifyPassword(r.Context(), userID, req.CurrentPassword); err != nil {
+			if errors.Is(err, repo.ErrInvalidCredentials) {
+				writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid password"})
+				return
+			}
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to update profile"})
+			return
+		}
+		if _, err := h.repo.GetByEmail(r.Context(), req.Email); err == nil {
+			writeJSON(w, http.StatusConflict, map[string]string{"error": repo.ErrEmailExists.Error()})
+			return
+		} else if !errors.Is(err, repo.ErrUserNotFound) {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to update profile"})
+			return
+		}
+	}
+
+	if req.FullName != "" && req.FullName != u.FullName {
+		if u, err = h.repo.UpdateProfile(r.Context(), userID, req.FullName); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to update profile"})
+			return
+		}
+		record(r, claimsActor(tc), "user.profile_update", "user", userID.String(), before, userResp(u))
+	}
+
+	resp := userResp(u)
+	if emailChange {
+		if err := h.requestEmailChange(r, u, req.Email); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to request email change"})
+			return
+		}
+		resp["pendingEmail"] = req.Email
+		record(r, claimsActor(tc), "user.email_change_request", "user", userID.String(), nil,
+			map[string]string{"pendingEmail": req.Email})
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// requestEmailChange salje link za potvrdu na novu adresu i obavjestenje na staru
+func (h *UserHandler) requestEmailChange(r *http.Request, u *repo.User, newEmail string) error {
+	token, err := newOpaqueToken()
+	if err != nil {
+		return err
+	}
+	expiresAt := time.Now().Add(h.cfg.EmailChangeTTL)
+	if err := h.repo.CreateEmailChange(r.Context(), u.ID, newEmail, hashToken(token), expiresAt); err != nil {
+		return err
+	}
+
+	link := h.cfg.EmailChangeURL + "?token=" + url.QueryEscape(token)
+	msgs := []services.Message{
+		{
+			To:      newEmail,
+			Subject: "Potvrda nove email adrese",
+			Body: fmt.Sprintf("Zdravo %s,\n\nnovu email adresu potvrdite na linku:\n%s\n\nLink vazi do %s.",
+				u.FullName, link, expiresAt.Format(time.RFC1123)),
+		},
+		{
+			To:      u.Email,
+			Subject: "Zahtjev za promjenu email adrese",
+			Body: fmt.Sprintf("Zdravo %s,\n\nzatrazena je promjena email adrese naloga na %s. Ako to niste bili vi, promijenite lozinku.",
+				u.FullName, newEmail),
+		},
+	}
+	for _, msg := range msgs {
+		if err := h.notifier.Notify(r.Context(), msg); err != nil {
+			log.Println("email change notification failed:", err)
+		}
+	}
+	return nil
+}
+
+// POST /api/v1/auth/me/email/confirm
+// Potvrdjuje novu email adresu tokenom iz linka; sesije se opozivaju jer
+// postojeci tokeni nose staru adresu
+func (h *UserHandler) ConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	var req confirmEmailReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, "invalid JSON body")
+		return
+	}
+	req.Token = strings.TrimSpace(req.Token)
+	if req.Token == "" {
+		badRequest(w, "token is required")
+		return
+	}
+
+	u, err := h.repo.ConfirmEmailChange(r.Context(), hashToken(req.Token))
+	if err != nil {
+		switch {
+		case errors.Is(err, repo.ErrInvalidEmailToken):
+			badRequest(w, err.Error())
+		case errors.Is(err, repo.ErrEmailExists):
+			writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		default:
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to confirm email"})
+		}
+		return
+	}
+
+	if err := h.tokens.RevokeAllForUser(r.Context(), u.ID); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to revoke sessions"})
+		return
+	}
+
+	record(r, userActor(u), "user.email_change", "user", u.ID.String(), nil, userResp(u))
+
+	writeJSON(w, http.StatusOK, userResp(u))
+}
+
+// POST /api/v1/auth/me/deactivate
+// Korisnik deaktivira svoj nalog uz lozinku; ponovo ga moze aktivirati samo administrator
+func (h *UserHandler) DeactivateMe(w http.ResponseWriter, r *http.Request) {
+	tc, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+	userID, err := uuid.Parse(tc.UserID)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+		return
+	}
+
+	var req deactivateReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, "invalid JSON body")
+		return
+	}
+	if req.Password == "" {
+		badRequest(w, "password is required")
+		return
+	}
+	if err := h.repo.VerifyPassword(r.Context(), userID, req.Password); err != nil {
+		if errors.Is(err, repo.ErrInvalidCredentials) {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid password"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to deactivate account"})
+		return
+	}
+
+	if _, err := h.repo.SetStatus(r.Context(), userID, repo.StatusDisabled); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to deactivate account"})
+		return
+	}
+	if err := h.tokens.RevokeAllForUser(r.Context(), userID); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to revoke sessions"})
+		return
+	}
+	if jti, err := uuid.Parse(tc.ID); err == nil {
+		if err := h.tokens.RevokeAccessToken(r.Context(), jti, tc.ExpiresAt); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to revoke sessions"})
+			return
+		}
+	}
+
+	record(r, claimsActor(tc), "user.deactivate", "user", userID.String(), nil, nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,310 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/Bijelic03/eAdministration/project/microservices/auth/policy"
+	repo "github.com/Bijelic03/eAdministration/project/microservices/auth/repositories"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// permissionServices odredjuje dozvole kojih servisa administrator moze
+// ukljuciti u prilagodjene uloge
+var permissionServices = map[repo.UserRole]string{
+	repo.RoleFacultyAdmin: policy.ServiceUniversity,
+	repo.RoleSSZAdmin:     policy.ServiceEmploymentOffice,
+}
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,48}$`)
+
+type createRoleReq struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+type updateRoleReq struct {
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+type assignRoleReq struct {
+	RoleID uuid.UUID `json:"roleId"`
+}
+
+// canGrant provjerava da su sve dozvole iz kataloga i iz domena administratora
+func canGrant(admin string, perms []string) (bool, string) {
+	service := permissionServices[repo.UserRole(admin)]
+	for _, name := range perms {
+		p, ok := policy.LookupPermission(name)
+		if !ok {
+			return false, "unknown permission " + name
+		}
+		if p.Service != service {
+			return false, "cannot grant permission " + name
+		}
+	}
+	return true, ""
+}
+
+// GET /api/v1/auth/permissions
+// Katalog dozvola koje administrator moze ukljuciti u uloge
+func (h *UserHandler) ListPermissions(w http.ResponseWriter, r *http.Request) {
+	tc := claimsFromContext(r.Context())
+	service := permissionServices[repo.UserRole(tc.Role)]
+
+	perms := []policy.Permission{}
+	for _, p := range policy.Permissions {
+		if p.Service == service {
+			perms = append(perms, p)
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"permissions": perms})
+}
+
+// GET /api/v1/auth/roles
+func (h *UserHandler) ListRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := h.roles.ListRoles(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to list roles"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"roles": roles})
+}
+
+// POST /api/v1/auth/roles
+// Nova prilagodjena uloga od dozvola iz domena administratora
+func (h *UserHandler) CreateRole(w http.ResponseWriter, r *http.Request) {
+	tc := claimsFromContext(r.Context())
+
+	var req createRoleReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, "invalid JSON body")
+		return
+	}
+	req.Name = strings.ToLower(strings.TrimSpace(req.Name))
+	req.Description = strings.TrimSpace(req.Description)
+	if !roleNamePattern.MatchString(req.Name) {
+		badRequest(w, "name must be 2-49 lowercase letters, digits, '-' or '_'")
+		return
+	}
+	if repo.UserRole(req.Name).Valid() || req.Name == policy.ServiceRole || req.Name == policy.EmployerRole {
+		badRequest(w, "name is reserved for a built-in role")
+		return
+	}
+	if len(req.Permissions) == 0 {
+		badRequest(w, "permissions are required")
+		return
+	}
+	if ok, msg := canGrant(tc.Role, req.Permissions); !ok {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": msg})
+		return
+	}
+
+	created, err := h.roles.CreateRole(r.Context(), repo.Role{
+		Name:        req.Name,
+		Description: req.Description,
+		Permissions: req.Permissions,
+	}, actorUUID(tc))
+	if err != nil {
+		if errors.Is(err, repo.ErrRoleExists) {
+			writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to create role"})
+		return
+	}
+
+	record(r, claimsActor(tc), "role.create", "role", created.ID.String(), nil, created)
+
+	writeJSON(w, http.StatusCreated, created)
+}
+
+// PUT /api/v1/auth/roles/{id}
+// Mijenja opis i dozvole uloge; korisnici dobijaju nove dozvole pri
+// sledecem osvjezavanju tokena
+func (h *UserHandler) UpdateRole(w http.ResponseWriter, r *http.Request) {
+	tc := claimsFromContext(r.Context())
+	before, ok := h.manageableRole(w, r, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	var req updateRoleReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, "invalid JSON body")
+		return
+	}
+	if len(req.Permissions) == 0 {
+		badRequest(w, "permissions are required")
+		return
+	}
+	if ok, msg := canGrant(tc.Role, req.Permissions); !ok {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": msg})
+		return
+	}
+
+	updated, err := h.roles.UpdateRole(r.Context(), before.ID, strings.TrimSpace(req.Description), req.Permissions)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to update role"})
+		return
+	}
+
+	record(r, claimsActor(tc), "role.update", "role", updated.ID.String(), before, updated)
+
+	writeJSON(w, http.StatusOK, updated)
+}
+
+// DELETE /api/v1/auth/roles/{id}
+func (h *UserHandler) DeleteRole(w http.ResponseWriter, r *http.Request) {
+	tc := claimsFromContext(r.Context())
+	before, ok := h.manageableRole(w, r, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	if err := h.roles.DeleteRole(r.Context(), before.ID); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to delete role"})
+		return
+	}
+
+	record(r, claimsActor(tc), "role.delete", "role", before.ID.String(), before, nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /api/v1/auth/users/{id}/roles
+// Prilagodjene uloge korisnika i njegove efektivne dozvole
+func (h *UserHandler) ListUserRoles(w http.ResponseWriter, r *http.Request) {
+	u, ok := h.manageableUser(w, r)
+	if !ok {
+		return
+	}
+
+	roles, err := h.roles.UserRoles(r.Context(), u.ID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to list roles"})
+		return
+	}
+	var extra []string
+	for _, role := range roles {
+		extra = append(extra, role.Permissions...)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"role":        u.Role,
+		"roles":       roles,
+		"permissions": policy.EffectivePermissions(string(u.Role), extra),
+	})
+}
+
+// POST /api/v1/auth/users/{id}/roles
+func (h *UserHandler) AssignUserRole(w http.ResponseWriter, r *http.Request) {
+	tc := claimsFromContext(r.Context())
+	u, ok := h.manageableUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req assignRoleReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, "invalid JSON body")
+		return
+	}
+	role, ok := h.manageableRole(w, r, req.RoleID.String())
+	if !ok {
+		return
+	}
+
+	if err := h.roles.AssignRole(r.Context(), u.ID, role.ID, actorUUID(tc)); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to assign role"})
+		return
+	}
+
+	record(r, claimsActor(tc), "user.role_assign", "user", u.ID.String(), nil, map[string]string{
+		"roleId": role.ID.String(),
+		"role":   role.Name,
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DELETE /api/v1/auth/users/{id}/roles/{roleId}
+func (h *UserHandler) UnassignUserRole(w http.ResponseWriter, r *http.Request) {
+	tc := claimsFromContext(r.Context())
+	u, ok := h.manageableUser(w, r)
+	if !ok {
+		return
+	}
+	role, ok := h.manageableRole(w, r, mux.Vars(r)["roleId"])
+	if !ok {
+		return
+	}
+
+	if err := h.roles.UnassignRole(r.Context(), u.ID, role.ID); err != nil {
+		if errors.Is(err, repo.ErrRoleNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "role is not assigned to user"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to unassign role"})
+		return
+	}
+
+	record(r, claimsActor(tc), "user.role_unassign", "user", u.ID.String(), map[string]string{
+		"roleId": role.ID.String(),
+		"role":   role.Name,
+	}, nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// manageableRole ucitava ulogu ciji su sve dozvole iz domena administratora
+func (h *UserHandler) manageableRole(w http.ResponseWriter, r *http.Request, idStr string) (*repo.Role, bool) {
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		badRequest(w, "invalid role id")
+		return nil, false
+	}
+	role, err := h.roles.GetRole(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repo.ErrRoleNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return nil, false
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to load role"})
+		return nil, false
+	}
+	if ok, _ := canGrant(claimsFromContext(r.Context()).Role, role.Permissions); !ok {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "role belongs to another domain"})
+		return nil, false
+	}
+	return role, true
+}
+
+// manageableUser ucitava korisnika iz {id} cijom ulogom administrator upravlja
+func (h *UserHandler) manageableUser(w http.ResponseWriter, r *http.Request) (*repo.User, bool) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		badRequest(w, "invalid id")
+		return nil, false
+	}
+	u, err := h.repo.GetByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, repo.ErrUserNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return nil, false
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to load user"})
+		return nil, false
+	}
+	if !canManage(claimsFromContext(r.Context()).Role, u.Role) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "cannot manage user with role " + string(u.Role)})
+		return nil, false
+	}
+	return u, true
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Bijelic03/eAdministration/project/microservices/auth/policy"
+	"github.com/Bijelic03/eAdministration/project/microservices/common/audit"
+)
+
+// serviceClient je servis koji smije traziti service token; cuva se samo
+// hash tajne
+type serviceClient struct {
+	id         string
+	secretHash [32]byte
+	scopes     []string
+}
+
+// ServiceTokenHandler izdaje service tokene (OAuth2 client credentials) kojima
+// servisi pozivaju interne rute drugih servisa
+type ServiceTokenHandler struct {
+	auth    *Auth
+	clients map[string]serviceClient
+	ttl     time.Duration
+}
+
+// NewServiceTokenHandler cita klijente iz liste "clientId:tajna:scope1 scope2";
+// neispravni unosi se preskacu
+func NewServiceTokenHandler(auth *Auth, specs []string, ttl time.Duration) *ServiceTokenHandler {
+	clients := make(map[string]serviceClient, len(specs))
+	for _, spec := range specs {
+		parts := strings.SplitN(spec, ":", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" {
+			log.Println("service client: invalid entry, expected clientId:secret:scopes")
+			continue
+		}
+		clients[parts[0]] = serviceClient{
+			id:         parts[0],
+			secretHash: sha256.Sum256([]byte(parts[1])),
+			scopes:     strings.Fields(parts[2]),
+		}
+	}
+	return &ServiceTokenHandler{auth: auth, clients: clients, ttl: ttl}
+}
+
+// POST /api/v1/auth/token  (application/x-www-form-urlencoded)
+// grant_type=client_credentials, klijent se autentifikuje Basic auth-om ili
+// poljima client_id/client_secret; opcioni scope suzava dodijeljene scope-ove
+func (h *ServiceTokenHandler) Token(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	if r.PostForm.Get("grant_type") != "client_credentials" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	clientID, secret, ok := r.BasicAuth()
+	if ok {
+		clientID, _ = url.QueryUnescape(clientID)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		clientID = r.PostForm.Get("client_id")
+		secret = r.PostForm.Get("client_secret")
+	}
+
+	client, found := h.clients[clientID]
+	sum := sha256.Sum256([]byte(secret))
+	if !found || subtle.ConstantTimeCompare(sum[:], client.secretHash[:]) != 1 {
+		record(r, audit.Actor{ID: ServiceSubjectPrefix + clientID, Role: policy.ServiceRole},
+			"auth.service_token_failed", "service_client", clientID, nil, nil)
+		w.Header().Set("WWW-Authenticate", `Basic realm="eadministration"`)
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	granted := client.scopes
+	if requested := strings.Fields(r.PostForm.Get("scope")); len(requested) > 0 {
+		for _, s := range requested {
+			if !hasScope(client.scopes, s) {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_scope"})
+				return
+			}
+		}
+		granted = requested
+	}
+
+	token, exp, err := h.auth.GenerateServiceToken(client.id, granted, h.ttl)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": token,
+		"token_type":   "Bearer",
+		"expires_in":   int(time.Until(exp).Seconds()),
+		"scope":        strings.Join(granted, " "),
+	})
+}
+
+func hasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
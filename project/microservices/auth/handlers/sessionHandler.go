@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	repo "github.com/Bijelic03/eAdministration/project/microservices/auth/repositories"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// GET /api/v1/auth/sessions
+// Aktivne sesije prijavljenog korisnika; trenutna sesija je oznacena sa current
+func (h *UserHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	tc, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+	userID, err := uuid.Parse(tc.UserID)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+		return
+	}
+
+	sessions, err := h.tokens.ListSessions(r.Context(), userID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to list sessions"})
+		return
+	}
+
+	out := make([]map[string]any, 0, len(sessions))
+	for _, s := range sessions {
+		out = append(out, map[string]any{
+			"id":         s.ID,
+			"createdAt":  s.CreatedAt,
+			"lastSeenAt": s.LastSeenAt,
+			"expiresAt":  s.ExpiresAt,
+			"userAgent":  s.UserAgent,
+			"ip":         s.IP,
+			"current":    s.ID.String() == tc.SessionID,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"sessions": out})
+}
+
+// DELETE /api/v1/auth/sessions/{id}
+// Odjavljuje jednu sesiju (npr. izgubljen uredjaj); njeni access tokeni
+// prestaju da vaze i u ostalim servisima
+func (h *UserHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	tc, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+	userID, err := uuid.Parse(tc.UserID)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+		return
+	}
+	sessionID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		badRequest(w, "invalid id")
+		return
+	}
+
+	if err := h.tokens.RevokeSession(r.Context(), userID, sessionID); err != nil {
+		if errors.Is(err, repo.ErrSessionNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to revoke session"})
+		return
+	}
+
+	record(r, claimsActor(tc), "session.revoke", "session", sessionID.String(), nil, nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DELETE /api/v1/auth/sessions
+// Odjavljuje sve sesije korisnika; uz ?keepCurrent=true trenutna sesija ostaje aktivna
+func (h *UserHandler) RevokeSessions(w http.ResponseWriter, r *http.Request) {
+	tc, ok := h.authenticate(w, r)
+	if !ok {
+		return
+	}
+	userID, err := uuid.Parse(tc.UserID)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+		return
+	}
+
+	if r.URL.Query().Get("keepCurrent") == "true" {
+		current, err := uuid.Parse(tc.SessionID)
+		if err != nil {
+			badRequest(w, "token has no session")
+			return
+		}
+		err = h.tokens.RevokeOtherSessions(r.Context(), userID, current)
+	} else {
+		err = h.tokens.RevokeAllForUser(r.Context(), userID)
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to revoke sessions"})
+		return
+	}
+
+	record(r, claimsActor(tc), "session.revoke_all", "user", userID.String(), nil,
+		map[string]bool{"keepCurrent": r.URL.Query().Get("keepCurrent") == "true"})
+
+	w.WriteHeader(http.StatusNoContent)
+}
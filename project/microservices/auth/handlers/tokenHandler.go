@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Bijelic03/eAdministration/project/microservices/auth/policy"
+	repo "github.com/Bijelic03/eAdministration/project/microservices/auth/repositories"
+	"github.com/google/uuid"
+)
+
+type refreshReq struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// maksimalna duzina User-Agent zapisa u sesiji
+const maxUserAgentLength = 512
+
+// issueTokens otvara novu sesiju (uredjaj/klijent sa kog je prijava) i izdaje
+// kratkotrajni access token i refresh token; ID sesije je familija refresh tokena
+func (h *UserHandler) issueTokens(r *http.Request, u *repo.User) (*authResp, error) {
+	refresh, err := newOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	session := repo.Session{
+		ID:        uuid.New(),
+		UserID:    u.ID,
+		UserAgent: userAgent,
+		IP:        h.clientIP(r),
+		ExpiresAt: time.Now().Add(h.cfg.RefreshTokenTTL),
+	}
+	if err := h.tokens.CreateSession(r.Context(), session); err != nil {
+		return nil, err
+	}
+	if err := h.tokens.CreateRefreshToken(r.Context(), u.ID, session.ID, hashToken(refresh), session.ExpiresAt); err != nil {
+		return nil, err
+	}
+	record(r, userActor(u), "auth.login", "user", u.ID.String(), nil, map[string]string{
+		"sessionId": session.ID.String(),
+		"ip":        session.IP,
+	})
+	return h.buildAuthResp(r.Context(), u, refresh, session.ID)
+}
+
+// buildAuthResp izdaje access token sa efektivnim dozvolama korisnika; izmjene
+// prilagodjenih uloga vaze od sledeceg osvjezavanja tokena
+func (h *UserHandler) buildAuthResp(ctx context.Context, u *repo.User, refresh string, sessionID uuid.UUID) (*authResp, error) {
+	extra, err := h.roles.UserPermissions(ctx, u.ID)
+	if err != nil {
+		return nil, err
+	}
+	perms := policy.EffectivePermissions(string(u.Role), extra)
+
+	token, exp, err := h.auth.GenerateToken(u.ID.String(), u.Email, string(u.Role), sessionID.String(), perms, h.cfg.AccessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &authResp{
+		Token:        token,
+		ExpiresAt:    exp,
+		RefreshToken: refresh,
+		User: map[string]any{
+			"id":       u.ID,
+			"fullName": u.FullName,
+			"email":    u.Email,
+			"role":     u.Role,
+			"perms":    perms,
+		},
+	}, nil
+}
+
+// POST /api/v1/auth/refresh
+// Mijenja refresh token za novi par (access + refresh); stari refresh token postaje nevazeci.
+func (h *UserHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req refreshReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		badRequest(w, "invalid JSON body")
+		return
+	}
+	req.RefreshToken = strings.TrimSpace(req.RefreshToken)
+	if req.RefreshToken == "" {
+		badRequest(w, "refreshToken is required")
+		return
+	}
+
+	next, err := newOpaqueToken()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to issue token"})
+		return
+	}
+
+	rt, err := h.tokens.RotateRefreshToken(r.Context(), hashToken(req.RefreshToken), hashToken(next), time.Now().Add(h.cfg.RefreshTokenTTL))
+	if err != nil {
+		if errors.Is(err, repo.ErrInvalidRefreshToken) || errors.Is(err, repo.ErrRefreshTokenReused) {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to refresh token"})
+		return
+	}
+
+	u, err := h.repo.GetByID(r.Context(), rt.UserID)
+	if err != nil {
+		if errors.Is(err, repo.ErrUserNotFound) {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to refresh token"})
+		return
+	}
+	if !u.Active() {
+		if err := h.tokens.RevokeAllForUser(r.Context(), u.ID); err != nil {
+			log.Println("revoke sessions of disabled user failed:", err)
+		}
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "account disabled"})
+		return
+	}
+
+	resp, err := h.buildAuthResp(r.Context(), u, next, rt.FamilyID)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to issue token"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// POST /api/v1/auth/logout
+// Opoziva access token iz Authorization headera (ako je validan) i refresh token iz body-ja.
+func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	var req refreshReq
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			badRequest(w, "invalid JSON body")
+			return
+		}
+	}
+
+	tokenString := parseBearerToken(r.Header.Get("Authorization"))
+	if tokenString == "" && strings.TrimSpace(req.RefreshToken) == "" {
+		badRequest(w, "bearer token or refreshToken is required")
+		return
+	}
+
+	if tokenString != "" {
+		if tc, err := h.auth.VerifyToken(tokenString); err == nil {
+			if jti, err := uuid.Parse(tc.ID); err == nil {
+				if err := h.tokens.RevokeAccessToken(r.Context(), jti, tc.ExpiresAt); err != nil {
+					writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to logout"})
+					return
+				}
+			}
+			// kraj impersonacije opoziva samo impersonacioni token, ne i
+			// sesiju administratora
+			if tc.Actor != nil {
+				record(r, claimsActor(tc), "user.impersonate_end", "user", tc.UserID, nil, nil)
+			} else {
+				userID, uerr := uuid.Parse(tc.UserID)
+				sid, serr := uuid.Parse(tc.SessionID)
+				if uerr == nil && serr == nil {
+					if err := h.tokens.RevokeSession(r.Context(), userID, sid); err != nil && !errors.Is(err, repo.ErrSessionNotFound) {
+						writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to logout"})
+						return
+					}
+				}
+				record(r, claimsActor(tc), "auth.logout", "session", tc.SessionID, nil, nil)
+			}
+		}
+	}
+
+	if rt := strings.TrimSpace(req.RefreshToken); rt != "" {
+		if err := h.tokens.RevokeRefreshToken(r.Context(), hashToken(rt)); err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to logout"})
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /api/v1/auth/revoked
+// Lista opozvanih access tokena koji jos nisu istekli, te sesija i naloga
+// opozvanih/deaktiviranih unutar trajanja access tokena; servisi je
+// periodicno preuzimaju jer tokene verifikuju lokalno.
+func (h *UserHandler) Revoked(w http.ResponseWriter, r *http.Request) {
+	jtis, err := h.tokens.ListRevokedAccessTokens(r.Context())
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to list revoked tokens"})
+		return
+	}
+	since := time.Now().Add(-h.cfg.AccessTokenTTL)
+	sessions, err := h.tokens.ListRevokedSessions(r.Context(), since)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to list revoked tokens"})
+		return
+	}
+	disabled, err := h.repo.ListDisabledUsers(r.Context(), since)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to list revoked tokens"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"jtis": jtis, "sessions": sessions, "disabledUsers": disabled})
+}
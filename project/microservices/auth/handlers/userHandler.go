@@ -5,25 +5,128 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"log"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/Bijelic03/eAdministration/project/microservices/auth/config"
 	repo "github.com/Bijelic03/eAdministration/project/microservices/auth/repositories"
+	"github.com/Bijelic03/eAdministration/project/microservices/auth/services"
+	"github.com/Bijelic03/eAdministration/project/microservices/common/audit"
+	"github.com/google/uuid"
 )
 
 type UserRepo interface {
 	Login(ctx context.Context, email, password string) (*repo.User, error)
 	Register(ctx context.Context, u repo.User) (*repo.User, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*repo.User, error)
+	GetByEmail(ctx context.Context, email string) (*repo.User, error)
+	ChangePassword(ctx context.Context, id uuid.UUID, oldPassword, newPassword string) error
+	Provision(ctx context.Context, u repo.User, createdBy *uuid.UUID) (*repo.User, error)
+	ChangeRole(ctx context.Context, userID uuid.UUID, role repo.UserRole, changedBy uuid.UUID) (*repo.User, error)
+	ListRoleChanges(ctx context.Context, userID uuid.UUID) ([]repo.RoleChange, error)
+	VerifyPassword(ctx context.Context, id uuid.UUID, password string) error
+	UpdateProfile(ctx context.Context, id uuid.UUID, fullName string) (*repo.User, error)
+	SetStatus(ctx context.Context, id uuid.UUID, status repo.UserStatus) (*repo.User, error)
+	ListDisabledUsers(ctx context.Context, since time.Time) ([]uuid.UUID, error)
+	CreateEmailChange(ctx context.Context, userID uuid.UUID, newEmail, tokenHash string, expiresAt time.Time) error
+	ConfirmEmailChange(ctx context.Context, tokenHash string) (*repo.User, error)
+	VerifyEmail(ctx context.Context, id uuid.UUID, email string) (*repo.User, error)
+}
+
+type TokenRepo interface {
+	CreateRefreshToken(ctx context.Context, userID, familyID uuid.UUID, tokenHash string, expiresAt time.Time) error
+	RotateRefreshToken(ctx context.Context, oldHash, newHash string, expiresAt time.Time) (*repo.RefreshToken, error)
+	RevokeRefreshToken(ctx context.Context, tokenHash string) error
+	RevokeAllForUser(ctx context.Context, userID uuid.UUID) error
+	RevokeAccessToken(ctx context.Context, jti uuid.UUID, expiresAt time.Time) error
+	IsAccessTokenRevoked(ctx context.Context, jti uuid.UUID) (bool, error)
+	ListRevokedAccessTokens(ctx context.Context) ([]uuid.UUID, error)
+	CreateSession(ctx context.Context, s repo.Session) error
+	ListSessions(ctx context.Context, userID uuid.UUID) ([]repo.Session, error)
+	RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error
+	RevokeOtherSessions(ctx context.Context, userID, currentID uuid.UUID) error
+	IsSessionRevoked(ctx context.Context, sessionID uuid.UUID) (bool, error)
+	ListRevokedSessions(ctx context.Context, since time.Time) ([]uuid.UUID, error)
+}
+
+type PasswordResetRepo interface {
+	CreateResetToken(ctx context.Context, userID uuid.UUID, tokenHash string, expiresAt time.Time) error
+	ResetTokenUser(ctx context.Context, tokenHash string) (uuid.UUID, error)
+	ResetPassword(ctx context.Context, tokenHash, newPassword string) (uuid.UUID, error)
+}
+
+type LoginThrottleRepo interface {
+	LockedUntil(ctx context.Context, kind, key string) (time.Time, error)
+	RegisterFailure(ctx context.Context, kind, key string, p repo.LockoutPolicy) (time.Time, error)
+	Reset(ctx context.Context, kind, key string) error
+}
+
+type MFARepo interface {
+	GetTOTP(ctx context.Context, userID uuid.UUID) (*repo.TOTPEnrollment, error)
+	SaveTOTPSecret(ctx context.Context, userID uuid.UUID, secret string) error
+	EnableTOTP(ctx context.Context, userID uuid.UUID, step int64, recoveryHashes []string) error
+	UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error)
+	ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, recoveryHashes []string) error
+	CountRecoveryCodes(ctx context.Context, userID uuid.UUID) (int, error)
+	DisableTOTP(ctx context.Context, userID uuid.UUID) error
+	CreateChallenge(ctx context.Context, userID uuid.UUID, tokenHash string, expiresAt time.Time) error
+	GetChallenge(ctx context.Context, tokenHash string) (uuid.UUID, error)
+	FailChallenge(ctx context.Context, tokenHash string) error
+	ConsumeChallenge(ctx context.Context, tokenHash string) error
+}
+
+type IdentityRepo interface {
+	CreateOIDCState(ctx context.Context, stateHash, codeVerifier, nonce string, expiresAt time.Time) error
+	ConsumeOIDCState(ctx context.Context, stateHash string) (string, string, error)
+	TouchIdentity(ctx context.Context, issuer, subject string) (uuid.UUID, error)
+	LinkIdentity(ctx context.Context, userID uuid.UUID, issuer, subject, email string) error
+}
+
+type RoleRepo interface {
+	ListRoles(ctx context.Context) ([]repo.Role, error)
+	GetRole(ctx context.Context, id uuid.UUID) (*repo.Role, error)
+	CreateRole(ctx context.Context, role repo.Role, createdBy *uuid.UUID) (*repo.Role, error)
+	UpdateRole(ctx context.Context, id uuid.UUID, description string, permissions []string) (*repo.Role, error)
+	DeleteRole(ctx context.Context, id uuid.UUID) error
+	AssignRole(ctx context.Context, userID, roleID uuid.UUID, assignedBy *uuid.UUID) error
+	UnassignRole(ctx context.Context, userID, roleID uuid.UUID) error
+	UserRoles(ctx context.Context, userID uuid.UUID) ([]repo.Role, error)
+	UserPermissions(ctx context.Context, userID uuid.UUID) ([]string, error)
 }
 
 type UserHandler struct {
-	repo UserRepo
-	auth *Auth
+	repo       UserRepo
+	tokens     TokenRepo
+	resets     PasswordResetRepo
+	throttles  LoginThrottleRepo
+	mfa        MFARepo
+	identities IdentityRepo
+	roles      RoleRepo
+	oidc       *services.OIDCClient // nil ako SSO nije podesen
+	passwords  *services.PasswordPolicy
+	notifier   services.Notifier
+	auth       *Auth
+	cfg        config.Config
 }
 
-func NewUserHandler(r UserRepo, secretKey []byte) *UserHandler {
-	return &UserHandler{repo: r, auth: NewAuth(secretKey)}
+func NewUserHandler(r UserRepo, tokens TokenRepo, resets PasswordResetRepo, throttles LoginThrottleRepo, mfa MFARepo, identities IdentityRepo, roles RoleRepo, oidc *services.OIDCClient, passwords *services.PasswordPolicy, notifier services.Notifier, auth *Auth, cfg config.Config) *UserHandler {
+	return &UserHandler{
+		repo:       r,
+		tokens:     tokens,
+		resets:     resets,
+		throttles:  throttles,
+		mfa:        mfa,
+		identities: identities,
+		roles:      roles,
+		oidc:       oidc,
+		passwords:  passwords,
+		notifier:   notifier,
+		auth:       auth,
+		cfg:        cfg,
+	}
 }
 
 func writeJSON(w http.ResponseWriter, status int, v any) {
@@ -44,8 +147,10 @@ type registerReq struct {
 }
 
 type authResp struct {
-	Token string      `json:"token"`
-	User  interface{} `json:"user"`
+	Token        string      `json:"token"`
+	ExpiresAt    time.Time   `json:"expiresAt"`
+	RefreshToken string      `json:"refreshToken"`
+	User         interface{} `json:"user"`
 }
 
 func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
@@ -56,18 +161,36 @@ func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 	}
 	req.Email = strings.TrimSpace(req.Email)
 	req.FullName = strings.TrimSpace(req.FullName)
-	req.Password = strings.TrimSpace(req.Password)
 
 	if req.FullName == "" || req.Email == "" || req.Password == "" {
 		badRequest(w, "fullName, email and password are required")
 		return
 	}
 
+	// samoregistracija je dozvoljena samo za uloge sa liste (podrazumijevano candidate);
+	// naloge osoblja otvara administrator kroz /auth/users
+	if req.Role == "" {
+		req.Role = repo.RoleCandidate
+	}
+	if !req.Role.Valid() {
+		badRequest(w, repo.ErrInvalidRole.Error())
+		return
+	}
+	if !h.canSelfRegister(req.Role) {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "role cannot be self-registered"})
+		return
+	}
+	if !h.checkPassword(w, req.Password, req.Email, req.FullName) {
+		return
+	}
+
+	// nalog ceka potvrdu email adrese; tokeni se izdaju tek pri prijavi nakon potvrde
 	u := repo.User{
 		FullName: req.FullName,
 		Email:    req.Email,
 		Password: req.Password,
 		Role:     req.Role,
+		Status:   repo.StatusPendingVerification,
 	}
 	created, err := h.repo.Register(r.Context(), u)
 	if err != nil {
@@ -79,20 +202,15 @@ func (h *UserHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, _, err := h.auth.GenerateToken(created.Email, string(created.Role), 24*time.Hour)
-	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to issue token"})
-		return
+	record(r, userActor(created), "user.register", "user", created.ID.String(), nil, userResp(created))
+
+	if err := h.sendVerificationEmail(r.Context(), created); err != nil {
+		log.Println("verification email failed:", err)
 	}
 
-	writeJSON(w, http.StatusCreated, authResp{
-		Token: token,
-		User: map[string]any{
-			"id":       created.ID,
-			"fullName": created.FullName,
-			"email":    created.Email,
-			"role":     created.Role,
-		},
+	writeJSON(w, http.StatusCreated, map[string]any{
+		"user":    userResp(created),
+		"message": "verification email sent",
 	})
 }
 
@@ -108,15 +226,27 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	req.Email = strings.TrimSpace(req.Email)
-	req.Password = strings.TrimSpace(req.Password)
 	if req.Email == "" || req.Password == "" {
 		badRequest(w, "email and password are required")
 		return
 	}
 
+	account := strings.ToLower(req.Email)
+	ip := h.clientIP(r)
+	if !h.checkLoginThrottle(w, r, account, ip) {
+		return
+	}
+
 	u, err := h.repo.Login(r.Context(), req.Email, req.Password)
+	// lozinke su ranije cuvane bez razmaka na pocetku i kraju, pa stari
+	// nalozi i dalje moraju moci da se prijave skracenom lozinkom
+	if trimmed := strings.TrimSpace(req.Password); errors.Is(err, repo.ErrInvalidCredentials) && trimmed != req.Password && trimmed != "" {
+		u, err = h.repo.Login(r.Context(), req.Email, trimmed)
+	}
 	if err != nil {
 		if errors.Is(err, repo.ErrInvalidCredentials) {
+			h.registerLoginFailure(r, account, ip)
+			record(r, audit.Actor{Email: req.Email}, "auth.login_failed", "user", "", nil, map[string]string{"ip": ip})
 			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid email or password"})
 			return
 		}
@@ -124,35 +254,42 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, _, err := h.auth.GenerateToken(u.Email, string(u.Role), 24*time.Hour)
+	if !h.checkLoginStatus(w, u) {
+		return
+	}
+	h.completeLogin(w, r, u)
+}
+
+// completeLogin zavrsava prijavu potvrdjenog korisnika (lozinkom ili SSO-om)
+func (h *UserHandler) completeLogin(w http.ResponseWriter, r *http.Request, u *repo.User) {
+	// uz ukljucen 2FA (ili obavezan za ulogu) lozinka nije dovoljna: klijent
+	// dobija challenge token i prijavu zavrsava kodom na /auth/login/mfa;
+	// brojac neuspjelih pokusaja se tada brise tek nakon ispravnog koda
+	enrollment, err := h.mfa.GetTOTP(r.Context(), u.ID)
+	if err != nil && !errors.Is(err, repo.ErrMFANotEnrolled) {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to login"})
+		return
+	}
+	if enrollment.Enabled() || h.mfaRequired(u.Role) {
+		h.startMFAChallenge(w, r, u, !enrollment.Enabled())
+		return
+	}
+
+	resp, err := h.issueTokens(r, u)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to issue token"})
 		return
 	}
+	h.resetLoginThrottle(r, strings.ToLower(u.Email))
 
-	writeJSON(w, http.StatusOK, authResp{
-		Token: token,
-		User: map[string]any{
-			"id":       u.ID,
-			"fullName": u.FullName,
-			"email":    u.Email,
-			"role":     u.Role,
-		},
-	})
+	writeJSON(w, http.StatusOK, resp)
 }
 
 // GET /api/v1/auth/verify  (ili HEAD)
 // Vraća 200 + { ok:true, email, role } ako je token validan; inače 401
 func (h *UserHandler) Verify(w http.ResponseWriter, r *http.Request) {
-	tokenString := parseBearerToken(r.Header.Get("Authorization"))
-	if tokenString == "" {
-		writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "missing bearer token"})
-		return
-	}
-
-	tc, err := h.auth.VerifyToken(tokenString)
-	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "invalid token"})
+	tc, ok := h.authenticate(w, r)
+	if !ok {
 		return
 	}
 
@@ -165,23 +302,23 @@ func (h *UserHandler) Verify(w http.ResponseWriter, r *http.Request) {
 		"ok":    true,
 		"email": tc.Email,
 		"role":  tc.Role,
+		"perms": tc.Perms,
+		"act":   tc.Actor,
 	})
 }
 
 func (h *UserHandler) Authorize(w http.ResponseWriter, r *http.Request) {
-	tokenString := parseBearerToken(r.Header.Get("Authorization"))
-	if tokenString == "" {
-		writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "missing bearer token"})
+	tc, ok := h.authenticate(w, r)
+	if !ok {
 		return
 	}
 
-	tc, err := h.auth.VerifyToken(tokenString)
-	if err != nil {
-		writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "invalid token"})
+	q := r.URL.Query()
+	if q.Get("service") != "" {
+		h.authorizeRoute(w, r, tc)
 		return
 	}
 
-	q := r.URL.Query()
 	roles := q["role"]
 	if any := q.Get("any"); any != "" {
 		for _, part := range strings.Split(any, ",") {
@@ -191,17 +328,8 @@ func (h *UserHandler) Authorize(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if len(roles) == 0 {
-		writeJSON(w, http.StatusOK, map[string]any{
-			"allowed": true,
-			"email":   tc.Email,
-			"role":    tc.Role,
-		})
-		return
-	}
-
 	userRole := strings.ToUpper(tc.Role)
-	allowed := false
+	allowed := len(roles) == 0
 	for _, r := range roles {
 		if strings.ToUpper(strings.TrimSpace(r)) == userRole {
 			allowed = true
@@ -218,13 +346,96 @@ func (h *UserHandler) Authorize(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// ?perm= se moze ponoviti; token mora imati sve trazene dozvole
+	for _, p := range q["perm"] {
+		if !hasScope(tc.Perms, strings.TrimSpace(p)) {
+			writeJSON(w, http.StatusForbidden, map[string]any{
+				"allowed": false,
+				"reason":  "missing permission " + strings.TrimSpace(p),
+				"role":    tc.Role,
+				"perms":   tc.Perms,
+			})
+			return
+		}
+	}
+
 	writeJSON(w, http.StatusOK, map[string]any{
 		"allowed": true,
 		"email":   tc.Email,
 		"role":    tc.Role,
+		"perms":   tc.Perms,
 	})
 }
 
+// authenticate provjerava bearer token (potpis, exp i listu opozvanih tokena);
+// ako token nije validan, upisuje 401 odgovor i vraca false
+func (h *UserHandler) authenticate(w http.ResponseWriter, r *http.Request) (*TokenClaims, bool) {
+	tokenString := parseBearerToken(r.Header.Get("Authorization"))
+	if tokenString == "" {
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "missing bearer token"})
+		return nil, false
+	}
+
+	tc, err := h.auth.VerifyToken(tokenString)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "invalid token"})
+		return nil, false
+	}
+	// impersonacioni token je samo za citanje
+	if tc.Actor != nil && r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeJSON(w, http.StatusForbidden, map[string]any{"error": "impersonation is read-only"})
+		return nil, false
+	}
+
+	jti, err := uuid.Parse(tc.ID)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "invalid token"})
+		return nil, false
+	}
+	revoked, err := h.tokens.IsAccessTokenRevoked(r.Context(), jti)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "failed to verify token"})
+		return nil, false
+	}
+	if revoked {
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "token revoked"})
+		return nil, false
+	}
+	if sid, err := uuid.Parse(tc.SessionID); err == nil {
+		revoked, err := h.tokens.IsSessionRevoked(r.Context(), sid)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "failed to verify token"})
+			return nil, false
+		}
+		if revoked {
+			writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "session revoked"})
+			return nil, false
+		}
+	}
+
+	// deaktiviran nalog gubi pristup odmah, i sa tokenom koji jos nije istekao
+	userID, err := uuid.Parse(tc.UserID)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "invalid token"})
+		return nil, false
+	}
+	u, err := h.repo.GetByID(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, repo.ErrUserNotFound) {
+			writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "invalid token"})
+			return nil, false
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "failed to verify token"})
+		return nil, false
+	}
+	if !u.Active() {
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "account disabled"})
+		return nil, false
+	}
+
+	return tc, true
+}
+
 func parseBearerToken(header string) string {
 	if header == "" {
 		return ""
@@ -3,16 +3,20 @@ package main
 
 import (
 	"context"
+	"crypto/ed25519"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"time"
 
 	"github.com/Bijelic03/eAdministration/project/microservices/auth/config"
 	"github.com/Bijelic03/eAdministration/project/microservices/auth/db"
 	"github.com/Bijelic03/eAdministration/project/microservices/auth/handlers"
 	"github.com/Bijelic03/eAdministration/project/microservices/auth/repositories"
+	"github.com/Bijelic03/eAdministration/project/microservices/auth/services"
+	"github.com/Bijelic03/eAdministration/project/microservices/common/audit"
 	handler "github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
 )
@@ -24,12 +28,56 @@ func main() {
 	handleErr(err)
 	defer conn.Close()
 
-	// secret za JWT
-	secretKey := []byte(cfg.SecretKeyAuth)
+	// kljuc za potpisivanje JWT-a
+	signingKey, err := loadSigningKey(cfg.JWTPrivateKeyFile)
+	handleErr(err)
+	auth := handlers.NewAuth(signingKey)
 
 	// repo + handler
 	userRepo := repositories.NewUserRepository(conn)
-	userHandler := handlers.NewUserHandler(userRepo, secretKey)
+	tokenRepo := repositories.NewTokenRepository(conn)
+	resetRepo := repositories.NewPasswordResetRepository(conn)
+	throttleRepo := repositories.NewLoginThrottleRepository(conn)
+	mfaRepo := repositories.NewMFARepository(conn)
+	identityRepo := repositories.NewIdentityRepository(conn)
+	apiKeyRepo := repositories.NewAPIKeyRepository(conn)
+	roleRepo := repositories.NewRoleRepository(conn)
+
+	// SSO: lokalni provider (samo za razvoj) se koristi ako drugi nije podesen
+	var devProvider *services.OIDCDevProvider
+	if cfg.OIDCDevProvider {
+		devProvider, err = services.NewOIDCDevProvider("http://localhost:8083" + oidcDevPath)
+		handleErr(err)
+		log.Println("OIDC_DEV_PROVIDER enabled: local sign-on provider accepts any email, never use in production")
+		if cfg.OIDCIssuer == "" {
+			cfg.OIDCIssuer = "http://localhost:8083" + oidcDevPath
+		}
+		if cfg.OIDCClientID == "" {
+			cfg.OIDCClientID = "eadministration"
+		}
+	}
+	var oidcClient *services.OIDCClient
+	if cfg.OIDCIssuer != "" && cfg.OIDCClientID != "" {
+		oidcClient = services.NewOIDCClient(cfg.OIDCIssuer, cfg.OIDCClientID, cfg.OIDCClientSecret, cfg.OIDCRedirectURL, cfg.OIDCScopes)
+	}
+
+	passwordPolicy, err := services.NewPasswordPolicy(cfg.PasswordMinLength, cfg.PasswordMaxLength, cfg.PasswordRequiredClasses, cfg.PasswordDisallowPersonal, cfg.PasswordBlocklistFile)
+	handleErr(err)
+
+	userHandler := handlers.NewUserHandler(userRepo, tokenRepo, resetRepo, throttleRepo, mfaRepo, identityRepo, roleRepo, oidcClient, passwordPolicy, newNotifier(cfg), auth, cfg)
+
+	// audit log dijele svi servisi; akter se u auth handlerima postavlja eksplicitno
+	auditLog := audit.NewLogger(conn, "auth", nil)
+	auditHandler := handlers.NewAuditHandler(auditLog)
+
+	// service tokeni za interne pozive izmedju servisa (SERVICE_CLIENTS)
+	serviceTokenHandler := handlers.NewServiceTokenHandler(auth, cfg.ServiceClients, cfg.ServiceTokenTTL)
+
+	// API kljucevi poslodavaca za berzu poslova
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyRepo)
+
+	// pocetni administratori (BOOTSTRAP_ADMINS), jer se admin uloge ne mogu samoregistrovati
+	bootstrapAdmins(userRepo, cfg.BootstrapAdmins)
 
 	address := ":8083"
 
@@ -37,13 +85,15 @@ func main() {
 	router := mux.NewRouter()
 	router.Use(mux.CORSMethodMiddleware(router))
 	router.Use(jsonContentTypeMiddleware) // setuje Content-Type: application/json
+	router.Use(auditLog.Middleware)
 
 	api := router.PathPrefix("/api/v1").Subrouter()
 
 	// CORS
 	cors := handler.CORS(
 		handler.AllowedMethods([]string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
-		handler.AllowedHeaders([]string{"Authorization", "Content-Type"}),
+		handler.AllowedHeaders([]string{"Authorization", "Content-Type", audit.RequestIDHeader}),
+		handler.ExposedHeaders([]string{audit.RequestIDHeader}),
 		handler.AllowCredentials(),
 		handler.AllowedOrigins([]string{"*"}),
 	)
@@ -51,10 +101,79 @@ func main() {
 	// AUTH ROUTES
 	api.HandleFunc("/auth/register", userHandler.Register).Methods("POST")
 	api.HandleFunc("/auth/login", userHandler.Login).Methods("POST")
+	api.HandleFunc("/auth/login/mfa", userHandler.LoginMFA).Methods("POST")
+	api.HandleFunc("/auth/refresh", userHandler.Refresh).Methods("POST")
+	api.HandleFunc("/auth/logout", userHandler.Logout).Methods("POST")
+	api.HandleFunc("/auth/email/verify", userHandler.VerifyEmail).Methods("POST")
+	api.HandleFunc("/auth/email/verify/resend", userHandler.ResendVerification).Methods("POST")
+
+	// SSO ROUTES
+	api.HandleFunc("/auth/oidc", userHandler.OIDCConfig).Methods("GET")
+	api.HandleFunc("/auth/oidc/start", userHandler.StartOIDC).Methods("POST")
+	api.HandleFunc("/auth/oidc/callback", userHandler.OIDCCallback).Methods("POST")
+	if devProvider != nil {
+		api.PathPrefix("/auth/oidc-dev").Handler(http.StripPrefix(oidcDevPath, devProvider))
+	}
+
+	// PASSWORD ROUTES
+	api.HandleFunc("/auth/password/change", userHandler.ChangePassword).Methods("POST")
+	api.HandleFunc("/auth/password/forgot", userHandler.ForgotPassword).Methods("POST")
+	api.HandleFunc("/auth/password/reset", userHandler.ResetPassword).Methods("POST")
+	api.HandleFunc("/auth/password/policy", userHandler.PasswordPolicy).Methods("GET")
+
+	// SESSION ROUTES
+	api.HandleFunc("/auth/sessions", userHandler.ListSessions).Methods("GET")
+	api.HandleFunc("/auth/sessions", userHandler.RevokeSessions).Methods("DELETE")
+	api.HandleFunc("/auth/sessions/{id}", userHandler.RevokeSession).Methods("DELETE")
+
+	// PROFILE ROUTES
+	api.HandleFunc("/auth/me", userHandler.Me).Methods("GET")
+	api.HandleFunc("/auth/me", userHandler.UpdateMe).Methods("PUT")
+	api.HandleFunc("/auth/me/email/confirm", userHandler.ConfirmEmailChange).Methods("POST")
+	api.HandleFunc("/auth/me/deactivate", userHandler.DeactivateMe).Methods("POST")
+
+	// MFA ROUTES
+	api.HandleFunc("/auth/mfa", userHandler.MFAStatus).Methods("GET")
+	api.HandleFunc("/auth/mfa/totp/enroll", userHandler.EnrollTOTP).Methods("POST")
+	api.HandleFunc("/auth/mfa/totp/confirm", userHandler.ConfirmTOTP).Methods("POST")
+	api.HandleFunc("/auth/mfa/totp/disable", userHandler.DisableTOTP).Methods("POST")
+	api.HandleFunc("/auth/mfa/recovery-codes", userHandler.RegenerateRecoveryCodes).Methods("POST")
+
+	// ADMIN ROUTES
+	api.Handle("/auth/users", userHandler.RequirePolicy(userHandler.ProvisionUser)).Methods("POST")
+	api.Handle("/auth/users/{id}/role", userHandler.RequirePolicy(userHandler.ChangeRole)).Methods("PUT")
+	api.Handle("/auth/users/{id}/role-changes", userHandler.RequirePolicy(userHandler.ListRoleChanges)).Methods("GET")
+	api.Handle("/auth/users/{id}/status", userHandler.RequirePolicy(userHandler.ChangeStatus)).Methods("PUT")
+	api.Handle("/auth/users/{id}/unlock", userHandler.RequirePolicy(userHandler.UnlockUser)).Methods("POST")
+	api.Handle("/auth/users/{id}/mfa", userHandler.RequirePolicy(userHandler.ResetUserMFA)).Methods("DELETE")
+	api.Handle("/auth/users/{id}/impersonate", userHandler.RequirePolicy(userHandler.Impersonate)).Methods("POST")
+	api.Handle("/auth/audit", userHandler.RequirePolicy(auditHandler.List)).Methods("GET")
+
+	// ROLE & PERMISSION ROUTES
+	api.Handle("/auth/permissions", userHandler.RequirePolicy(userHandler.ListPermissions)).Methods("GET")
+	api.Handle("/auth/roles", userHandler.RequirePolicy(userHandler.ListRoles)).Methods("GET")
+	api.Handle("/auth/roles", userHandler.RequirePolicy(userHandler.CreateRole)).Methods("POST")
+	api.Handle("/auth/roles/{id}", userHandler.RequirePolicy(userHandler.UpdateRole)).Methods("PUT")
+	api.Handle("/auth/roles/{id}", userHandler.RequirePolicy(userHandler.DeleteRole)).Methods("DELETE")
+	api.Handle("/auth/users/{id}/roles", userHandler.RequirePolicy(userHandler.ListUserRoles)).Methods("GET")
+	api.Handle("/auth/users/{id}/roles", userHandler.RequirePolicy(userHandler.AssignUserRole)).Methods("POST")
+	api.Handle("/auth/users/{id}/roles/{roleId}", userHandler.RequirePolicy(userHandler.UnassignUserRole)).Methods("DELETE")
+
+	// API KEY ROUTES
+	api.Handle("/auth/api-keys", userHandler.RequirePolicy(apiKeyHandler.Create)).Methods("POST")
+	api.Handle("/auth/api-keys", userHandler.RequirePolicy(apiKeyHandler.List)).Methods("GET")
+	api.Handle("/auth/api-keys/{id}/rotate", userHandler.RequirePolicy(apiKeyHandler.Rotate)).Methods("POST")
+	api.Handle("/auth/api-keys/{id}", userHandler.RequirePolicy(apiKeyHandler.Revoke)).Methods("DELETE")
+	api.Handle("/auth/api-keys/{id}/usage", userHandler.RequirePolicy(apiKeyHandler.Usage)).Methods("GET")
+	api.Handle("/auth/internal/api-keys/introspect", auth.RequireServicePolicy(apiKeyHandler.Introspect)).Methods("POST")
 
 	// AUTH CHECK ROUTES
 	api.HandleFunc("/auth/verify", userHandler.Verify).Methods("GET", "HEAD")
 	api.HandleFunc("/auth/authorize", userHandler.Authorize).Methods("GET")
+	api.HandleFunc("/auth/jwks", auth.JWKS).Methods("GET")
+	api.HandleFunc("/auth/revoked", userHandler.Revoked).Methods("GET")
+	api.HandleFunc("/auth/policy", userHandler.Policy).Methods("GET")
+	api.HandleFunc("/auth/token", serviceTokenHandler.Token).Methods("POST")
 
 	// HTTP Server
 	server := &http.Server{
@@ -82,12 +201,68 @@ func main() {
 	log.Println("Server stopped")
 }
 
+// putanja lokalnog SSO providera (OIDC_DEV_PROVIDER=true)
+const oidcDevPath = "/api/v1/auth/oidc-dev"
+
 func handleErr(err error) {
 	if err != nil {
 		log.Fatalln(err)
 	}
 }
 
+// loadSigningKey cita Ed25519 kljuc iz fajla; bez fajla se generise privremeni
+// kljuc, pa tokeni prestaju da vaze nakon restarta servisa
+func loadSigningKey(path string) (ed25519.PrivateKey, error) {
+	if path == "" {
+		log.Println("JWT_PRIVATE_KEY_FILE not set, generating ephemeral signing key")
+		return handlers.GenerateKey()
+	}
+	pemBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return handlers.ParsePrivateKey(pemBytes)
+}
+
+// bootstrapAdmins kreira naloge iz liste "uloga:email:lozinka" odvojene zarezom,
+// ako vec ne postoje
+func bootstrapAdmins(users handlers.UserRepo, spec string) {
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 {
+			log.Println("bootstrap admin: invalid entry, expected role:email:password")
+			continue
+		}
+
+		ctx := context.Background()
+		if _, err := users.GetByEmail(ctx, parts[1]); err == nil {
+			continue
+		}
+		u := repositories.User{FullName: parts[1], Email: parts[1], Password: parts[2], Role: repositories.UserRole(parts[0])}
+		if _, err := users.Provision(ctx, u, nil); err != nil {
+			log.Printf("bootstrap admin %s: %v", parts[1], err)
+			continue
+		}
+		log.Printf("bootstrap admin %s created with role %s", parts[1], parts[0])
+	}
+}
+
+// newNotifier bira nacin dostave poruka: SMTP ako je SMTP_HOST postavljen,
+// fajl ako je NOTIFIER_FILE postavljen, inace log
+func newNotifier(cfg config.Config) services.Notifier {
+	if cfg.SMTPHost != "" {
+		return services.NewSMTPNotifier(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPFrom)
+	}
+	if cfg.NotifierFile != "" {
+		return services.NewFileNotifier(cfg.NotifierFile)
+	}
+	return services.NewLogNotifier()
+}
+
 func jsonContentTypeMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,132 @@
+package policy
+
+// Dozvole su fina podjela prava unutar servisa ("resurs:akcija"). Osnovna
+// uloga korisnika donosi svoj skup dozvola (RolePermissions), a prilagodjene
+// uloge iz baze dodaju nove; efektivne dozvole se upisuju u token (claim
+// "perms") i provjeravaju prema Rule.Perms.
+const (
+	PermStudentsRead   = "students:read"
+	PermStudentsCreate = "students:create"
+	PermStudentsUpdate = "students:update"
+	PermStudentsDelete = "students:delete"
+
+	PermProfessorsCreate = "professors:create"
+	PermProfessorsUpdate = "professors:update"
+	PermProfessorsDelete = "professors:delete"
+
+	PermCoursesCreate = "courses:create"
+	PermCoursesUpdate = "courses:update"
+	PermCoursesDelete = "courses:delete"
+
+	PermExamsManage = "exams:manage"
+	PermExamsGrade  = "exams:grade"
+
+	PermGradesApprove = "grades:approve"
+
+	PermCalendarManage = "calendar:manage"
+
+	PermEmployeesRead   = "employees:read"
+	PermEmployeesCreate = "employees:create"
+	PermEmployeesUpdate = "employees:update"
+	PermEmployeesDelete = "employees:delete"
+
+	PermCandidatesRead   = "candidates:read"
+	PermCandidatesCreate = "candidates:create"
+	PermCandidatesUpdate = "candidates:update"
+	PermCandidatesDelete = "candidates:delete"
+
+	PermJobsRead         = "jobs:read"
+	PermJobsWrite        = "jobs:write"
+	PermApplicationsRead = "applications:read"
+	PermInterviewsManage = "interviews:manage"
+)
+
+// Permission je opis dozvole u katalogu; Service je servis cije rute dozvola
+// otvara (administrator dodjeljuje samo dozvole svog servisa)
+type Permission struct {
+	Name        string `json:"name"`
+	Service     string `json:"service"`
+	Description string `json:"description"`
+}
+
+// Permissions je katalog svih dozvola
+var Permissions = []Permission{
+	{PermStudentsRead, ServiceUniversity, "List and view students"},
+	{PermStudentsCreate, ServiceUniversity, "Register students"},
+	{PermStudentsUpdate, ServiceUniversity, "Edit students"},
+	{PermStudentsDelete, ServiceUniversity, "Delete students"},
+	{PermProfessorsCreate, ServiceUniversity, "Add professors"},
+	{PermProfessorsUpdate, ServiceUniversity, "Edit professors"},
+	{PermProfessorsDelete, ServiceUniversity, "Delete professors"},
+	{PermCoursesCreate, ServiceUniversity, "Create courses"},
+	{PermCoursesUpdate, ServiceUniversity, "Edit courses"},
+	{PermCoursesDelete, ServiceUniversity, "Delete courses"},
+	{PermExamsManage, ServiceUniversity, "Create, edit and delete exams"},
+	{PermExamsGrade, ServiceUniversity, "View exam registrations and enter grades"},
+	{PermGradesApprove, ServiceUniversity, "Approve or reject grade change requests"},
+	{PermCalendarManage, ServiceUniversity, "Manage academic years, semesters and exam periods"},
+
+	{PermEmployeesRead, ServiceEmploymentOffice, "List and view employees"},
+	{PermEmployeesCreate, ServiceEmploymentOffice, "Add employees"},
+	{PermEmployeesUpdate, ServiceEmploymentOffice, "Edit employees"},
+	{PermEmployeesDelete, ServiceEmploymentOffice, "Delete employees"},
+	{PermCandidatesRead, ServiceEmploymentOffice, "List and view candidates"},
+	{PermCandidatesCreate, ServiceEmploymentOffice, "Add candidates"},
+	{PermCandidatesUpdate, ServiceEmploymentOffice, "Edit candidates"},
+	{PermCandidatesDelete, ServiceEmploymentOffice, "Delete candidates"},
+	{PermJobsRead, ServiceEmploymentOffice, "List and view job offers"},
+	{PermJobsWrite, ServiceEmploymentOffice, "Create, edit and delete job offers"},
+	{PermApplicationsRead, ServiceEmploymentOffice, "View job applications and applicants"},
+	{PermInterviewsManage, ServiceEmploymentOffice, "Schedule, reject and conclude interviews"},
+}
+
+// APIKeyPermissions su dozvole koje se mogu dodijeliti API kljucu poslodavca
+var APIKeyPermissions = []string{PermJobsRead, PermJobsWrite, PermApplicationsRead}
+
+// RolePermissions su dozvole koje donosi osnovna uloga. Administrator
+// fakulteta ne dobija dozvole za ispite i ocjene: ocjene upisuje profesor, a
+// administrator ih mijenja samo odobravanjem zahtjeva za izmjenu.
+var RolePermissions = map[string][]string{
+	facultyAdmin: {
+		PermStudentsRead, PermStudentsCreate, PermStudentsUpdate, PermStudentsDelete,
+		PermProfessorsCreate, PermProfessorsUpdate, PermProfessorsDelete,
+		PermCoursesCreate, PermCoursesUpdate, PermCoursesDelete,
+		PermGradesApprove, PermCalendarManage,
+	},
+	professor: {PermExamsManage, PermExamsGrade},
+	sszAdmin:  permissionsOf(ServiceEmploymentOffice),
+}
+
+// LookupPermission vraca dozvolu iz kataloga
+func LookupPermission(name string) (Permission, bool) {
+	for _, p := range Permissions {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Permission{}, false
+}
+
+// EffectivePermissions spaja dozvole osnovne uloge i prilagodjenih uloga,
+// bez duplikata i dozvola kojih vise nema u katalogu
+func EffectivePermissions(role string, extra []string) []string {
+	perms := []string{}
+	seen := map[string]bool{}
+	for _, p := range append(append([]string{}, RolePermissions[role]...), extra...) {
+		if _, ok := LookupPermission(p); ok && !seen[p] {
+			seen[p] = true
+			perms = append(perms, p)
+		}
+	}
+	return perms
+}
+
+func permissionsOf(service string) []string {
+	var perms []string
+	for _, p := range Permissions {
+		if p.Service == service {
+			perms = append(perms, p.Name)
+		}
+	}
+	return perms
+}
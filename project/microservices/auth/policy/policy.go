@@ -0,0 +1,20 @@
+package policy
+
+import (
+	common "github.com/Bijelic03/eAdministration/project/microservices/common/policy"
+)
+
+// Evaluator pravila je u zajednickom modulu; ovdje su samo aliasi kako bi
+// matrica dozvola i handleri auth servisa koristili iste tipove.
+type (
+	Owner    = common.Owner
+	Rule     = common.Rule
+	Policy   = common.Policy
+	Subject  = common.Subject
+	Decision = common.Decision
+)
+
+const (
+	ServiceRole  = common.ServiceRole
+	EmployerRole = common.EmployerRole
+)
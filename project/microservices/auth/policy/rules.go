@@ -0,0 +1,229 @@
+package policy
+
+import (
+	repo "github.com/Bijelic03/eAdministration/project/microservices/auth/repositories"
+)
+
+// Matrica dozvola za sve servise. Svaka ruta koju servis izlaze iza
+// auth middleware-a mora imati pravilo, inace je zahtjev odbijen.
+
+const (
+	ServiceAuth             = "auth"
+	ServiceUniversity       = "university"
+	ServiceEmploymentOffice = "employmentOffice"
+)
+
+var (
+	student      = string(repo.RoleStudent)
+	professor    = string(repo.RoleProfessor)
+	facultyAdmin = string(repo.RoleFacultyAdmin)
+	employee     = string(repo.RoleEmployee)
+	candidate    = string(repo.RoleCandidate)
+	sszAdmin     = string(repo.RoleSSZAdmin)
+)
+
+var (
+	ownID    = &Owner{Param: "id", Claim: "sub"}
+	ownEmail = &Owner{Param: "email", Claim: "email"}
+)
+
+func roles(r ...string) []string { return r }
+
+func perms(p ...string) []string { return p }
+
+// scope-ovi service tokena za interne pozive izmedju servisa
+const (
+	ScopeProfessorsRead = "university.professors.read"
+	ScopeStudentsRead   = "university.students.read"
+	ScopeGraduationRead = "university.graduation.read"
+	ScopeEmploymentRead = "employmentOffice.employment.read"
+	ScopeAPIKeysVerify  = "auth.apikeys.introspect"
+)
+
+func scopes(s ...string) []string { return s }
+
+const authPrefix = "/api/v1/auth"
+
+var authRules = []Rule{
+	// provisioning naloga i promjena uloga
+	{Method: "POST", Path: authPrefix + "/users", Roles: roles(facultyAdmin, sszAdmin)},
+	{Method: "PUT", Path: authPrefix + "/users/{id}/role", Roles: roles(facultyAdmin, sszAdmin)},
+	{Method: "GET", Path: authPrefix + "/users/{id}/role-changes", Roles: roles(facultyAdmin, sszAdmin)},
+	{Method: "PUT", Path: authPrefix + "/users/{id}/status", Roles: roles(facultyAdmin, sszAdmin)},
+	{Method: "POST", Path: authPrefix + "/users/{id}/unlock", Roles: roles(facultyAdmin, sszAdmin)},
+	{Method: "DELETE", Path: authPrefix + "/users/{id}/mfa", Roles: roles(facultyAdmin, sszAdmin)},
+	{Method: "POST", Path: authPrefix + "/users/{id}/impersonate", Roles: roles(facultyAdmin, sszAdmin)},
+
+	// audit log
+	{Method: "GET", Path: authPrefix + "/audit", Roles: roles(facultyAdmin, sszAdmin)},
+
+	// dozvole i prilagodjene uloge
+	{Method: "GET", Path: authPrefix + "/permissions", Roles: roles(facultyAdmin, sszAdmin)},
+	{Method: "GET", Path: authPrefix + "/roles", Roles: roles(facultyAdmin, sszAdmin)},
+	{Method: "POST", Path: authPrefix + "/roles", Roles: roles(facultyAdmin, sszAdmin)},
+	{Method: "PUT", Path: authPrefix + "/roles/{id}", Roles: roles(facultyAdmin, sszAdmin)},
+	{Method: "DELETE", Path: authPrefix + "/roles/{id}", Roles: roles(facultyAdmin, sszAdmin)},
+	{Method: "GET", Path: authPrefix + "/users/{id}/roles", Roles: roles(facultyAdmin, sszAdmin)},
+	{Method: "POST", Path: authPrefix + "/users/{id}/roles", Roles: roles(facultyAdmin, sszAdmin)},
+	{Method: "DELETE", Path: authPrefix + "/users/{id}/roles/{roleId}", Roles: roles(facultyAdmin, sszAdmin)},
+
+	// API kljucevi poslodavaca
+	{Method: "POST", Path: authPrefix + "/api-keys", Roles: roles(sszAdmin)},
+	{Method: "GET", Path: authPrefix + "/api-keys", Roles: roles(sszAdmin)},
+	{Method: "POST", Path: authPrefix + "/api-keys/{id}/rotate", Roles: roles(sszAdmin)},
+	{Method: "DELETE", Path: authPrefix + "/api-keys/{id}", Roles: roles(sszAdmin)},
+	{Method: "GET", Path: authPrefix + "/api-keys/{id}/usage", Roles: roles(sszAdmin)},
+
+	// interne rute, samo za service tokene (employmentOffice)
+	{Method: "POST", Path: authPrefix + "/internal/api-keys/introspect", Scopes: scopes(ScopeAPIKeysVerify)},
+}
+
+const uni = "/api/v1/university"
+
+var universityRules = []Rule{
+	// professors
+	{Method: "POST", Path: uni + "/professors", Roles: roles(facultyAdmin), Perms: perms(PermProfessorsCreate)},
+	{Method: "GET", Path: uni + "/professors", Roles: roles(facultyAdmin, professor, student)},
+	{Method: "GET", Path: uni + "/professors/{id}", Roles: roles(facultyAdmin, professor, student)},
+	{Method: "GET", Path: uni + "/professors/by-email", Roles: roles(facultyAdmin, professor, student)},
+	{Method: "PUT", Path: uni + "/professors/{id}", Roles: roles(facultyAdmin), OwnRoles: roles(professor), Owner: ownID, Perms: perms(PermProfessorsUpdate)},
+	{Method: "DELETE", Path: uni + "/professors/{id}", Roles: roles(facultyAdmin), Perms: perms(PermProfessorsDelete)},
+
+	// students
+	{Method: "POST", Path: uni + "/students", Roles: roles(facultyAdmin), Perms: perms(PermStudentsCreate)},
+	{Method: "POST", Path: uni + "/students/import", Roles: roles(facultyAdmin), Perms: perms(PermStudentsCreate)},
+	{Method: "GET", Path: uni + "/students", Roles: roles(facultyAdmin, professor, student), Perms: perms(PermStudentsRead)},
+	{Method: "GET", Path: uni + "/students/{id}", Roles: roles(facultyAdmin, professor), OwnRoles: roles(student), Owner: ownID, Perms: perms(PermStudentsRead)},
+	{Method: "GET", Path: uni + "/students/by-email", Roles: roles(facultyAdmin, professor), Perms: perms(PermStudentsRead)},
+	{Method: "GET", Path: uni + "/students/verify-graduation/{indexno}", Roles: roles(facultyAdmin)},
+	{Method: "GET", Path: uni + "/students/{id}/transcript", Roles: roles(facultyAdmin), OwnRoles: roles(student), Owner: ownID, Perms: perms(PermStudentsRead)},
+	{Method: "POST", Path: uni + "/students/{id}/credentials", Roles: roles(facultyAdmin), Perms: perms(PermStudentsUpdate)},
+	{Method: "GET", Path: uni + "/students/{id}/credentials", Roles: roles(facultyAdmin), OwnRoles: roles(student), Owner: ownID, Perms: perms(PermStudentsRead)},
+	{Method: "POST", Path: uni + "/credentials/{id}/revoke", Roles: roles(facultyAdmin), Perms: perms(PermStudentsUpdate)},
+	{Method: "PUT", Path: uni + "/students/{id}/year-of-study", Roles: roles(facultyAdmin), Perms: perms(PermStudentsUpdate)},
+	{Method: "PUT", Path: uni + "/students/{id}", Roles: roles(facultyAdmin), Perms: perms(PermStudentsUpdate)},
+	{Method: "DELETE", Path: uni + "/students/{id}", Roles: roles(facultyAdmin), Perms: perms(PermStudentsDelete)},
+	{Method: "GET", Path: uni + "/students/get/indexno/all", Roles: roles(facultyAdmin)},
+	{Method: "POST", Path: uni + "/students/avg-grades", Roles: roles(facultyAdmin)},
+
+	// courses & programs
+	{Method: "POST", Path: uni + "/courses", Roles: roles(facultyAdmin), Perms: perms(PermCoursesCreate)},
+	{Method: "GET", Path: uni + "/courses", Roles: roles(facultyAdmin, professor, student)},
+	{Method: "GET", Path: uni + "/courses/{id}", Roles: roles(facultyAdmin, professor, student)},
+	{Method: "PUT", Path: uni + "/courses/{id}", Roles: roles(facultyAdmin), Perms: perms(PermCoursesUpdate)},
+	{Method: "DELETE", Path: uni + "/courses/{id}", Roles: roles(facultyAdmin), Perms: perms(PermCoursesDelete)},
+	{Method: "GET", Path: uni + "/courses/{id}/prerequisites", Roles: roles(facultyAdmin, professor, student)},
+	{Method: "PUT", Path: uni + "/courses/{id}/prerequisites", Roles: roles(facultyAdmin), Perms: perms(PermCoursesUpdate)},
+	{Method: "POST", Path: uni + "/courses/{id}/register", Roles: roles(student)},
+	{Method: "DELETE", Path: uni + "/courses/{id}/register", Roles: roles(student)},
+	{Method: "GET", Path: uni + "/courses/{id}/waitlist", Roles: roles(facultyAdmin), Perms: perms(PermStudentsRead)},
+	{Method: "GET", Path: uni + "/courses/{id}/waitlist/me", Roles: roles(student)},
+	{Method: "GET", Path: uni + "/students/me/waitlist", Roles: roles(student)},
+	{Method: "GET", Path: uni + "/courses/my-registrations", Roles: roles(student)},
+	{Method: "GET", Path: uni + "/programs", Roles: roles(facultyAdmin, professor, student)},
+	{Method: "GET", Path: uni + "/programs/{id}/courses", Roles: roles(facultyAdmin, professor, student)},
+	{Method: "GET", Path: uni + "/programs/{id}/curriculum", Roles: roles(facultyAdmin, professor, student)},
+	{Method: "PUT", Path: uni + "/programs/{id}/curriculum/{courseId}", Roles: roles(facultyAdmin), Perms: perms(PermCoursesUpdate)},
+	{Method: "DELETE", Path: uni + "/programs/{id}/curriculum/{courseId}", Roles: roles(facultyAdmin), Perms: perms(PermCoursesUpdate)},
+
+	// exams
+	{Method: "POST", Path: uni + "/exams", Roles: roles(professor), Perms: perms(PermExamsManage)},
+	{Method: "GET", Path: uni + "/exams", Roles: roles(facultyAdmin, professor, student)},
+	{Method: "GET", Path: uni + "/exams/{id}", Roles: roles(facultyAdmin, professor, student)},
+	{Method: "PUT", Path: uni + "/exams/{id}", Roles: roles(professor), Perms: perms(PermExamsManage)},
+	{Method: "DELETE", Path: uni + "/exams/{id}", Roles: roles(professor), Perms: perms(PermExamsManage)},
+	{Method: "POST", Path: uni + "/exams/{id}/register", Roles: roles(student)},
+	{Method: "DELETE", Path: uni + "/exams/{id}/register", Roles: roles(student)},
+	{Method: "PUT", Path: uni + "/exams/{id}/grade", Roles: roles(professor), Perms: perms(PermExamsGrade)},
+	{Method: "PUT", Path: uni + "/exams/{id}/absent", Roles: roles(professor), Perms: perms(PermExamsGrade)},
+	{Method: "GET", Path: uni + "/exams/my-registrations", Roles: roles(student)},
+	{Method: "GET", Path: uni + "/exams/{id}/examregistrations", Roles: roles(professor), Perms: perms(PermExamsGrade)},
+	{Method: "POST", Path: uni + "/exams/{id}/grade-changes", Roles: roles(professor), Perms: perms(PermExamsGrade)},
+	{Method: "GET", Path: uni + "/exams/{id}/grade-changes", Roles: roles(facultyAdmin, professor), Perms: perms(PermExamsGrade)},
+	{Method: "GET", Path: uni + "/grade-changes", Roles: roles(facultyAdmin), Perms: perms(PermGradesApprove)},
+	{Method: "POST", Path: uni + "/grade-changes/{id}/approve", Roles: roles(facultyAdmin), Perms: perms(PermGradesApprove)},
+	{Method: "POST", Path: uni + "/grade-changes/{id}/reject", Roles: roles(facultyAdmin), Perms: perms(PermGradesApprove)},
+
+	// akademski kalendar
+	{Method: "POST", Path: uni + "/academic-years", Roles: roles(facultyAdmin), Perms: perms(PermCalendarManage)},
+	{Method: "GET", Path: uni + "/academic-years", Roles: roles(facultyAdmin, professor, student)},
+	{Method: "GET", Path: uni + "/academic-years/{id}", Roles: roles(facultyAdmin, professor, student)},
+	{Method: "POST", Path: uni + "/academic-years/{id}/semesters", Roles: roles(facultyAdmin), Perms: perms(PermCalendarManage)},
+	{Method: "POST", Path: uni + "/academic-years/{id}/exam-periods", Roles: roles(facultyAdmin), Perms: perms(PermCalendarManage)},
+	{Method: "PUT", Path: uni + "/semesters/{id}", Roles: roles(facultyAdmin), Perms: perms(PermCalendarManage)},
+	{Method: "DELETE", Path: uni + "/semesters/{id}", Roles: roles(facultyAdmin), Perms: perms(PermCalendarManage)},
+	{Method: "PUT", Path: uni + "/exam-periods/{id}", Roles: roles(facultyAdmin), Perms: perms(PermCalendarManage)},
+	{Method: "DELETE", Path: uni + "/exam-periods/{id}", Roles: roles(facultyAdmin), Perms: perms(PermCalendarManage)},
+	{Method: "GET", Path: uni + "/exam-periods/{id}/exams", Roles: roles(facultyAdmin, professor, student)},
+	{Method: "GET", Path: uni + "/calendar/current", Roles: roles(facultyAdmin, professor, student)},
+
+	// interne rute, samo za service tokene (employmentOffice)
+	{Method: "GET", Path: uni + "/internal/professors", Scopes: scopes(ScopeProfessorsRead)},
+	{Method: "GET", Path: uni + "/internal/students/indexno", Scopes: scopes(ScopeStudentsRead)},
+	{Method: "POST", Path: uni + "/internal/students/avg-grades", Scopes: scopes(ScopeStudentsRead)},
+	{Method: "GET", Path: uni + "/internal/students/verify-graduation/{indexno}", Scopes: scopes(ScopeGraduationRead)},
+}
+
+const eo = "/api/v1/employmentOffice"
+
+var employmentOfficeRules = []Rule{
+	// employees
+	{Method: "POST", Path: eo + "/employees", Roles: roles(sszAdmin), Perms: perms(PermEmployeesCreate)},
+	{Method: "GET", Path: eo + "/employees", Roles: roles(sszAdmin, employee), Perms: perms(PermEmployeesRead)},
+	{Method: "GET", Path: eo + "/employees/{id}", Roles: roles(sszAdmin), OwnRoles: roles(employee), Owner: ownID, Perms: perms(PermEmployeesRead)},
+	{Method: "GET", Path: eo + "/employees/by-email", Roles: roles(sszAdmin, employee), Perms: perms(PermEmployeesRead)},
+	{Method: "PUT", Path: eo + "/employees/{id}", Roles: roles(sszAdmin), OwnRoles: roles(employee), Owner: ownID, Perms: perms(PermEmployeesUpdate)},
+	{Method: "PUT", Path: eo + "/employees/quit/job", Roles: roles(employee)},
+	{Method: "DELETE", Path: eo + "/employees/{id}", Roles: roles(sszAdmin), Perms: perms(PermEmployeesDelete)},
+	{Method: "GET", Path: eo + "/employees/professors/all", Roles: roles(sszAdmin, employee)},
+	{Method: "GET", Path: eo + "/employees/employed/{indexno}", Roles: roles(sszAdmin)},
+
+	// candidates
+	{Method: "POST", Path: eo + "/candidates", Roles: roles(sszAdmin), Perms: perms(PermCandidatesCreate)},
+	{Method: "GET", Path: eo + "/candidates", Roles: roles(sszAdmin), Perms: perms(PermCandidatesRead)},
+	{Method: "GET", Path: eo + "/candidates/{id}", Roles: roles(sszAdmin), OwnRoles: roles(candidate), Owner: ownID, Perms: perms(PermCandidatesRead)},
+	{Method: "GET", Path: eo + "/candidates/by-email", Roles: roles(sszAdmin, candidate), Perms: perms(PermCandidatesRead)},
+	{Method: "PUT", Path: eo + "/candidates/{id}", Roles: roles(sszAdmin), OwnRoles: roles(candidate), Owner: ownID, Perms: perms(PermCandidatesUpdate)},
+	{Method: "DELETE", Path: eo + "/candidates/{id}", Roles: roles(sszAdmin), Perms: perms(PermCandidatesDelete)},
+	{Method: "GET", Path: eo + "/candidates/get/indexno/all", Roles: roles(sszAdmin)},
+
+	// jobs
+	// (API kljuc poslodavca smije raditi samo sa oglasima svog poslodavca)
+	{Method: "POST", Path: eo + "/jobs", Roles: roles(sszAdmin), Perms: perms(PermJobsWrite)},
+	{Method: "GET", Path: eo + "/jobs", Roles: roles(sszAdmin, candidate, employee), Perms: perms(PermJobsRead)},
+	{Method: "GET", Path: eo + "/jobs/{id}", Roles: roles(sszAdmin, candidate, employee), Perms: perms(PermJobsRead)},
+	{Method: "PUT", Path: eo + "/jobs/{id}", Roles: roles(sszAdmin), Perms: perms(PermJobsWrite)},
+	{Method: "DELETE", Path: eo + "/jobs/{id}", Roles: roles(sszAdmin), Perms: perms(PermJobsWrite)},
+	{Method: "POST", Path: eo + "/jobs/{id}/{email}/apply", OwnRoles: roles(candidate), Owner: ownEmail},
+	{Method: "GET", Path: eo + "/jobs/{id}/candidates", Roles: roles(sszAdmin), Perms: perms(PermApplicationsRead)},
+
+	// provjera diploma kandidata (potpis univerziteta)
+	{Method: "POST", Path: eo + "/credentials/verify", Roles: roles(sszAdmin, employee)},
+
+	// job applications
+	// (API kljuc poslodavca vidi samo prijave na oglase svog poslodavca)
+	{Method: "GET", Path: eo + "/jobapplications", Roles: roles(sszAdmin, candidate), Perms: perms(PermApplicationsRead)},
+	{Method: "DELETE", Path: eo + "/jobapplications/{id}", Roles: roles(sszAdmin, candidate)},
+
+	// interviews
+	{Method: "POST", Path: eo + "/interviews", Roles: roles(sszAdmin), Perms: perms(PermInterviewsManage)},
+	{Method: "GET", Path: eo + "/interviews", Roles: roles(sszAdmin, candidate)},
+	{Method: "DELETE", Path: eo + "/interviews/{id}", Roles: roles(sszAdmin, candidate)},
+	{Method: "PATCH", Path: eo + "/interviews/{id}", Roles: roles(candidate)},
+	{Method: "DELETE", Path: eo + "/interviews/{id}/odbij", Roles: roles(sszAdmin), Perms: perms(PermInterviewsManage)},
+	{Method: "PATCH", Path: eo + "/interviews/{candidateid}/zaposli/{jobid}", Roles: roles(sszAdmin), Perms: perms(PermInterviewsManage)},
+
+	// interne rute, samo za service tokene (university)
+	{Method: "GET", Path: eo + "/internal/employees/employed/{indexno}", Scopes: scopes(ScopeEmploymentRead)},
+}
+
+var policies = map[string]*Policy{
+	ServiceAuth:             {Service: ServiceAuth, Rules: authRules},
+	ServiceUniversity:       {Service: ServiceUniversity, Rules: universityRules},
+	ServiceEmploymentOffice: {Service: ServiceEmploymentOffice, Rules: employmentOfficeRules},
+}
+
+// For vraca politiku servisa ili nil ako servis nije poznat
+func For(service string) *Policy {
+	return policies[service]
+}
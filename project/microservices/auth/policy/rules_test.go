@@ -0,0 +1,116 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+)
+
+// subject gradi korisnika sa dozvolama koje donosi njegova osnovna uloga
+// (kao u access tokenu)
+func subject(role, id, email string) Subject {
+	return Subject{UserID: id, Email: email, Role: role, Perms: EffectivePermissions(role, nil)}
+}
+
+func TestAuthorizationMatrix(t *testing.T) {
+	const (
+		studentID = "11111111-1111-1111-1111-111111111111"
+		otherID   = "22222222-2222-2222-2222-222222222222"
+	)
+	var (
+		admin    = subject(facultyAdmin, otherID, "admin@uni.rs")
+		prof     = subject(professor, otherID, "prof@uni.rs")
+		stud     = subject(student, studentID, "student@uni.rs")
+		ssz      = subject(sszAdmin, otherID, "ssz@ssz.rs")
+		cand     = subject(candidate, studentID, "cand@mail.rs")
+		emp      = subject(employee, otherID, "emp@ssz.rs")
+		service  = Subject{Role: ServiceRole, Scopes: []string{ScopeStudentsRead}}
+		employer = Subject{Role: EmployerRole, Perms: []string{PermJobsRead, PermApplicationsRead}}
+	)
+
+	tests := []struct {
+		name    string
+		service string
+		method  string
+		path    string
+		sub     Subject
+		allowed bool
+	}{
+		{"admin creates student", ServiceUniversity, "POST", uni + "/students", admin, true},
+		{"professor cannot create student", ServiceUniversity, "POST", uni + "/students", prof, false},
+		{"student reads own record", ServiceUniversity, "GET", uni + "/students/" + studentID, stud, true},
+		{"student cannot read other record", ServiceUniversity, "GET", uni + "/students/" + otherID, stud, false},
+		{"by-email is not an id", ServiceUniversity, "GET", uni + "/students/by-email", stud, false},
+		{"professor looks up by email", ServiceUniversity, "GET", uni + "/students/by-email", prof, true},
+		{"student reads own transcript", ServiceUniversity, "GET", uni + "/students/" + studentID + "/transcript", stud, true},
+		{"professor grades", ServiceUniversity, "PUT", uni + "/exams/" + otherID + "/grade", prof, true},
+		{"student cannot grade", ServiceUniversity, "PUT", uni + "/exams/" + otherID + "/grade", stud, false},
+		{"student withdraws from exam", ServiceUniversity, "DELETE", uni + "/exams/" + otherID + "/register", stud, true},
+		{"professor cannot approve grade change", ServiceUniversity, "POST", uni + "/grade-changes/" + otherID + "/approve", prof, false},
+		{"admin cannot grade", ServiceUniversity, "PUT", uni + "/exams/" + otherID + "/grade", admin, false},
+		{"admin cannot mark absent", ServiceUniversity, "PUT", uni + "/exams/" + otherID + "/absent", admin, false},
+		{"admin cannot read exam registrations", ServiceUniversity, "GET", uni + "/exams/" + otherID + "/examregistrations", admin, false},
+		{"admin cannot create exam", ServiceUniversity, "POST", uni + "/exams", admin, false},
+		{"admin cannot update exam", ServiceUniversity, "PUT", uni + "/exams/" + otherID, admin, false},
+		{"admin cannot delete exam", ServiceUniversity, "DELETE", uni + "/exams/" + otherID, admin, false},
+		{"admin cannot request grade change", ServiceUniversity, "POST", uni + "/exams/" + otherID + "/grade-changes", admin, false},
+		{"admin approves grade change", ServiceUniversity, "POST", uni + "/grade-changes/" + otherID + "/approve", admin, true},
+		{"admin manages calendar", ServiceUniversity, "POST", uni + "/academic-years", admin, true},
+		{"service reads index numbers", ServiceUniversity, "GET", uni + "/internal/students/indexno", service, true},
+		{"service needs the right scope", ServiceUniversity, "GET", uni + "/internal/students/verify-graduation/RA1", service, false},
+		{"user cannot call internal route", ServiceUniversity, "GET", uni + "/internal/students/indexno", admin, false},
+		{"employer key on university", ServiceUniversity, "GET", uni + "/students", employer, false},
+		{"unknown university route", ServiceUniversity, "GET", uni + "/reports", admin, false},
+
+		{"candidate applies as self", ServiceEmploymentOffice, "POST", eo + "/jobs/" + otherID + "/cand@mail.rs/apply", cand, true},
+		{"candidate cannot apply for another", ServiceEmploymentOffice, "POST", eo + "/jobs/" + otherID + "/other@mail.rs/apply", cand, false},
+		{"employee reads own record", ServiceEmploymentOffice, "GET", eo + "/employees/" + otherID, emp, true},
+		{"employer key reads jobs", ServiceEmploymentOffice, "GET", eo + "/jobs", employer, true},
+		{"employer key cannot write jobs", ServiceEmploymentOffice, "POST", eo + "/jobs", employer, false},
+		{"employer key reads applicants of a job", ServiceEmploymentOffice, "GET", eo + "/jobs/" + otherID + "/candidates", employer, true},
+		{"ssz admin schedules interview", ServiceEmploymentOffice, "POST", eo + "/interviews", ssz, true},
+		{"university admin on employment office", ServiceEmploymentOffice, "GET", eo + "/employees", admin, false},
+
+		{"ssz admin issues api key", ServiceAuth, "POST", authPrefix + "/api-keys", ssz, true},
+		{"faculty admin cannot issue api key", ServiceAuth, "POST", authPrefix + "/api-keys", admin, false},
+		{"student cannot read audit log", ServiceAuth, "GET", authPrefix + "/audit", stud, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := For(tt.service).Authorize(tt.method, tt.path, tt.sub)
+			if d.Allowed != tt.allowed {
+				t.Errorf("%s %s as %s: Allowed = %v, want %v (reason %q)", tt.method, tt.path, tt.sub.Role, d.Allowed, tt.allowed, d.Reason)
+			}
+		})
+	}
+}
+
+func TestRulesAreWellFormed(t *testing.T) {
+	prefixes := map[string]string{
+		ServiceAuth:             authPrefix + "/",
+		ServiceUniversity:       uni + "/",
+		ServiceEmploymentOffice: eo + "/",
+	}
+	for service, p := range policies {
+		seen := map[string]bool{}
+		for _, rule := range p.Rules {
+			key := rule.Method + " " + rule.Path
+			if !strings.HasPrefix(rule.Path, prefixes[service]) {
+				t.Errorf("%s: %s belongs to another service", service, key)
+			}
+			if seen[key] {
+				t.Errorf("%s: duplicate rule %s", service, key)
+			}
+			seen[key] = true
+
+			for _, perm := range rule.Perms {
+				if _, ok := LookupPermission(perm); !ok {
+					t.Errorf("%s: %s uses unknown permission %q", service, key, perm)
+				}
+			}
+			if len(rule.OwnRoles) > 0 && rule.Owner == nil {
+				t.Errorf("%s: %s has OwnRoles without Owner", service, key)
+			}
+		}
+	}
+}
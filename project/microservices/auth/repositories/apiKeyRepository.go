@@ -0,0 +1,189 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	ErrInvalidAPIKey  = errors.New("invalid, revoked or expired api key")
+)
+
+// APIKey je kljuc poslodavca za pristup berzi poslova; sam kljuc se prikazuje
+// samo pri kreiranju i rotaciji
+type APIKey struct {
+	ID          uuid.UUID  `json:"id"`
+	EmployerID  uuid.UUID  `json:"employerId"`
+	Name        string     `json:"name"`
+	Prefix      string     `json:"prefix"`
+	Permissions []string   `json:"permissions"`
+	CreatedBy   *uuid.UUID `json:"createdBy"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	ExpiresAt   *time.Time `json:"expiresAt"`
+	RevokedAt   *time.Time `json:"revokedAt"`
+	RotatedFrom *uuid.UUID `json:"rotatedFrom"`
+	LastUsedAt  *time.Time `json:"lastUsedAt"`
+	UsageCount  int64      `json:"usageCount"`
+}
+
+// APIKeyUsage je broj zahtjeva jednog kljuca u danu
+type APIKeyUsage struct {
+	Day      time.Time `json:"day"`
+	Requests int64     `json:"requests"`
+}
+
+type apiKeyRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAPIKeyRepository(db *pgxpool.Pool) *apiKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+const apiKeyColumns = `id, employer_id, name, prefix, permissions, created_by, created_at,
+	expires_at, revoked_at, rotated_from, last_used_at, usage_count`
+
+func scanAPIKey(row pgx.Row) (*APIKey, error) {
+	var k APIKey
+	err := row.Scan(&k.ID, &k.EmployerID, &k.Name, &k.Prefix, &k.Permissions, &k.CreatedBy, &k.CreatedAt,
+		&k.ExpiresAt, &k.RevokedAt, &k.RotatedFrom, &k.LastUsedAt, &k.UsageCount)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, err
+	}
+	return &k, nil
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, k APIKey, keyHash string) (*APIKey, error) {
+	q := `
+		INSERT INTO api_keys (id, employer_id, name, prefix, key_hash, permissions, created_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING ` + apiKeyColumns
+	return scanAPIKey(r.db.QueryRow(ctx, q, uuid.New(), k.EmployerID, k.Name, k.Prefix, keyHash,
+		k.Permissions, k.CreatedBy, k.ExpiresAt))
+}
+
+func (r *apiKeyRepository) GetByID(ctx context.Context, id uuid.UUID) (*APIKey, error) {
+	q := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE id = $1`
+	return scanAPIKey(r.db.QueryRow(ctx, q, id))
+}
+
+// List vraca kljuceve (najnoviji prvi), opciono samo jednog poslodavca
+func (r *apiKeyRepository) List(ctx context.Context, employerID *uuid.UUID) ([]APIKey, error) {
+	q := `
+		SELECT ` + apiKeyColumns + ` FROM api_keys
+		WHERE $1::uuid IS NULL OR employer_id = $1
+		ORDER BY created_at DESC
+	`
+	rows, err := r.db.Query(ctx, q, employerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []APIKey{}
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *k)
+	}
+	return keys, rows.Err()
+}
+
+// Revoke opoziva aktivan kljuc
+func (r *apiKeyRepository) Revoke(ctx context.Context, id uuid.UUID) (*APIKey, error) {
+	q := `
+		UPDATE api_keys SET revoked_at = now()
+		WHERE id = $1 AND revoked_at IS NULL
+		RETURNING ` + apiKeyColumns
+	return scanAPIKey(r.db.QueryRow(ctx, q, id))
+}
+
+// Rotate opoziva aktivan kljuc i u istoj transakciji izdaje novi sa istim
+// poslodavcem, nazivom, dozvolama i rokom vazenja
+func (r *apiKeyRepository) Rotate(ctx context.Context, id uuid.UUID, prefix, keyHash string, rotatedBy *uuid.UUID) (*APIKey, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	old, err := scanAPIKey(tx.QueryRow(ctx, `
+		UPDATE api_keys SET revoked_at = now()
+		WHERE id = $1 AND revoked_at IS NULL
+		RETURNING `+apiKeyColumns, id))
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := scanAPIKey(tx.QueryRow(ctx, `
+		INSERT INTO api_keys (id, employer_id, name, prefix, key_hash, permissions, created_by, expires_at, rotated_from)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING `+apiKeyColumns,
+		uuid.New(), old.EmployerID, old.Name, prefix, keyHash, old.Permissions, rotatedBy, old.ExpiresAt, old.ID))
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// Use pronalazi aktivan kljuc po hashu i uvecava njegove brojace upotrebe
+func (r *apiKeyRepository) Use(ctx context.Context, keyHash string) (*APIKey, error) {
+	q := `
+		UPDATE api_keys SET usage_count = usage_count + 1, last_used_at = now()
+		WHERE key_hash = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > now())
+		RETURNING ` + apiKeyColumns
+	k, err := scanAPIKey(r.db.QueryRow(ctx, q, keyHash))
+	if err != nil {
+		if errors.Is(err, ErrAPIKeyNotFound) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+
+	const usage = `
+		INSERT INTO api_key_usage (key_id, day, requests) VALUES ($1, current_date, 1)
+		ON CONFLICT (key_id, day) DO UPDATE SET requests = api_key_usage.requests + 1
+	`
+	if _, err := r.db.Exec(ctx, usage, k.ID); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Usage vraca dnevne brojace kljuca od datuma since (najnoviji dan prvi)
+func (r *apiKeyRepository) Usage(ctx context.Context, id uuid.UUID, since time.Time) ([]APIKeyUsage, error) {
+	const q = `
+		SELECT day, requests FROM api_key_usage
+		WHERE key_id = $1 AND day >= $2::date
+		ORDER BY day DESC
+	`
+	rows, err := r.db.Query(ctx, q, id, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	usage := []APIKeyUsage{}
+	for rows.Next() {
+		var u APIKeyUsage
+		if err := rows.Scan(&u.Day, &u.Requests); err != nil {
+			return nil, err
+		}
+		usage = append(usage, u)
+	}
+	return usage, rows.Err()
+}
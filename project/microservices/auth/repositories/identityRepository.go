@@ -0,0 +1,88 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrIdentityNotFound = errors.New("external identity is not linked")
+	ErrIdentityConflict = errors.New("account is already linked to another external identity")
+	ErrInvalidOIDCState = errors.New("invalid or expired sso state")
+)
+
+type identityRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewIdentityRepository(db *pgxpool.Pool) *identityRepository {
+	return &identityRepository{db: db}
+}
+
+// CreateOIDCState cuva hash state-a zapocete SSO prijave zajedno sa PKCE
+// verifierom i nonce-om, koji se koriste pri razmjeni koda
+func (r *identityRepository) CreateOIDCState(ctx context.Context, stateHash, codeVerifier, nonce string, expiresAt time.Time) error {
+	const q = `
+		INSERT INTO oidc_states (state_hash, code_verifier, nonce, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`
+	_, err := r.db.Exec(ctx, q, stateHash, codeVerifier, nonce, expiresAt)
+	return err
+}
+
+// ConsumeOIDCState trosi state (moze uspjeti samo jednom) i vraca verifier i nonce
+func (r *identityRepository) ConsumeOIDCState(ctx context.Context, stateHash string) (string, string, error) {
+	const q = `
+		UPDATE oidc_states SET used_at = now()
+		WHERE state_hash = $1 AND used_at IS NULL AND expires_at > now()
+		RETURNING code_verifier, nonce
+	`
+	var verifier, nonce string
+	if err := r.db.QueryRow(ctx, q, stateHash).Scan(&verifier, &nonce); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", "", ErrInvalidOIDCState
+		}
+		return "", "", err
+	}
+	return verifier, nonce, nil
+}
+
+// TouchIdentity biljezi prijavu vanjskim identitetom i vraca povezani nalog
+func (r *identityRepository) TouchIdentity(ctx context.Context, issuer, subject string) (uuid.UUID, error) {
+	const q = `
+		UPDATE user_identities SET last_login_at = now()
+		WHERE issuer = $1 AND subject = $2
+		RETURNING user_id
+	`
+	var userID uuid.UUID
+	if err := r.db.QueryRow(ctx, q, issuer, subject).Scan(&userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return uuid.Nil, ErrIdentityNotFound
+		}
+		return uuid.Nil, err
+	}
+	return userID, nil
+}
+
+// LinkIdentity povezuje vanjski identitet sa nalogom; nalog moze imati samo
+// jedan identitet po izdavaocu
+func (r *identityRepository) LinkIdentity(ctx context.Context, userID uuid.UUID, issuer, subject, email string) error {
+	const q = `
+		INSERT INTO user_identities (id, user_id, issuer, subject, email, last_login_at)
+		VALUES ($1, $2, $3, $4, $5, now())
+	`
+	if _, err := r.db.Exec(ctx, q, uuid.New(), userID, issuer, subject, email); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrIdentityConflict
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	ThrottleAccount = "account"
+	ThrottleIP      = "ip"
+	// ponovno slanje verifikacionog emaila, po adresi
+	ThrottleVerificationEmail = "verification-email"
+)
+
+// LockoutPolicy: nakon MaxAttempts neuspjesnih pokusaja unutar Window kljuc se
+// zakljucava na BaseLockout, a svaki sljedeci neuspjeh udvostrucuje trajanje do MaxLockout
+type LockoutPolicy struct {
+	MaxAttempts int
+	Window      time.Duration
+	BaseLockout time.Duration
+	MaxLockout  time.Duration
+}
+
+func (p LockoutPolicy) lockoutFor(failures int) time.Duration {
+	if failures < p.MaxAttempts {
+		return 0
+	}
+	d := p.BaseLockout
+	for i := p.MaxAttempts; i < failures && d < p.MaxLockout; i++ {
+		d *= 2
+	}
+	if d > p.MaxLockout {
+		d = p.MaxLockout
+	}
+	return d
+}
+
+type loginThrottleRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewLoginThrottleRepository(db *pgxpool.Pool) *loginThrottleRepository {
+	return &loginThrottleRepository{db: db}
+}
+
+// LockedUntil vraca vrijeme do kada je kljuc zakljucan (nulto vrijeme ako nije)
+func (r *loginThrottleRepository) LockedUntil(ctx context.Context, kind, key string) (time.Time, error) {
+	const q = `SELECT locked_until FROM login_throttles WHERE kind = $1 AND key = $2 AND locked_until > now()`
+	var until time.Time
+	if err := r.db.QueryRow(ctx, q, kind, key).Scan(&until); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return time.Time{}, nil
+		}
+		return time.Time{}, err
+	}
+	return until, nil
+}
+
+// RegisterFailure biljezi neuspjesan pokusaj i po potrebi zakljucava kljuc;
+// brojac se resetuje ako je prethodni neuspjeh stariji od Window
+func (r *loginThrottleRepository) RegisterFailure(ctx context.Context, kind, key string, p LockoutPolicy) (time.Time, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	const sel = `
+		SELECT failures, last_failure_at
+		FROM login_throttles
+		WHERE kind = $1 AND key = $2
+		FOR UPDATE
+	`
+	now := time.Now()
+	failures := 0
+	var last time.Time
+	err = tx.QueryRow(ctx, sel, kind, key).Scan(&failures, &last)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return time.Time{}, err
+	}
+	if err == nil && now.Sub(last) > p.Window {
+		failures = 0
+	}
+	failures++
+
+	var lockedUntil *time.Time
+	if d := p.lockoutFor(failures); d > 0 {
+		until := now.Add(d)
+		lockedUntil = &until
+	}
+
+	const upsert = `
+		INSERT INTO login_throttles (kind, key, failures, last_failure_at, locked_until)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (kind, key) DO UPDATE
+		SET failures = EXCLUDED.failures,
+		    last_failure_at = EXCLUDED.last_failure_at,
+		    locked_until = EXCLUDED.locked_until
+	`
+	if _, err := tx.Exec(ctx, upsert, kind, key, failures, now, lockedUntil); err != nil {
+		return time.Time{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return time.Time{}, err
+	}
+
+	if lockedUntil == nil {
+		return time.Time{}, nil
+	}
+	return *lockedUntil, nil
+}
+
+// Reset brise brojac (uspjesna prijava ili otkljucavanje od strane admina)
+func (r *loginThrottleRepository) Reset(ctx context.Context, kind, key string) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM login_throttles WHERE kind = $1 AND key = $2`, kind, key)
+	return err
+}
@@ -0,0 +1,224 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrMFANotEnrolled      = errors.New("two-factor authentication is not enrolled")
+	ErrMFAAlreadyEnabled   = errors.New("two-factor authentication is already enabled")
+	ErrInvalidMFAChallenge = errors.New("invalid or expired mfa challenge")
+)
+
+// broj pogresnih kodova nakon kojeg challenge prestaje da vazi
+const maxMFAChallengeAttempts = 5
+
+// TOTPEnrollment je TOTP secret korisnika; EnabledAt je nil dok korisnik
+// ne potvrdi upis prvim ispravnim kodom
+type TOTPEnrollment struct {
+	UserID       uuid.UUID
+	Secret       string
+	EnabledAt    *time.Time
+	LastUsedStep int64
+}
+
+func (e *TOTPEnrollment) Enabled() bool {
+	return e != nil && e.EnabledAt != nil
+}
+
+type mfaRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewMFARepository(db *pgxpool.Pool) *mfaRepository {
+	return &mfaRepository{db: db}
+}
+
+func (r *mfaRepository) GetTOTP(ctx context.Context, userID uuid.UUID) (*TOTPEnrollment, error) {
+	const q = `SELECT user_id, totp_secret, enabled_at, last_used_step FROM user_mfa WHERE user_id = $1`
+	var e TOTPEnrollment
+	if err := r.db.QueryRow(ctx, q, userID).Scan(&e.UserID, &e.Secret, &e.EnabledAt, &e.LastUsedStep); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrMFANotEnrolled
+		}
+		return nil, err
+	}
+	return &e, nil
+}
+
+// SaveTOTPSecret cuva novi (jos nepotvrdjeni) secret; aktivan secret se ne moze prepisati
+func (r *mfaRepository) SaveTOTPSecret(ctx context.Context, userID uuid.UUID, secret string) error {
+	const q = `
+		INSERT INTO user_mfa (user_id, totp_secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET totp_secret = EXCLUDED.totp_secret, last_used_step = 0, created_at = now()
+		WHERE user_mfa.enabled_at IS NULL
+	`
+	tag, err := r.db.Exec(ctx, q, userID, secret)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrMFAAlreadyEnabled
+	}
+	return nil
+}
+
+// EnableTOTP aktivira upisani secret i postavlja nove recovery kodove
+func (r *mfaRepository) EnableTOTP(ctx context.Context, userID uuid.UUID, step int64, recoveryHashes []string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	const upd = `
+		UPDATE user_mfa SET enabled_at = now(), last_used_step = $2
+		WHERE user_id = $1 AND enabled_at IS NULL
+	`
+	tag, err := tx.Exec(ctx, upd, userID, step)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrMFAAlreadyEnabled
+	}
+	if err := replaceRecoveryCodes(ctx, tx, userID, recoveryHashes); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// UseTOTPStep biljezi iskoristen vremenski korak; vraca false ako je kod
+// tog ili kasnijeg koraka vec iskoristen (zastita od ponovne upotrebe koda)
+func (r *mfaRepository) UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	const q = `UPDATE user_mfa SET last_used_step = $2 WHERE user_id = $1 AND last_used_step < $2`
+	tag, err := r.db.Exec(ctx, q, userID, step)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// UseRecoveryCode trosi jednokratni recovery kod
+func (r *mfaRepository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	const q = `
+		UPDATE mfa_recovery_codes SET used_at = now()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+	tag, err := r.db.Exec(ctx, q, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *mfaRepository) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, recoveryHashes []string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, recoveryHashes); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *mfaRepository) CountRecoveryCodes(ctx context.Context, userID uuid.UUID) (int, error) {
+	const q = `SELECT count(*) FROM mfa_recovery_codes WHERE user_id = $1 AND used_at IS NULL`
+	var n int
+	err := r.db.QueryRow(ctx, q, userID).Scan(&n)
+	return n, err
+}
+
+// DisableTOTP brise secret i recovery kodove korisnika
+func (r *mfaRepository) DisableTOTP(ctx context.Context, userID uuid.UUID) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM user_mfa WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM mfa_challenges WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx pgx.Tx, userID uuid.UUID, hashes []string) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	for _, h := range hashes {
+		const ins = `INSERT INTO mfa_recovery_codes (id, user_id, code_hash) VALUES ($1, $2, $3)`
+		if _, err := tx.Exec(ctx, ins, uuid.New(), userID, h); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateChallenge cuva hash challenge tokena koji se izdaje nakon ispravne lozinke
+func (r *mfaRepository) CreateChallenge(ctx context.Context, userID uuid.UUID, tokenHash string, expiresAt time.Time) error {
+	const q = `
+		INSERT INTO mfa_challenges (id, user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`
+	_, err := r.db.Exec(ctx, q, uuid.New(), userID, tokenHash, expiresAt)
+	return err
+}
+
+// GetChallenge vraca korisnika vazeceg challenge-a (neiskoristen, neistekao,
+// bez previse pogresnih kodova)
+func (r *mfaRepository) GetChallenge(ctx context.Context, tokenHash string) (uuid.UUID, error) {
+	const q = `
+		SELECT user_id FROM mfa_challenges
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > now() AND attempts < $2
+	`
+	var userID uuid.UUID
+	if err := r.db.QueryRow(ctx, q, tokenHash, maxMFAChallengeAttempts).Scan(&userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return uuid.Nil, ErrInvalidMFAChallenge
+		}
+		return uuid.Nil, err
+	}
+	return userID, nil
+}
+
+// FailChallenge broji pogresan kod za challenge
+func (r *mfaRepository) FailChallenge(ctx context.Context, tokenHash string) error {
+	_, err := r.db.Exec(ctx, `UPDATE mfa_challenges SET attempts = attempts + 1 WHERE token_hash = $1`, tokenHash)
+	return err
+}
+
+// ConsumeChallenge oznacava challenge iskoristenim; moze uspjeti samo jednom
+func (r *mfaRepository) ConsumeChallenge(ctx context.Context, tokenHash string) error {
+	const q = `
+		UPDATE mfa_challenges SET used_at = now()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > now()
+	`
+	tag, err := r.db.Exec(ctx, q, tokenHash)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrInvalidMFAChallenge
+	}
+	return nil
+}
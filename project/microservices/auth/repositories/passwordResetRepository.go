@@ -0,0 +1,106 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrInvalidResetToken = errors.New("invalid or expired reset token")
+
+type passwordResetRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewPasswordResetRepository(db *pgxpool.Pool) *passwordResetRepository {
+	return &passwordResetRepository{db: db}
+}
+
+// CreateResetToken cuva hash novog reset tokena; ranije neiskoristeni tokeni
+// korisnika se ponistavaju, pa vazi samo posljednji poslati link
+func (r *passwordResetRepository) CreateResetToken(ctx context.Context, userID uuid.UUID, tokenHash string, expiresAt time.Time) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	const invalidate = `UPDATE password_reset_tokens SET used_at = now() WHERE user_id = $1 AND used_at IS NULL`
+	if _, err := tx.Exec(ctx, invalidate, userID); err != nil {
+		return err
+	}
+
+	const ins = `
+		INSERT INTO password_reset_tokens (id, user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`
+	if _, err := tx.Exec(ctx, ins, uuid.New(), userID, tokenHash, expiresAt); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ResetTokenUser vraca korisnika vazeceg reset tokena bez da ga iskoristi
+// (za provjeru nove lozinke prema licnim podacima korisnika)
+func (r *passwordResetRepository) ResetTokenUser(ctx context.Context, tokenHash string) (uuid.UUID, error) {
+	const q = `
+		SELECT user_id
+		FROM password_reset_tokens
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > now()
+	`
+	var userID uuid.UUID
+	if err := r.db.QueryRow(ctx, q, tokenHash).Scan(&userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return uuid.Nil, ErrInvalidResetToken
+		}
+		return uuid.Nil, err
+	}
+	return userID, nil
+}
+
+// ResetPassword iskoristava reset token i postavlja novu lozinku u istoj
+// transakciji; token se moze iskoristiti samo jednom
+func (r *passwordResetRepository) ResetPassword(ctx context.Context, tokenHash, newPassword string) (uuid.UUID, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	const sel = `
+		SELECT id, user_id
+		FROM password_reset_tokens
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > now()
+		FOR UPDATE
+	`
+	var id, userID uuid.UUID
+	if err := tx.QueryRow(ctx, sel, tokenHash).Scan(&id, &userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return uuid.Nil, ErrInvalidResetToken
+		}
+		return uuid.Nil, err
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE password_reset_tokens SET used_at = now() WHERE id = $1`, id); err != nil {
+		return uuid.Nil, err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE users SET password = $1 WHERE id = $2`, string(hash), userID); err != nil {
+		return uuid.Nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return uuid.Nil, err
+	}
+	return userID, nil
+}
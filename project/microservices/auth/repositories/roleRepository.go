@@ -0,0 +1,173 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrRoleNotFound = errors.New("role not found")
+	ErrRoleExists   = errors.New("role already exists")
+)
+
+// Role je prilagodjena uloga: imenovan skup dozvola koji se dodjeljuje
+// korisnicima pored osnovne uloge
+type Role struct {
+	ID          uuid.UUID  `json:"id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Permissions []string   `json:"permissions"`
+	CreatedBy   *uuid.UUID `json:"createdBy"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+}
+
+type roleRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRoleRepository(db *pgxpool.Pool) *roleRepository {
+	return &roleRepository{db: db}
+}
+
+const roleColumns = `id, name, description, permissions, created_by, created_at, updated_at`
+
+func scanRole(row pgx.Row) (*Role, error) {
+	var role Role
+	err := row.Scan(&role.ID, &role.Name, &role.Description, &role.Permissions, &role.CreatedBy, &role.CreatedAt, &role.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrRoleNotFound
+		}
+		return nil, err
+	}
+	return &role, nil
+}
+
+func collectRoles(rows pgx.Rows) ([]Role, error) {
+	defer rows.Close()
+	roles := []Role{}
+	for rows.Next() {
+		role, err := scanRole(rows)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, *role)
+	}
+	return roles, rows.Err()
+}
+
+func (r *roleRepository) ListRoles(ctx context.Context) ([]Role, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+roleColumns+` FROM roles ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	return collectRoles(rows)
+}
+
+func (r *roleRepository) GetRole(ctx context.Context, id uuid.UUID) (*Role, error) {
+	return scanRole(r.db.QueryRow(ctx, `SELECT `+roleColumns+` FROM roles WHERE id = $1`, id))
+}
+
+func (r *roleRepository) CreateRole(ctx context.Context, role Role, createdBy *uuid.UUID) (*Role, error) {
+	q := `
+		INSERT INTO roles (id, name, description, permissions, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING ` + roleColumns
+	created, err := scanRole(r.db.QueryRow(ctx, q, uuid.New(), role.Name, role.Description, role.Permissions, createdBy))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrRoleExists
+		}
+		return nil, err
+	}
+	return created, nil
+}
+
+func (r *roleRepository) UpdateRole(ctx context.Context, id uuid.UUID, description string, permissions []string) (*Role, error) {
+	q := `
+		UPDATE roles SET description = $2, permissions = $3, updated_at = now()
+		WHERE id = $1
+		RETURNING ` + roleColumns
+	return scanRole(r.db.QueryRow(ctx, q, id, description, permissions))
+}
+
+// DeleteRole brise ulogu i sva njena dodjeljivanja
+func (r *roleRepository) DeleteRole(ctx context.Context, id uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM roles WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrRoleNotFound
+	}
+	return nil
+}
+
+// AssignRole dodjeljuje ulogu korisniku; ponovno dodjeljivanje nema efekta
+func (r *roleRepository) AssignRole(ctx context.Context, userID, roleID uuid.UUID, assignedBy *uuid.UUID) error {
+	const q = `
+		INSERT INTO user_roles (user_id, role_id, assigned_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, role_id) DO NOTHING
+	`
+	_, err := r.db.Exec(ctx, q, userID, roleID, assignedBy)
+	return err
+}
+
+func (r *roleRepository) UnassignRole(ctx context.Context, userID, roleID uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM user_roles WHERE user_id = $1 AND role_id = $2`, userID, roleID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrRoleNotFound
+	}
+	return nil
+}
+
+// UserRoles vraca prilagodjene uloge korisnika
+func (r *roleRepository) UserRoles(ctx context.Context, userID uuid.UUID) ([]Role, error) {
+	q := `
+		SELECT ` + roleColumns + ` FROM roles
+		WHERE id IN (SELECT role_id FROM user_roles WHERE user_id = $1)
+		ORDER BY name
+	`
+	rows, err := r.db.Query(ctx, q, userID)
+	if err != nil {
+		return nil, err
+	}
+	return collectRoles(rows)
+}
+
+// UserPermissions vraca uniju dozvola svih prilagodjenih uloga korisnika
+func (r *roleRepository) UserPermissions(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	const q = `
+		SELECT DISTINCT p FROM user_roles ur
+		JOIN roles ro ON ro.id = ur.role_id, unnest(ro.permissions) AS p
+		WHERE ur.user_id = $1
+		ORDER BY p
+	`
+	rows, err := r.db.Query(ctx, q, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	perms := []string{}
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, err
+		}
+		perms = append(perms, p)
+	}
+	return perms, rows.Err()
+}
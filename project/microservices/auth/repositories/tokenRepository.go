@@ -0,0 +1,304 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token already used")
+	ErrSessionNotFound     = errors.New("session not found")
+)
+
+type RefreshToken struct {
+	ID        uuid.UUID  `json:"id"`
+	UserID    uuid.UUID  `json:"userId"`
+	FamilyID  uuid.UUID  `json:"familyId"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+	CreatedAt time.Time  `json:"createdAt"`
+	RevokedAt *time.Time `json:"revokedAt"`
+}
+
+// Session je jedna prijava korisnika; ID je ujedno familija refresh tokena
+// i "sid" claim svih access tokena izdatih u toj sesiji
+type Session struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     uuid.UUID  `json:"userId"`
+	UserAgent  string     `json:"userAgent"`
+	IP         string     `json:"ip"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastSeenAt time.Time  `json:"lastSeenAt"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+}
+
+type tokenRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewTokenRepository(db *pgxpool.Pool) *tokenRepository {
+	return &tokenRepository{db: db}
+}
+
+// CreateRefreshToken cuva hash novog refresh tokena; familyID povezuje sve
+// tokene nastale rotacijom iz jednog logina
+func (r *tokenRepository) CreateRefreshToken(ctx context.Context, userID, familyID uuid.UUID, tokenHash string, expiresAt time.Time) error {
+	const q = `
+		INSERT INTO refresh_tokens (id, user_id, family_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	_, err := r.db.Exec(ctx, q, uuid.New(), userID, familyID, tokenHash, expiresAt)
+	return err
+}
+
+// RotateRefreshToken ponistava stari refresh token i upisuje novi u istoj familiji.
+// Ako je stari token vec iskoristen, cijela familija se opoziva (reuse detection).
+func (r *tokenRepository) RotateRefreshToken(ctx context.Context, oldHash, newHash string, expiresAt time.Time) (*RefreshToken, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	const sel = `
+		SELECT id, user_id, family_id, expires_at, created_at, revoked_at
+		FROM refresh_tokens
+		WHERE token_hash = $1
+		FOR UPDATE
+	`
+	var old RefreshToken
+	if err := tx.QueryRow(ctx, sel, oldHash).
+		Scan(&old.ID, &old.UserID, &old.FamilyID, &old.ExpiresAt, &old.CreatedAt, &old.RevokedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	if old.RevokedAt != nil {
+		const revokeFamily = `
+			UPDATE refresh_tokens SET revoked_at = now()
+			WHERE family_id = $1 AND revoked_at IS NULL
+		`
+		if _, err := tx.Exec(ctx, revokeFamily, old.FamilyID); err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec(ctx, `UPDATE sessions SET revoked_at = now() WHERE id = $1 AND revoked_at IS NULL`, old.FamilyID); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+	if time.Now().After(old.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	next := RefreshToken{
+		ID:        uuid.New(),
+		UserID:    old.UserID,
+		FamilyID:  old.FamilyID,
+		ExpiresAt: expiresAt,
+	}
+
+	const ins = `
+		INSERT INTO refresh_tokens (id, user_id, family_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at
+	`
+	if err := tx.QueryRow(ctx, ins, next.ID, next.UserID, next.FamilyID, newHash, next.ExpiresAt).
+		Scan(&next.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	const upd = `UPDATE refresh_tokens SET revoked_at = now(), replaced_by = $2 WHERE id = $1`
+	if _, err := tx.Exec(ctx, upd, old.ID, next.ID); err != nil {
+		return nil, err
+	}
+
+	// sesija se produzava sa svakim refresh-om; familije nastale prije uvodjenja
+	// sesija dobijaju zapis pri prvoj rotaciji
+	const touch = `
+		INSERT INTO sessions (id, user_id, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (id) DO UPDATE SET last_seen_at = now(), expires_at = EXCLUDED.expires_at
+	`
+	if _, err := tx.Exec(ctx, touch, next.FamilyID, next.UserID, next.ExpiresAt); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &next, nil
+}
+
+// RevokeRefreshToken opoziva cijelu familiju kojoj token pripada, zajedno sa sesijom (logout)
+func (r *tokenRepository) RevokeRefreshToken(ctx context.Context, tokenHash string) error {
+	var familyID uuid.UUID
+	err := r.db.QueryRow(ctx, `SELECT family_id FROM refresh_tokens WHERE token_hash = $1`, tokenHash).Scan(&familyID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+	return r.revokeSessions(ctx, `id = $1`, `family_id = $1`, familyID)
+}
+
+// RevokeAllForUser opoziva sve sesije i refresh tokene korisnika
+func (r *tokenRepository) RevokeAllForUser(ctx context.Context, userID uuid.UUID) error {
+	return r.revokeSessions(ctx, `user_id = $1`, `user_id = $1`, userID)
+}
+
+// CreateSession upisuje novu sesiju pri prijavi
+func (r *tokenRepository) CreateSession(ctx context.Context, s Session) error {
+	const q = `
+		INSERT INTO sessions (id, user_id, user_agent, ip, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	_, err := r.db.Exec(ctx, q, s.ID, s.UserID, s.UserAgent, s.IP, s.ExpiresAt)
+	return err
+}
+
+// ListSessions vraca aktivne sesije korisnika, posljednje koristene prvo
+func (r *tokenRepository) ListSessions(ctx context.Context, userID uuid.UUID) ([]Session, error) {
+	const q = `
+		SELECT id, user_id, user_agent, ip, created_at, last_seen_at, expires_at, revoked_at
+		FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > now()
+		ORDER BY last_seen_at DESC
+	`
+	rows, err := r.db.Query(ctx, q, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []Session{}
+	for rows.Next() {
+		var s Session
+		if err := rows.Scan(&s.ID, &s.UserID, &s.UserAgent, &s.IP, &s.CreatedAt, &s.LastSeenAt, &s.ExpiresAt, &s.RevokedAt); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+// RevokeSession opoziva jednu sesiju korisnika
+func (r *tokenRepository) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	const q = `SELECT EXISTS (SELECT 1 FROM sessions WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL)`
+	var exists bool
+	if err := r.db.QueryRow(ctx, q, sessionID, userID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrSessionNotFound
+	}
+	return r.revokeSessions(ctx, `id = $1`, `family_id = $1`, sessionID)
+}
+
+// RevokeOtherSessions opoziva sve sesije korisnika osim trenutne
+func (r *tokenRepository) RevokeOtherSessions(ctx context.Context, userID, currentID uuid.UUID) error {
+	return r.revokeSessions(ctx, `user_id = $1 AND id <> $2`, `user_id = $1 AND family_id <> $2`, userID, currentID)
+}
+
+func (r *tokenRepository) IsSessionRevoked(ctx context.Context, sessionID uuid.UUID) (bool, error) {
+	const q = `SELECT EXISTS (SELECT 1 FROM sessions WHERE id = $1 AND revoked_at IS NOT NULL)`
+	var revoked bool
+	if err := r.db.QueryRow(ctx, q, sessionID).Scan(&revoked); err != nil {
+		return false, err
+	}
+	return revoked, nil
+}
+
+// ListRevokedSessions vraca sesije opozvane nakon since; starije sesije ne
+// mogu imati access token koji jos vazi
+func (r *tokenRepository) ListRevokedSessions(ctx context.Context, since time.Time) ([]uuid.UUID, error) {
+	rows, err := r.db.Query(ctx, `SELECT id FROM sessions WHERE revoked_at > $1`, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]uuid.UUID, 0)
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// RevokeAccessToken dodaje jti access tokena na listu opozvanih;
+// zapis je potreban samo dok token ne istekne
+func (r *tokenRepository) RevokeAccessToken(ctx context.Context, jti uuid.UUID, expiresAt time.Time) error {
+	const q = `
+		INSERT INTO revoked_tokens (jti, expires_at)
+		VALUES ($1, $2)
+		ON CONFLICT (jti) DO NOTHING
+	`
+	if _, err := r.db.Exec(ctx, q, jti, expiresAt); err != nil {
+		return err
+	}
+
+	_, err := r.db.Exec(ctx, `DELETE FROM revoked_tokens WHERE expires_at < now()`)
+	return err
+}
+
+func (r *tokenRepository) IsAccessTokenRevoked(ctx context.Context, jti uuid.UUID) (bool, error) {
+	const q = `SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)`
+	var revoked bool
+	if err := r.db.QueryRow(ctx, q, jti).Scan(&revoked); err != nil {
+		return false, err
+	}
+	return revoked, nil
+}
+
+func (r *tokenRepository) ListRevokedAccessTokens(ctx context.Context) ([]uuid.UUID, error) {
+	rows, err := r.db.Query(ctx, `SELECT jti FROM revoked_tokens WHERE expires_at > now()`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jtis := make([]uuid.UUID, 0)
+	for rows.Next() {
+		var jti uuid.UUID
+		if err := rows.Scan(&jti); err != nil {
+			return nil, err
+		}
+		jtis = append(jtis, jti)
+	}
+	return jtis, rows.Err()
+}
+
+// revokeSessions opoziva sesije i refresh tokene njihovih familija u istoj
+// transakciji; uslov za refresh_tokens pokriva i familije nastale prije
+// uvodjenja sesija
+func (r *tokenRepository) revokeSessions(ctx context.Context, sessionWhere, familyWhere string, args ...any) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `UPDATE sessions SET revoked_at = now() WHERE revoked_at IS NULL AND `+sessionWhere, args...); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE refresh_tokens SET revoked_at = now() WHERE revoked_at IS NULL AND `+familyWhere, args...); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
@@ -3,8 +3,8 @@ package repositories
 import (
 	"context"
 	"errors"
-	"log"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgconn"
@@ -24,19 +24,64 @@ const (
 	RoleSSZAdmin UserRole = "sszadmin"
 )
 
+// Valid provjerava da li je uloga jedna od poznatih UserRole konstanti
+func (r UserRole) Valid() bool {
+	switch r {
+	case RoleStudent, RoleProfessor, RoleEmployee, RoleCandidate, RoleFacultyAdmin, RoleSSZAdmin:
+		return true
+	}
+	return false
+}
+
+// UserStatus je stanje naloga (kolona account_status, ne users.status koji je
+// status studenta): deaktiviran nalog se ne moze prijaviti, a njegovi tokeni se
+// odbijaju; samoregistrovan nalog je pending_verification dok se ne potvrdi email adresa
+type UserStatus string
+
+const (
+	StatusActive              UserStatus = "active"
+	StatusDisabled            UserStatus = "disabled"
+	StatusPendingVerification UserStatus = "pending_verification"
+)
+
+// Valid provjerava statuse koje administrator moze postaviti
+func (s UserStatus) Valid() bool {
+	return s == StatusActive || s == StatusDisabled
+}
+
 type User struct {
-	ID       uuid.UUID `json:"id"`
-	FullName string    `json:"fullname"`
-	Email    string    `json:"email"`
-	Password string    `json:"password"`
-	Role     UserRole  `json:"role"`
+	ID       uuid.UUID  `json:"id"`
+	FullName string     `json:"fullname"`
+	Email    string     `json:"email"`
+	Password string     `json:"password"`
+	Role     UserRole   `json:"role"`
+	Status   UserStatus `json:"status"`
+}
+
+func (u *User) Active() bool {
+	return u.Status == StatusActive
 }
 
 var (
 	ErrEmailExists        = errors.New("email already exists")
 	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrUserNotFound       = errors.New("user not found")
+	ErrInvalidRole        = errors.New("invalid role")
+	ErrInvalidStatus      = errors.New("invalid status")
+	ErrInvalidEmailToken  = errors.New("invalid or expired email change token")
 )
 
+// RoleChange je zapis u audit tabeli promjena uloga; OldRole je nil kad je
+// nalog kreiran, a ChangedBy nil kad promjenu nije napravio korisnik (bootstrap)
+type RoleChange struct {
+	ID        uuid.UUID  `json:"id"`
+	UserID    uuid.UUID  `json:"userId"`
+	OldRole   *UserRole  `json:"oldRole"`
+	NewRole   UserRole   `json:"newRole"`
+	ChangedBy *uuid.UUID `json:"changedBy"`
+	ChangedAt time.Time  `json:"changedAt"`
+}
+
 type userRepository struct {
 	db *pgxpool.Pool
 }
@@ -49,10 +94,10 @@ func NewUserRepository(db *pgxpool.Pool) *userRepository {
 func (r *userRepository) Login(ctx context.Context, email, password string) (*User, error) {
 	email = strings.ToLower(strings.TrimSpace(email))
 
-	const q = `SELECT id, fullname, email, password, role FROM users WHERE email = $1`
+	const q = `SELECT id, fullname, email, password, role, account_status FROM users WHERE email = $1`
 	var u User
 	if err := r.db.QueryRow(ctx, q, email).
-		Scan(&u.ID, &u.FullName, &u.Email, &u.Password, &u.Role); err != nil {
+		Scan(&u.ID, &u.FullName, &u.Email, &u.Password, &u.Role, &u.Status); err != nil {
 
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrInvalidCredentials
@@ -60,8 +105,6 @@ func (r *userRepository) Login(ctx context.Context, email, password string) (*Us
 		return nil, err
 	}
 
-	log.Println(bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password)), " poredjenje sifre")
-
 	if bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password)) != nil {
 		return nil, ErrInvalidCredentials
 	}
@@ -79,15 +122,18 @@ func (r *userRepository) Register(ctx context.Context, u User) (*User, error) {
 	}
 
 	u.ID = uuid.New()
+	if u.Status == "" {
+		u.Status = StatusActive
+	}
 
 	const insUser = `
-		INSERT INTO users (id, fullname, email, password, role)
-		VALUES ($1,$2,$3,$4,$5)
-		RETURNING id, fullname, email, role
+		INSERT INTO users (id, fullname, email, password, role, account_status)
+		VALUES ($1,$2,$3,$4,$5,$6)
+		RETURNING id, fullname, email, role, account_status
 	`
 	if err = r.db.QueryRow(ctx, insUser,
-		u.ID, u.FullName, u.Email, string(hash), u.Role,
-	).Scan(&u.ID, &u.FullName, &u.Email, &u.Role); err != nil {
+		u.ID, u.FullName, u.Email, string(hash), u.Role, u.Status,
+	).Scan(&u.ID, &u.FullName, &u.Email, &u.Role, &u.Status); err != nil {
 
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
@@ -99,3 +145,327 @@ func (r *userRepository) Register(ctx context.Context, u User) (*User, error) {
 	u.Password = ""
 	return &u, nil
 }
+
+// GetByID vraca korisnika bez lozinke (npr. pri osvjezavanju tokena)
+func (r *userRepository) GetByID(ctx context.Context, id uuid.UUID) (*User, error) {
+	const q = `SELECT id, fullname, email, role, account_status FROM users WHERE id = $1`
+	var u User
+	if err := r.db.QueryRow(ctx, q, id).Scan(&u.ID, &u.FullName, &u.Email, &u.Role, &u.Status); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &u, nil
+}
+
+// GetByEmail vraca korisnika bez lozinke
+func (r *userRepository) GetByEmail(ctx context.Context, email string) (*User, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+
+	const q = `SELECT id, fullname, email, role, account_status FROM users WHERE email = $1`
+	var u User
+	if err := r.db.QueryRow(ctx, q, email).Scan(&u.ID, &u.FullName, &u.Email, &u.Role, &u.Status); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &u, nil
+}
+
+// VerifyPassword provjerava lozinku korisnika (za osjetljive izmjene naloga)
+func (r *userRepository) VerifyPassword(ctx context.Context, id uuid.UUID, password string) error {
+	var current string
+	if err := r.db.QueryRow(ctx, `SELECT password FROM users WHERE id = $1`, id).Scan(&current); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(current), []byte(password)) != nil {
+		return ErrInvalidCredentials
+	}
+	return nil
+}
+
+// ChangePassword postavlja novu lozinku ako je stara ispravna
+func (r *userRepository) ChangePassword(ctx context.Context, id uuid.UUID, oldPassword, newPassword string) error {
+	if err := r.VerifyPassword(ctx, id, oldPassword); err != nil {
+		return err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(ctx, `UPDATE users SET password = $1 WHERE id = $2`, string(hash), id)
+	return err
+}
+
+// Provision kreira nalog koji je otvorio administrator i u istoj transakciji
+// upisuje dodijeljenu ulogu u audit tabelu
+func (r *userRepository) Provision(ctx context.Context, u User, createdBy *uuid.UUID) (*User, error) {
+	if !u.Role.Valid() {
+		return nil, ErrInvalidRole
+	}
+	u.Email = strings.ToLower(strings.TrimSpace(u.Email))
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	const insUser = `
+		INSERT INTO users (id, fullname, email, password, role)
+		VALUES ($1,$2,$3,$4,$5)
+		RETURNING id, fullname, email, role, account_status
+	`
+	if err := tx.QueryRow(ctx, insUser, uuid.New(), u.FullName, u.Email, string(hash), u.Role).
+		Scan(&u.ID, &u.FullName, &u.Email, &u.Role, &u.Status); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrEmailExists
+		}
+		return nil, err
+	}
+
+	if err := insertRoleChange(ctx, tx, u.ID, nil, u.Role, createdBy); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	u.Password = ""
+	return &u, nil
+}
+
+// ChangeRole mijenja ulogu korisnika i biljezi promjenu u audit tabeli
+func (r *userRepository) ChangeRole(ctx context.Context, userID uuid.UUID, role UserRole, changedBy uuid.UUID) (*User, error) {
+	if !role.Valid() {
+		return nil, ErrInvalidRole
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var u User
+	const sel = `SELECT id, fullname, email, role, account_status FROM users WHERE id = $1 FOR UPDATE`
+	if err := tx.QueryRow(ctx, sel, userID).Scan(&u.ID, &u.FullName, &u.Email, &u.Role, &u.Status); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	if u.Role == role {
+		return &u, nil
+	}
+
+	old := u.Role
+	if _, err := tx.Exec(ctx, `UPDATE users SET role = $1 WHERE id = $2`, role, userID); err != nil {
+		return nil, err
+	}
+	if err := insertRoleChange(ctx, tx, userID, &old, role, &changedBy); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	u.Role = role
+	return &u, nil
+}
+
+// ListRoleChanges vraca istoriju uloga korisnika, najnovije prvo
+func (r *userRepository) ListRoleChanges(ctx context.Context, userID uuid.UUID) ([]RoleChange, error) {
+	const q = `
+		SELECT id, user_id, old_role, new_role, changed_by, changed_at
+		FROM role_changes
+		WHERE user_id = $1
+		ORDER BY changed_at DESC
+	`
+	rows, err := r.db.Query(ctx, q, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []RoleChange{}
+	for rows.Next() {
+		var c RoleChange
+		if err := rows.Scan(&c.ID, &c.UserID, &c.OldRole, &c.NewRole, &c.ChangedBy, &c.ChangedAt); err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
+// UpdateProfile mijenja ime korisnika
+func (r *userRepository) UpdateProfile(ctx context.Context, id uuid.UUID, fullName string) (*User, error) {
+	const q = `
+		UPDATE users SET fullname = $1 WHERE id = $2
+		RETURNING id, fullname, email, role, account_status
+	`
+	var u User
+	if err := r.db.QueryRow(ctx, q, fullName, id).Scan(&u.ID, &u.FullName, &u.Email, &u.Role, &u.Status); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &u, nil
+}
+
+// SetStatus aktivira ili deaktivira nalog
+func (r *userRepository) SetStatus(ctx context.Context, id uuid.UUID, status UserStatus) (*User, error) {
+	if !status.Valid() {
+		return nil, ErrInvalidStatus
+	}
+
+	const q = `
+		UPDATE users SET account_status = $1, account_status_changed_at = now() WHERE id = $2
+		RETURNING id, fullname, email, role, account_status
+	`
+	var u User
+	if err := r.db.QueryRow(ctx, q, status, id).Scan(&u.ID, &u.FullName, &u.Email, &u.Role, &u.Status); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &u, nil
+}
+
+// VerifyEmail aktivira nalog koji ceka potvrdu email adrese; email mora biti
+// isti kao u trenutku slanja linka
+func (r *userRepository) VerifyEmail(ctx context.Context, id uuid.UUID, email string) (*User, error) {
+	const q = `
+		UPDATE users SET account_status = $1, account_status_changed_at = now()
+		WHERE id = $2 AND email = $3 AND account_status = $4
+		RETURNING id, fullname, email, role, account_status
+	`
+	var u User
+	err := r.db.QueryRow(ctx, q, StatusActive, id, strings.ToLower(email), StatusPendingVerification).
+		Scan(&u.ID, &u.FullName, &u.Email, &u.Role, &u.Status)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return &u, nil
+}
+
+// ListDisabledUsers vraca naloge deaktivirane nakon since; stariji nalozi
+// ne mogu imati access token koji jos vazi
+func (r *userRepository) ListDisabledUsers(ctx context.Context, since time.Time) ([]uuid.UUID, error) {
+	const q = `SELECT id FROM users WHERE account_status = $1 AND account_status_changed_at > $2`
+	rows, err := r.db.Query(ctx, q, StatusDisabled, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]uuid.UUID, 0)
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// CreateEmailChange cuva zahtjev za promjenu email adrese; adresa se mijenja
+// tek kada korisnik potvrdi link poslat na novu adresu
+func (r *userRepository) CreateEmailChange(ctx context.Context, userID uuid.UUID, newEmail, tokenHash string, expiresAt time.Time) error {
+	newEmail = strings.ToLower(strings.TrimSpace(newEmail))
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	const invalidate = `UPDATE email_change_tokens SET used_at = now() WHERE user_id = $1 AND used_at IS NULL`
+	if _, err := tx.Exec(ctx, invalidate, userID); err != nil {
+		return err
+	}
+
+	const ins = `
+		INSERT INTO email_change_tokens (id, user_id, new_email, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	if _, err := tx.Exec(ctx, ins, uuid.New(), userID, newEmail, tokenHash, expiresAt); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ConfirmEmailChange iskoristava token i postavlja novu email adresu
+func (r *userRepository) ConfirmEmailChange(ctx context.Context, tokenHash string) (*User, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	const sel = `
+		SELECT id, user_id, new_email
+		FROM email_change_tokens
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > now()
+		FOR UPDATE
+	`
+	var id, userID uuid.UUID
+	var newEmail string
+	if err := tx.QueryRow(ctx, sel, tokenHash).Scan(&id, &userID, &newEmail); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInvalidEmailToken
+		}
+		return nil, err
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE email_change_tokens SET used_at = now() WHERE id = $1`, id); err != nil {
+		return nil, err
+	}
+
+	const upd = `
+		UPDATE users SET email = $1 WHERE id = $2
+		RETURNING id, fullname, email, role, account_status
+	`
+	var u User
+	if err := tx.QueryRow(ctx, upd, newEmail, userID).Scan(&u.ID, &u.FullName, &u.Email, &u.Role, &u.Status); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrEmailExists
+		}
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func insertRoleChange(ctx context.Context, tx pgx.Tx, userID uuid.UUID, oldRole *UserRole, newRole UserRole, changedBy *uuid.UUID) error {
+	const q = `
+		INSERT INTO role_changes (id, user_id, old_role, new_role, changed_by)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	_, err := tx.Exec(ctx, q, uuid.New(), userID, oldRole, newRole, changedBy)
+	return err
+}
@@ -0,0 +1,277 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"html/template"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// OIDCDevProvider je lokalna zamjena za institucionalni OpenID Connect
+// provider, za razvoj i testiranje SSO prijave. Prijavljuje bilo koju email
+// adresu bez lozinke, pa se NIKADA ne smije ukljuciti u produkciji.
+type OIDCDevProvider struct {
+	issuer string
+	key    *rsa.PrivateKey
+	keyID  string
+
+	mu    sync.Mutex
+	codes map[string]devAuthCode
+}
+
+type devAuthCode struct {
+	clientID    string
+	redirectURI string
+	nonce       string
+	challenge   string
+	email       string
+	name        string
+	expiresAt   time.Time
+}
+
+const devCodeTTL = time.Minute
+
+func NewOIDCDevProvider(issuer string) (*OIDCDevProvider, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(key.PublicKey.N.Bytes())
+	return &OIDCDevProvider{
+		issuer: strings.TrimRight(issuer, "/"),
+		key:    key,
+		keyID:  base64.RawURLEncoding.EncodeToString(sum[:12]),
+		codes:  make(map[string]devAuthCode),
+	}, nil
+}
+
+// ServeHTTP obradjuje putanje relativne u odnosu na issuer
+// (main.go ga montira uz http.StripPrefix)
+func (p *OIDCDevProvider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/.well-known/openid-configuration" && r.Method == http.MethodGet:
+		p.discovery(w)
+	case r.URL.Path == "/jwks" && r.Method == http.MethodGet:
+		p.jwks(w)
+	case r.URL.Path == "/authorize" && r.Method == http.MethodGet:
+		p.loginForm(w, r)
+	case r.URL.Path == "/authorize" && r.Method == http.MethodPost:
+		p.authorize(w, r)
+	case r.URL.Path == "/token" && r.Method == http.MethodPost:
+		p.token(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (p *OIDCDevProvider) discovery(w http.ResponseWriter) {
+	devJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                p.issuer,
+		"authorization_endpoint":                p.issuer + "/authorize",
+		"token_endpoint":                        p.issuer + "/token",
+		"jwks_uri":                              p.issuer + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"scopes_supported":                      []string{"openid", "email", "profile"},
+	})
+}
+
+func (p *OIDCDevProvider) jwks(w http.ResponseWriter) {
+	pub := p.key.PublicKey
+	devJSON(w, http.StatusOK, map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"alg": "RS256",
+			"use": "sig",
+			"kid": p.keyID,
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+var devLoginPage = template.Must(template.New("login").Parse(`<!doctype html>
+<html><head><meta charset="utf-8"><title>Lokalni SSO provider</title></head>
+<body style="font-family:sans-serif;max-width:28rem;margin:4rem auto">
+<h2>Lokalni SSO provider</h2>
+<p>Samo za razvoj: prijava bilo kojom email adresom, bez lozinke.</p>
+<form method="post">
+{{range $k, $v := .Params}}<input type="hidden" name="{{$k}}" value="{{$v}}">
+{{end}}<p><label>Email<br><input name="email" type="email" required autofocus></label></p>
+<p><label>Ime i prezime<br><input name="name"></label></p>
+<button type="submit">Prijavi se</button>
+</form></body></html>`))
+
+// authorizeParams su parametri autorizacionog zahtjeva koji se prenose kroz formu
+var authorizeParams = []string{"response_type", "client_id", "redirect_uri", "scope", "state", "nonce", "code_challenge", "code_challenge_method"}
+
+func (p *OIDCDevProvider) loginForm(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if msg := validateAuthorizeRequest(q); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	params := make(map[string]string, len(authorizeParams))
+	for _, k := range authorizeParams {
+		params[k] = q.Get(k)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_ = devLoginPage.Execute(w, map[string]any{"Params": params})
+}
+
+func validateAuthorizeRequest(q url.Values) string {
+	switch {
+	case q.Get("response_type") != "code":
+		return "unsupported response_type"
+	case q.Get("client_id") == "" || q.Get("redirect_uri") == "":
+		return "client_id and redirect_uri are required"
+	case q.Get("code_challenge") == "" || q.Get("code_challenge_method") != "S256":
+		return "PKCE (S256) is required"
+	case !strings.Contains(" "+q.Get("scope")+" ", " openid "):
+		return "scope must include openid"
+	}
+	return ""
+}
+
+func (p *OIDCDevProvider) authorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+	f := r.PostForm
+	if msg := validateAuthorizeRequest(f); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	email := strings.ToLower(strings.TrimSpace(f.Get("email")))
+	if email == "" {
+		http.Error(w, "email is required", http.StatusBadRequest)
+		return
+	}
+	redirect, err := url.Parse(f.Get("redirect_uri"))
+	if err != nil || (redirect.Scheme != "http" && redirect.Scheme != "https") {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	code, err := randomString()
+	if err != nil {
+		http.Error(w, "failed to issue code", http.StatusInternalServerError)
+		return
+	}
+	p.mu.Lock()
+	for k, c := range p.codes {
+		if time.Now().After(c.expiresAt) {
+			delete(p.codes, k)
+		}
+	}
+	p.codes[code] = devAuthCode{
+		clientID:    f.Get("client_id"),
+		redirectURI: f.Get("redirect_uri"),
+		nonce:       f.Get("nonce"),
+		challenge:   f.Get("code_challenge"),
+		email:       email,
+		name:        strings.TrimSpace(f.Get("name")),
+		expiresAt:   time.Now().Add(devCodeTTL),
+	}
+	p.mu.Unlock()
+
+	q := redirect.Query()
+	q.Set("code", code)
+	if state := f.Get("state"); state != "" {
+		q.Set("state", state)
+	}
+	redirect.RawQuery = q.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (p *OIDCDevProvider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		devTokenError(w, "invalid_request")
+		return
+	}
+	f := r.PostForm
+	if f.Get("grant_type") != "authorization_code" {
+		devTokenError(w, "unsupported_grant_type")
+		return
+	}
+	clientID := f.Get("client_id")
+	if user, _, ok := r.BasicAuth(); ok {
+		clientID, _ = url.QueryUnescape(user)
+	}
+
+	// kod je jednokratan
+	p.mu.Lock()
+	c, ok := p.codes[f.Get("code")]
+	delete(p.codes, f.Get("code"))
+	p.mu.Unlock()
+
+	if !ok || time.Now().After(c.expiresAt) ||
+		c.clientID != clientID || c.redirectURI != f.Get("redirect_uri") ||
+		subtle.ConstantTimeCompare([]byte(PKCEChallenge(f.Get("code_verifier"))), []byte(c.challenge)) != 1 {
+		devTokenError(w, "invalid_grant")
+		return
+	}
+
+	now := time.Now()
+	sub := sha256.Sum256([]byte(c.email))
+	claims := jwt.MapClaims{
+		"iss":            p.issuer,
+		"sub":            "dev-" + hex.EncodeToString(sub[:8]),
+		"aud":            c.clientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"email":          c.email,
+		"email_verified": true,
+	}
+	if c.nonce != "" {
+		claims["nonce"] = c.nonce
+	}
+	if c.name != "" {
+		claims["name"] = c.name
+	}
+	t := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	t.Header["kid"] = p.keyID
+	idToken, err := t.SignedString(p.key)
+	if err != nil {
+		http.Error(w, "failed to sign token", http.StatusInternalServerError)
+		return
+	}
+	accessToken, err := randomString()
+	if err != nil {
+		http.Error(w, "failed to issue token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	devJSON(w, http.StatusOK, map[string]any{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func devTokenError(w http.ResponseWriter, code string) {
+	devJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+}
+
+func devJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
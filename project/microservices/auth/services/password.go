@@ -0,0 +1,212 @@
+package services
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// bcrypt uzima u obzir samo prva 72 bajta lozinke
+const bcryptMaxBytes = 72
+
+// klase karaktera koje politika moze zahtijevati
+const (
+	ClassLower  = "lower"
+	ClassUpper  = "upper"
+	ClassDigit  = "digit"
+	ClassSymbol = "symbol"
+)
+
+//go:embed common_passwords.txt
+var commonPasswordsFile string
+
+// PasswordPolicyError nabraja sva pravila koja lozinka ne zadovoljava
+type PasswordPolicyError struct {
+	Violations []string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return "password does not meet policy: " + strings.Join(e.Violations, "; ")
+}
+
+// PasswordPolicy provjerava nove lozinke: duzinu, klase karaktera, licne
+// podatke korisnika i listu cesto koriscenih (procurjelih) lozinki
+type PasswordPolicy struct {
+	MinLength        int
+	MaxLength        int
+	RequiredClasses  []string
+	DisallowPersonal bool
+
+	common map[string]struct{}
+}
+
+// NewPasswordPolicy pravi politiku sa ugradjenom listom cestih lozinki;
+// blocklistFile (opciono) je lokalni fajl sa dodatnim lozinkama, jedna po liniji
+func NewPasswordPolicy(minLength, maxLength int, classes []string, disallowPersonal bool, blocklistFile string) (*PasswordPolicy, error) {
+	if maxLength <= 0 || maxLength > bcryptMaxBytes {
+		maxLength = bcryptMaxBytes
+	}
+	if minLength <= 0 || minLength > maxLength {
+		return nil, fmt.Errorf("invalid password length limits %d-%d", minLength, maxLength)
+	}
+	for _, c := range classes {
+		switch c {
+		case ClassLower, ClassUpper, ClassDigit, ClassSymbol:
+		default:
+			return nil, fmt.Errorf("unknown password character class %q", c)
+		}
+	}
+
+	p := &PasswordPolicy{
+		MinLength:        minLength,
+		MaxLength:        maxLength,
+		RequiredClasses:  classes,
+		DisallowPersonal: disallowPersonal,
+		common:           map[string]struct{}{},
+	}
+	p.addCommon(bufio.NewScanner(strings.NewReader(commonPasswordsFile)))
+
+	if blocklistFile != "" {
+		f, err := os.Open(blocklistFile)
+		if err != nil {
+			return nil, fmt.Errorf("open password blocklist: %w", err)
+		}
+		defer f.Close()
+		sc := bufio.NewScanner(f)
+		p.addCommon(sc)
+		if err := sc.Err(); err != nil {
+			return nil, fmt.Errorf("read password blocklist: %w", err)
+		}
+	}
+	return p, nil
+}
+
+func (p *PasswordPolicy) addCommon(sc *bufio.Scanner) {
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p.common[strings.ToLower(line)] = struct{}{}
+	}
+}
+
+// Validate vraca *PasswordPolicyError ako lozinka krsi politiku; email i
+// ime korisnika se koriste za provjeru licnih podataka
+func (p *PasswordPolicy) Validate(password, email, fullName string) error {
+	var violations []string
+
+	if utf8.RuneCountInString(password) < p.MinLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters", p.MinLength))
+	}
+	if len(password) > p.MaxLength {
+		violations = append(violations, fmt.Sprintf("must be at most %d bytes", p.MaxLength))
+	}
+
+	for _, c := range p.RequiredClasses {
+		if !hasClass(password, c) {
+			violations = append(violations, "must contain at least one "+classDescription(c))
+		}
+	}
+
+	lower := strings.ToLower(password)
+	if p.DisallowPersonal {
+		for _, part := range personalParts(email, fullName) {
+			if strings.Contains(lower, part) {
+				violations = append(violations, "must not contain your name or email")
+				break
+			}
+		}
+	}
+	if _, ok := p.common[lower]; ok {
+		violations = append(violations, "is too common or has appeared in a data breach")
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// Describe opisuje politiku za frontend (prikaz pravila pri unosu lozinke)
+func (p *PasswordPolicy) Describe() map[string]any {
+	rules := []string{fmt.Sprintf("At least %d characters", p.MinLength)}
+	for _, c := range p.RequiredClasses {
+		rules = append(rules, "At least one "+classDescription(c))
+	}
+	if p.DisallowPersonal {
+		rules = append(rules, "Must not contain your name or email")
+	}
+	rules = append(rules, "Must not be a commonly used password")
+
+	classes := p.RequiredClasses
+	if classes == nil {
+		classes = []string{}
+	}
+	return map[string]any{
+		"minLength":            p.MinLength,
+		"maxLength":            p.MaxLength,
+		"requiredClasses":      classes,
+		"disallowPersonalInfo": p.DisallowPersonal,
+		"blockCommon":          true,
+		"rules":                rules,
+	}
+}
+
+func hasClass(password, class string) bool {
+	for _, r := range password {
+		switch class {
+		case ClassLower:
+			if unicode.IsLower(r) {
+				return true
+			}
+		case ClassUpper:
+			if unicode.IsUpper(r) {
+				return true
+			}
+		case ClassDigit:
+			if unicode.IsDigit(r) {
+				return true
+			}
+		case ClassSymbol:
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func classDescription(class string) string {
+	switch class {
+	case ClassLower:
+		return "lowercase letter"
+	case ClassUpper:
+		return "uppercase letter"
+	case ClassDigit:
+		return "digit"
+	default:
+		return "symbol"
+	}
+}
+
+// personalParts izdvaja dijelove email adrese i imena duze od 2 karaktera
+func personalParts(email, fullName string) []string {
+	local, _, _ := strings.Cut(strings.ToLower(email), "@")
+	split := func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }
+
+	var parts []string
+	if utf8.RuneCountInString(local) >= 3 {
+		parts = append(parts, local)
+	}
+	for _, f := range append(strings.FieldsFunc(local, split), strings.FieldsFunc(strings.ToLower(fullName), split)...) {
+		if utf8.RuneCountInString(f) >= 3 {
+			parts = append(parts, f)
+		}
+	}
+	return parts
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// secret iz RFC 6238 (ASCII "12345678901234567890") u base32 obliku
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestValidateTOTP(t *testing.T) {
+	at := func(unix int64) time.Time { return time.Unix(unix, 0) }
+	now := at(1111111109) // korak 37037036, kod 081804 (RFC 6238, zadnjih 6 cifara)
+	step := now.Unix() / totpPeriod
+	key, _ := b32.DecodeString(rfcSecret)
+	codeAt := func(offset int64) string { return totpCode(key, step+offset) }
+
+	tests := []struct {
+		name     string
+		secret   string
+		code     string
+		now      time.Time
+		wantStep int64
+		ok       bool
+	}{
+		// vektori iz RFC 6238 (SHA1)
+		{"rfc vector 59", rfcSecret, "287082", at(59), 1, true},
+		{"rfc vector 1111111109", rfcSecret, "081804", now, step, true},
+		{"rfc vector 1234567890", rfcSecret, "005924", at(1234567890), 1234567890 / totpPeriod, true},
+		{"rfc vector 2000000000", rfcSecret, "279037", at(2000000000), 2000000000 / totpPeriod, true},
+
+		// prozor od jednog koraka u oba smjera
+		{"previous step", rfcSecret, codeAt(-1), now, step - 1, true},
+		{"next step", rfcSecret, codeAt(1), now, step + 1, true},
+		{"two steps back", rfcSecret, codeAt(-2), now, 0, false},
+		{"two steps ahead", rfcSecret, codeAt(2), now, 0, false},
+
+		// format koda i secret-a
+		{"spaces in code", rfcSecret, "081 804", now, step, true},
+		{"lower-case secret", strings.ToLower(rfcSecret), "081804", now, step, true},
+		{"wrong code", rfcSecret, "000000", now, 0, false},
+		{"too short", rfcSecret, "08180", now, 0, false},
+		{"too long", rfcSecret, "0818040", now, 0, false},
+		{"empty", rfcSecret, "", now, 0, false},
+		{"invalid secret", "not base32!", "081804", now, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ValidateTOTP(tt.secret, tt.code, tt.now)
+			if ok != tt.ok || got != tt.wantStep {
+				t.Errorf("ValidateTOTP = (%d, %v), want (%d, %v)", got, ok, tt.wantStep, tt.ok)
+			}
+		})
+	}
+}
+
+// TestTOTPReplay provjerava da vraceni korak omogucava odbijanje ponovljenog
+// koda: baza prihvata kod samo ako je njegov korak veci od posljednjeg
+// iskoristenog (last_used_step < step)
+func TestTOTPReplay(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	key, _ := b32.DecodeString(rfcSecret)
+	step := now.Unix() / totpPeriod
+
+	tests := []struct {
+		name     string
+		lastUsed int64
+		code     string
+		accepted bool
+	}{
+		{"first use", 0, "081804", true},
+		{"same code again", step, "081804", false},
+		{"older code in the window", step, totpCode(key, step-1), false},
+		{"next code", step, totpCode(key, step+1), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ValidateTOTP(rfcSecret, tt.code, now)
+			if !ok {
+				t.Fatalf("code %s rejected", tt.code)
+			}
+			if accepted := tt.lastUsed < got; accepted != tt.accepted {
+				t.Errorf("step %d after last used %d: accepted = %v, want %v", got, tt.lastUsed, accepted, tt.accepted)
+			}
+		})
+	}
+}
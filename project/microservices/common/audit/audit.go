@@ -0,0 +1,269 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Audit log je zajednicki za sve servise: svaki servis upisuje u istu
+// append-only tabelu audit_log, a auth servis je izlaze administratorima.
+
+// RequestIDHeader prenosi ID zahtjeva izmedju klijenta i servisa
+const RequestIDHeader = "X-Request-ID"
+
+// Actor je korisnik koji je izvrsio akciju
+type Actor struct {
+	ID    string `json:"id"`
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+// ActorFunc cita aktera iz konteksta zahtjeva (npr. iz claims tokena)
+type ActorFunc func(ctx context.Context) Actor
+
+// Entry je jedan zapis audit loga
+type Entry struct {
+	ID         uuid.UUID       `json:"id"`
+	Service    string          `json:"service"`
+	Actor      Actor           `json:"actor"`
+	Action     string          `json:"action"`
+	EntityType string          `json:"entityType"`
+	EntityID   string          `json:"entityId"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	RequestID  string          `json:"requestId"`
+	CreatedAt  time.Time       `json:"createdAt"`
+}
+
+type Logger struct {
+	db      *pgxpool.Pool
+	service string
+	actor   ActorFunc
+}
+
+func NewLogger(db *pgxpool.Pool, service string, actor ActorFunc) *Logger {
+	return &Logger{db: db, service: service, actor: actor}
+}
+
+type ctxKey int
+
+const (
+	loggerKey ctxKey = iota
+	requestIDKey
+	actorKey
+)
+
+// Middleware dodjeljuje zahtjevu ID (ili preuzima X-Request-ID klijenta),
+// vraca ga u odgovoru i upisuje logger u kontekst za Record
+func (l *Logger) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		ctx := context.WithValue(r.Context(), loggerKey, l)
+		ctx = context.WithValue(ctx, requestIDKey, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		if c < 0x21 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// RequestID vraca ID tekuceg zahtjeva
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// WithActor eksplicitno postavlja aktera, za akcije prije nego sto je
+// korisnik autentifikovan (npr. prijava)
+func WithActor(ctx context.Context, a Actor) context.Context {
+	return context.WithValue(ctx, actorKey, a)
+}
+
+func (l *Logger) actorFrom(ctx context.Context) Actor {
+	if a, ok := ctx.Value(actorKey).(Actor); ok {
+		return a
+	}
+	if l.actor != nil {
+		return l.actor(ctx)
+	}
+	return Actor{}
+}
+
+// Record upisuje akciju loggerom iz konteksta. Greska pri upisu se samo
+// loguje, jer je akcija nad zapisom vec izvrsena.
+func Record(ctx context.Context, action, entityType, entityID string, before, after any) {
+	l, ok := ctx.Value(loggerKey).(*Logger)
+	if !ok {
+		log.Printf("audit: no logger in context for %s %s/%s", action, entityType, entityID)
+		return
+	}
+	if err := l.Record(ctx, action, entityType, entityID, before, after); err != nil {
+		log.Printf("audit: failed to record %s %s/%s: %v", action, entityType, entityID, err)
+	}
+}
+
+// Record upisuje jedan zapis; before/after se cuvaju kao JSON (nil = NULL)
+func (l *Logger) Record(ctx context.Context, action, entityType, entityID string, before, after any) error {
+	beforeJSON, err := marshal(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := marshal(after)
+	if err != nil {
+		return err
+	}
+	a := l.actorFrom(ctx)
+
+	const q = `
+		INSERT INTO audit_log (id, service, actor_id, actor_email, actor_role, action,
+			entity_type, entity_id, before, after, request_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+	// upis ne zavisi od otkazivanja zahtjeva (npr. klijent je prekinuo vezu)
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	_, err = l.db.Exec(ctx, q, uuid.New(), l.service, a.ID, a.Email, a.Role, action,
+		entityType, entityID, beforeJSON, afterJSON, RequestID(ctx))
+	return err
+}
+
+func marshal(v any) ([]byte, error) {
+	if v == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if string(b) == "null" {
+		return nil, nil
+	}
+
+	// lozinke (i njihovi hashevi) se nikad ne upisuju u audit log
+	var generic any
+	if err := json.Unmarshal(b, &generic); err != nil {
+		return nil, err
+	}
+	return json.Marshal(redact(generic))
+}
+
+func redact(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, val := range t {
+			if sensitiveKey(k) {
+				delete(t, k)
+				continue
+			}
+			t[k] = redact(val)
+		}
+	case []any:
+		for i := range t {
+			t[i] = redact(t[i])
+		}
+	}
+	return v
+}
+
+func sensitiveKey(k string) bool {
+	k = strings.ToLower(k)
+	return strings.Contains(k, "password") || strings.Contains(k, "secret") || strings.Contains(k, "token")
+}
+
+// Filter ogranicava pretragu audit loga; prazna polja se ne primjenjuju
+type Filter struct {
+	Services   []string
+	Actor      string // email ili ID aktera
+	Action     string
+	EntityType string
+	EntityID   string
+	From       time.Time
+	To         time.Time
+}
+
+// Query vraca zapise (najnoviji prvi) i ukupan broj zapisa za filter
+func (l *Logger) Query(ctx context.Context, f Filter, page, limit int) ([]Entry, int, error) {
+	var where []string
+	var args []any
+	add := func(cond string, v any) {
+		args = append(args, v)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if len(f.Services) > 0 {
+		add("service = ANY($%d)", f.Services)
+	}
+	if f.Actor != "" {
+		add("(lower(actor_email) = lower($%[1]d) OR actor_id = $%[1]d)", f.Actor)
+	}
+	if f.Action != "" {
+		add("action = $%d", f.Action)
+	}
+	if f.EntityType != "" {
+		add("entity_type = $%d", f.EntityType)
+	}
+	if f.EntityID != "" {
+		add("entity_id = $%d", f.EntityID)
+	}
+	if !f.From.IsZero() {
+		add("created_at >= $%d", f.From)
+	}
+	if !f.To.IsZero() {
+		add("created_at < $%d", f.To)
+	}
+	cond := ""
+	if len(where) > 0 {
+		cond = "WHERE " + strings.Join(where, " AND ")
+	}
+
+	var total int
+	if err := l.db.QueryRow(ctx, "SELECT count(*) FROM audit_log "+cond, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * limit
+	q := fmt.Sprintf(`
+		SELECT id, service, actor_id, actor_email, actor_role, action, entity_type,
+			entity_id, before, after, request_id, created_at
+		FROM audit_log %s
+		ORDER BY created_at DESC, id
+		LIMIT $%d OFFSET $%d
+	`, cond, len(args)+1, len(args)+2)
+	rows, err := l.db.Query(ctx, q, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	entries := []Entry{}
+	for rows.Next() {
+		var e Entry
+		if err := rows.Scan(&e.ID, &e.Service, &e.Actor.ID, &e.Actor.Email, &e.Actor.Role, &e.Action,
+			&e.EntityType, &e.EntityID, &e.Before, &e.After, &e.RequestID, &e.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, total, rows.Err()
+}
@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// APIKeyHeader nosi API kljuc poslodavca (umjesto bearer tokena)
+const APIKeyHeader = "X-API-Key"
+
+// APIKeySubjectPrefix oznacava UserID zahtjeva sa API kljucem ("apikey:<id>")
+const APIKeySubjectPrefix = "apikey:"
+
+var (
+	ErrInvalidAPIKey    = errors.New("invalid api key")
+	ErrAPIKeysDisabled  = errors.New("api keys not accepted")
+	ErrAPIKeyUnverified = errors.New("api key verification unavailable")
+)
+
+// EnableAPIKeys ukljucuje prihvatanje API kljuceva u Middleware-u; kljuc se
+// provjerava kod auth servisa (sa service tokenom iz tokens) pri svakom
+// zahtjevu, pa opoziv vazi odmah, a auth broji upotrebu kljuca
+func (v *Verifier) EnableAPIKeys(tokens *TokenSource) {
+	v.mu.Lock()
+	v.apiKeys = tokens
+	v.mu.Unlock()
+}
+
+// VerifyAPIKey vraca claims za API kljuc: uloga je EmployerRole, a dozvole
+// su dozvole kljuca
+func (v *Verifier) VerifyAPIKey(ctx context.Context, key string) (*TokenClaims, error) {
+	v.mu.RLock()
+	tokens := v.apiKeys
+	v.mu.RUnlock()
+	if tokens == nil {
+		return nil, ErrAPIKeysDisabled
+	}
+
+	body, _ := json.Marshal(map[string]string{"key": key})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.authURL+"/api/v1/auth/internal/api-keys/introspect", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if err := tokens.AuthorizeRequest(req); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrAPIKeyUnverified, err)
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrAPIKeyUnverified, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: unexpected status %d", ErrAPIKeyUnverified, resp.StatusCode)
+	}
+
+	var out struct {
+		Active      bool     `json:"active"`
+		ID          string   `json:"id"`
+		EmployerID  string   `json:"employerId"`
+		Permissions []string `json:"permissions"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrAPIKeyUnverified, err)
+	}
+	if !out.Active {
+		return nil, ErrInvalidAPIKey
+	}
+
+	return &TokenClaims{
+		ID:         out.ID,
+		UserID:     APIKeySubjectPrefix + out.ID,
+		Role:       EmployerRole,
+		Perms:      out.Permissions,
+		EmployerID: out.EmployerID,
+	}, nil
+}
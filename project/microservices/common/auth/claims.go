@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/Bijelic03/eAdministration/project/microservices/common/policy"
+)
+
+// ServiceRole je uloga service tokena kojima servisi pozivaju jedni druge, a
+// EmployerRole uloga zahtjeva sa API kljucem poslodavca
+const (
+	ServiceRole  = policy.ServiceRole
+	EmployerRole = policy.EmployerRole
+)
+
+// TokenClaims su podaci iz verifikovanog access tokena
+type TokenClaims struct {
+	ID        string    `json:"jti"`
+	UserID    string    `json:"sub"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	SessionID string    `json:"sid"`
+	Perms     []string  `json:"perms"`
+	Scopes    []string  `json:"scope"`
+	ExpiresAt time.Time `json:"exp"`
+
+	// EmployerID je poslodavac kome pripada API kljuc (samo EmployerRole)
+	EmployerID string `json:"employerId,omitempty"`
+
+	// Actor je administrator koji impersonira korisnika (claim "act")
+	Actor *TokenActor `json:"act,omitempty"`
+}
+
+// TokenActor je stvarni korisnik iza impersonacionog tokena
+type TokenActor struct {
+	ID    string `json:"sub"`
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+type contextKey struct{}
+
+var claimsKey = contextKey{}
+
+// WithClaims vraca kontekst koji nosi claims verifikovanog tokena
+func WithClaims(ctx context.Context, claims *TokenClaims) context.Context {
+	return context.WithValue(ctx, claimsKey, claims)
+}
+
+// ClaimsFromContext vraca claims iz konteksta; za neautentifikovan zahtjev
+// vraca prazne claims, tako da handler moze direktno citati Role/Email
+func ClaimsFromContext(ctx context.Context) *TokenClaims {
+	if claims, ok := ctx.Value(claimsKey).(*TokenClaims); ok && claims != nil {
+		return claims
+	}
+	return &TokenClaims{}
+}
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Middleware zahtijeva validan bearer token (ili API kljuc, ako su ukljuceni),
+// provjerava pristup ruti prema politici iz auth servisa i upisuje
+// TokenClaims u kontekst zahtjeva
+func (v *Verifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if key := r.Header.Get(APIKeyHeader); key != "" {
+			claims, err := v.VerifyAPIKey(r.Context(), key)
+			if err != nil {
+				switch {
+				case errors.Is(err, ErrAPIKeysDisabled):
+					http.Error(w, `{"error":"api keys not accepted"}`, http.StatusUnauthorized)
+				case errors.Is(err, ErrInvalidAPIKey):
+					http.Error(w, `{"error":"api key not valid"}`, http.StatusUnauthorized)
+				default:
+					http.Error(w, `{"error":"api key verification unavailable"}`, http.StatusServiceUnavailable)
+				}
+				return
+			}
+			v.authorize(w, r, claims, next)
+			return
+		}
+
+		authHeader := r.Header.Get("Authorization")
+		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
+			http.Error(w, `{"error":"Missing or invalid Authorization header"}`, http.StatusUnauthorized)
+			return
+		}
+		token := strings.TrimPrefix(authHeader, "Bearer ")
+
+		claims, err := v.Verify(token)
+		if err != nil {
+			switch {
+			case errors.Is(err, ErrNoKeys):
+				http.Error(w, `{"error":"auth keys unavailable"}`, http.StatusServiceUnavailable)
+			case errors.Is(err, ErrStaleRevoked):
+				http.Error(w, `{"error":"token revocation list unavailable"}`, http.StatusServiceUnavailable)
+			case errors.Is(err, ErrTokenRevoked):
+				http.Error(w, `{"error":"token revoked"}`, http.StatusUnauthorized)
+			case errors.Is(err, ErrUserDisabled):
+				http.Error(w, `{"error":"account disabled"}`, http.StatusUnauthorized)
+			default:
+				http.Error(w, `{"error":"token not valid"}`, http.StatusUnauthorized)
+			}
+			return
+		}
+		// impersonacioni token je samo za citanje
+		if claims.Actor != nil && r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, `{"error":"impersonation is read-only"}`, http.StatusForbidden)
+			return
+		}
+		v.authorize(w, r, claims, next)
+	})
+}
+
+// authorize provjerava pristup ruti i prosljedjuje zahtjev sa claims u kontekstu
+func (v *Verifier) authorize(w http.ResponseWriter, r *http.Request, claims *TokenClaims, next http.Handler) {
+	decision, err := v.Authorize(r.Method, r.URL.Path, claims)
+	if err != nil {
+		http.Error(w, `{"error":"authorization policy unavailable"}`, http.StatusServiceUnavailable)
+		return
+	}
+	if !decision.Allowed {
+		http.Error(w, `{"error":"forbidden: `+decision.Reason+`"}`, http.StatusForbidden)
+		return
+	}
+
+	next.ServeHTTP(w, r.WithContext(WithClaims(r.Context(), claims)))
+}
@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrNoServiceClient = errors.New("service client credentials not configured")
+
+// token se obnavlja ovoliko prije isteka, da ne istekne usred poziva
+const tokenRefreshMargin = 30 * time.Second
+
+// TokenSource pribavlja service token od auth servisa (client credentials)
+// za pozive internih ruta drugih servisa i kesira ga do isteka. Korisnicki
+// token se nikad ne prosljedjuje drugom servisu.
+type TokenSource struct {
+	authURL      string
+	clientID     string
+	clientSecret string
+	client       *http.Client
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+func NewTokenSource(authURL, clientID, clientSecret string) *TokenSource {
+	return &TokenSource{
+		authURL:      authURL,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		client:       &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// Token vraca vazeci service token, po potrebi trazi novi
+func (s *TokenSource) Token(ctx context.Context) (string, error) {
+	if s.clientID == "" || s.clientSecret == "" {
+		return "", ErrNoServiceClient
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token != "" && time.Until(s.expiresAt) > tokenRefreshMargin {
+		return s.token, nil
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.authURL+"/api/v1/auth/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(s.clientID), url.QueryEscape(s.clientSecret))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("POST /api/v1/auth/token: unexpected status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	if body.AccessToken == "" {
+		return "", errors.New("token response has no access_token")
+	}
+	s.token = body.AccessToken
+	s.expiresAt = time.Now().Add(time.Duration(body.ExpiresIn) * time.Second)
+	return s.token, nil
+}
+
+// AuthorizeRequest postavlja service token u Authorization header zahtjeva
+func (s *TokenSource) AuthorizeRequest(req *http.Request) error {
+	token, err := s.Token(req.Context())
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
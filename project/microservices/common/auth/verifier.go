@@ -0,0 +1,336 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Bijelic03/eAdministration/project/microservices/common/policy"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Issuer mora odgovarati issuer-u koji auth servis upisuje u tokene
+const Issuer = "eadministration-auth"
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrTokenRevoked = errors.New("token revoked")
+	ErrUserDisabled = errors.New("account disabled")
+	ErrNoKeys       = errors.New("signing keys not loaded")
+	ErrNoPolicy     = errors.New("authorization policy not loaded")
+	ErrStaleRevoked = errors.New("revocation list is stale")
+)
+
+// Verifier lokalno verifikuje tokene koje izdaje auth servis. Javni kljucevi
+// (JWKS), lista opozvanih tokena i politika autorizacije servisa se kesiraju
+// i osvjezavaju svakih interval, pa je opozvan token prihvacen najvise jedan
+// interval. Ako lista opozvanih nije osvjezena duze od maxStale (auth nije
+// dostupan), tokeni se odbijaju umjesto da se vjeruje zastarjeloj listi.
+type Verifier struct {
+	authURL  string
+	service  string
+	client   *http.Client
+	interval time.Duration
+	maxStale time.Duration
+
+	mu          sync.RWMutex
+	keys        map[string]ed25519.PublicKey
+	revoked     map[string]struct{}
+	sessions    map[string]struct{}
+	disabled    map[string]struct{}
+	policy      *policy.Policy
+	refreshedAt time.Time
+	lastAttempt time.Time
+	apiKeys     *TokenSource // nil dok API kljucevi nisu ukljuceni
+}
+
+func NewVerifier(authURL, service string, interval time.Duration) *Verifier {
+	return &Verifier{
+		authURL:  authURL,
+		service:  service,
+		client:   &http.Client{Timeout: 5 * time.Second},
+		interval: interval,
+		maxStale: 3 * interval,
+		keys:     map[string]ed25519.PublicKey{},
+		revoked:  map[string]struct{}{},
+		sessions: map[string]struct{}{},
+		disabled: map[string]struct{}{},
+	}
+}
+
+// Start ucitava kljuceve i pokrece periodicno osvjezavanje dok se ctx ne otkaze
+func (v *Verifier) Start(ctx context.Context) {
+	if err := v.Refresh(ctx); err != nil {
+		log.Println("auth verifier: initial refresh failed:", err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(v.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := v.Refresh(ctx); err != nil {
+					log.Println("auth verifier: refresh failed:", err)
+				}
+			}
+		}
+	}()
+}
+
+// Refresh preuzima JWKS, listu opozvanih tokena i politiku; ako auth nije
+// dostupan zadrzavaju se prethodno kesirane vrijednosti
+func (v *Verifier) Refresh(ctx context.Context) error {
+	keys, err := v.fetchKeys(ctx)
+	if err != nil {
+		return err
+	}
+	rev, err := v.fetchRevoked(ctx)
+	if err != nil {
+		return err
+	}
+	pol, err := v.fetchPolicy(ctx)
+	if err != nil {
+		return err
+	}
+
+	v.mu.Lock()
+	v.keys = keys
+	v.revoked = rev.jtis
+	v.sessions = rev.sessions
+	v.disabled = rev.users
+	v.policy = pol
+	v.refreshedAt = time.Now()
+	v.mu.Unlock()
+	return nil
+}
+
+// stale javlja da lista opozvanih tokena nije osvjezena duze od maxStale
+func (v *Verifier) stale() bool {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return time.Since(v.refreshedAt) > v.maxStale
+}
+
+// Verify provjerava potpis, issuer, exp, da token i njegova sesija nisu
+// opozvani i da nalog (i administrator koji ga impersonira) nije deaktiviran
+func (v *Verifier) Verify(tokenString string) (*TokenClaims, error) {
+	v.mu.RLock()
+	noKeys := len(v.keys) == 0
+	v.mu.RUnlock()
+	if noKeys && !v.refreshIfStale() {
+		return nil, ErrNoKeys
+	}
+	if v.stale() {
+		v.refreshIfStale()
+		if v.stale() {
+			return nil, ErrStaleRevoked
+		}
+	}
+
+	var mc jwt.MapClaims
+	token, err := jwt.ParseWithClaims(tokenString, &mc, v.keyFunc,
+		jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithIssuer(Issuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	tc := &TokenClaims{}
+	tc.ID, _ = mc["jti"].(string)
+	tc.UserID, _ = mc["sub"].(string)
+	tc.Email, _ = mc["email"].(string)
+	tc.Role, _ = mc["role"].(string)
+	tc.SessionID, _ = mc["sid"].(string)
+	if perms, ok := mc["perms"].([]any); ok {
+		for _, p := range perms {
+			if s, ok := p.(string); ok {
+				tc.Perms = append(tc.Perms, s)
+			}
+		}
+	}
+	if scope, ok := mc["scope"].(string); ok {
+		tc.Scopes = strings.Fields(scope)
+	}
+	if exp, err := mc.GetExpirationTime(); err == nil && exp != nil {
+		tc.ExpiresAt = exp.Time
+	}
+	if act, ok := mc["act"].(map[string]any); ok {
+		tc.Actor = &TokenActor{}
+		tc.Actor.ID, _ = act["sub"].(string)
+		tc.Actor.Email, _ = act["email"].(string)
+		tc.Actor.Role, _ = act["role"].(string)
+	}
+
+	v.mu.RLock()
+	_, revoked := v.revoked[tc.ID]
+	_, sessionRevoked := v.sessions[tc.SessionID]
+	_, disabled := v.disabled[tc.UserID]
+	if tc.Actor != nil {
+		_, actorDisabled := v.disabled[tc.Actor.ID]
+		disabled = disabled || actorDisabled
+	}
+	v.mu.RUnlock()
+	if revoked || sessionRevoked {
+		return nil, ErrTokenRevoked
+	}
+	if disabled {
+		return nil, ErrUserDisabled
+	}
+
+	return tc, nil
+}
+
+// Authorize provjerava zahtjev prema kesiranoj politici servisa; bez
+// ucitane politike svi zahtjevi se odbijaju
+func (v *Verifier) Authorize(method, path string, claims *TokenClaims) (policy.Decision, error) {
+	v.mu.RLock()
+	p := v.policy
+	v.mu.RUnlock()
+	if p == nil {
+		return policy.Decision{}, ErrNoPolicy
+	}
+	return p.Authorize(method, path, policy.Subject{
+		UserID: claims.UserID,
+		Email:  claims.Email,
+		Role:   claims.Role,
+		Perms:  claims.Perms,
+		Scopes: claims.Scopes,
+	}), nil
+}
+
+func (v *Verifier) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	v.mu.RLock()
+	key, ok := v.keys[kid]
+	v.mu.RUnlock()
+	if ok {
+		return key, nil
+	}
+
+	// nepoznat kid: auth je mozda rotirao kljuc
+	if v.refreshIfStale() {
+		v.mu.RLock()
+		key, ok = v.keys[kid]
+		v.mu.RUnlock()
+		if ok {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+// refreshIfStale osvjezava kes van redovnog intervala, najvise jednom u 10s,
+// kako neispravni tokeni ne bi opteretili auth servis
+func (v *Verifier) refreshIfStale() bool {
+	v.mu.Lock()
+	if time.Since(v.lastAttempt) < 10*time.Second {
+		v.mu.Unlock()
+		return false
+	}
+	v.lastAttempt = time.Now()
+	v.mu.Unlock()
+
+	return v.Refresh(context.Background()) == nil
+}
+
+func (v *Verifier) fetchKeys(ctx context.Context) (map[string]ed25519.PublicKey, error) {
+	var body struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Crv string `json:"crv"`
+			Kid string `json:"kid"`
+			X   string `json:"x"`
+		} `json:"keys"`
+	}
+	if err := v.getJSON(ctx, "/api/v1/auth/jwks", &body); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]ed25519.PublicKey, len(body.Keys))
+	for _, k := range body.Keys {
+		if k.Kty != "OKP" || k.Crv != "Ed25519" {
+			continue
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			continue
+		}
+		keys[k.Kid] = ed25519.PublicKey(x)
+	}
+	if len(keys) == 0 {
+		return nil, ErrNoKeys
+	}
+	return keys, nil
+}
+
+// revocations su skupovi opozvanih tokena (jti), sesija (sid) i deaktiviranih naloga (sub)
+type revocations struct {
+	jtis     map[string]struct{}
+	sessions map[string]struct{}
+	users    map[string]struct{}
+}
+
+func (v *Verifier) fetchRevoked(ctx context.Context) (*revocations, error) {
+	var body struct {
+		Jtis          []string `json:"jtis"`
+		Sessions      []string `json:"sessions"`
+		DisabledUsers []string `json:"disabledUsers"`
+	}
+	if err := v.getJSON(ctx, "/api/v1/auth/revoked", &body); err != nil {
+		return nil, err
+	}
+
+	return &revocations{
+		jtis:     toSet(body.Jtis),
+		sessions: toSet(body.Sessions),
+		users:    toSet(body.DisabledUsers),
+	}, nil
+}
+
+func toSet(ids []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		set[id] = struct{}{}
+	}
+	return set
+}
+
+func (v *Verifier) fetchPolicy(ctx context.Context) (*policy.Policy, error) {
+	var p policy.Policy
+	if err := v.getJSON(ctx, "/api/v1/auth/policy?service="+url.QueryEscape(v.service), &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (v *Verifier) getJSON(ctx context.Context, path string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.authURL+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: unexpected status %d", path, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
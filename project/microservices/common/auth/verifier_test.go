@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// authServer glumi auth servis sa jednim kljucem i praznom listom opozvanih
+func authServer(t *testing.T) *httptest.Server {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/auth/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{
+			{"kty": "OKP", "crv": "Ed25519", "kid": "k1", "x": base64.RawURLEncoding.EncodeToString(pub)},
+		}})
+	})
+	mux.HandleFunc("/api/v1/auth/revoked", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"jtis": []string{}})
+	})
+	mux.HandleFunc("/api/v1/auth/policy", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"service": "test"})
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestVerifyRejectsStaleRevocations(t *testing.T) {
+	srv := authServer(t)
+	v := NewVerifier(srv.URL, "test", time.Second)
+
+	tests := []struct {
+		name    string
+		age     time.Duration // koliko je prosla posljednja uspjesna provjera liste
+		authUp  bool
+		wantErr error
+	}{
+		{"fresh list", 0, true, ErrInvalidToken},
+		{"stale list refreshed on demand", time.Minute, true, ErrInvalidToken},
+		{"stale list and auth down", time.Minute, false, ErrStaleRevoked},
+		{"within the window while auth is down", time.Second, false, ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := v.Refresh(context.Background()); err != nil && tt.authUp {
+				t.Fatal(err)
+			}
+			if !tt.authUp {
+				srv.Close()
+			}
+			v.mu.Lock()
+			v.refreshedAt = time.Now().Add(-tt.age)
+			v.lastAttempt = time.Time{}
+			v.mu.Unlock()
+
+			if _, err := v.Verify("not-a-token"); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+// Package credentials definise potpisane diplome (graduation credentials):
+// JWT potpisan Ed25519 kljucem univerziteta koji svako moze provjeriti bez
+// poziva univerziteta, samo uz javni kljuc (GET /credentials/keys).
+// Univerzitet diplome izdaje (Signer), a zavod za zaposljavanje ih provjerava
+// kesiranim kljucevima (Verifier).
+package credentials
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Issuer je izdavalac diploma upisan u "iss" claim
+const Issuer = "eadministration-university"
+
+// GraduationType je tip kredencijala o zavrsenim studijama
+const GraduationType = "GraduationCredential"
+
+var (
+	ErrInvalidCredential = errors.New("invalid credential")
+	ErrUnknownKey        = errors.New("credential signed with unknown key")
+	ErrCredentialRevoked = errors.New("credential revoked")
+)
+
+// Graduation je sadrzaj diplome
+type Graduation struct {
+	Type           string   `json:"type"`
+	FullName       string   `json:"fullName"`
+	IndexNo        string   `json:"indexNo"`
+	Program        string   `json:"program"`
+	GraduationDate string   `json:"graduationDate"` // YYYY-MM-DD
+	FinalAverage   *float64 `json:"finalAverage"`
+	Ects           int      `json:"ects"`
+}
+
+// Claims je diploma u JWT obliku; jti je ID kredencijala (za opoziv), a sub
+// ID studenta. Diploma nema rok vazenja.
+type Claims struct {
+	jwt.RegisteredClaims
+	Credential Graduation `json:"cred"`
+}
+
+// KeyID izvodi kid iz javnog kljuca (isto kao auth servis za JWKS)
+func KeyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return base64.RawURLEncoding.EncodeToString(sum[:12])
+}
+
+// Verify provjerava potpis diplome kljucem iz keys (po kid-u), issuer i tip;
+// opoziv provjerava pozivalac
+func Verify(token string, keys map[string]ed25519.PublicKey) (*Claims, error) {
+	var claims Claims
+	_, err := jwt.ParseWithClaims(token, &claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, ok := keys[kid]
+		if !ok {
+			return nil, ErrUnknownKey
+		}
+		return key, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg()}), jwt.WithIssuer(Issuer))
+	if err != nil {
+		if errors.Is(err, ErrUnknownKey) {
+			return nil, ErrUnknownKey
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredential, err)
+	}
+	if claims.Credential.Type != GraduationType || claims.ID == "" {
+		return nil, ErrInvalidCredential
+	}
+	return &claims, nil
+}
@@ -0,0 +1,106 @@
+package credentials
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newSigner(t *testing.T) *Signer {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Signer{key: priv, KeyID: KeyID(priv.Public().(ed25519.PublicKey))}
+}
+
+// signClaims potpisuje proizvoljne claim-ove kljucem signer-a, mimo Sign
+func signClaims(t *testing.T, s *Signer, claims jwt.Claims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = s.KeyID
+	signed, err := token.SignedString(s.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+// tamper mijenja ime u sadrzaju diplome, a potpis ostavlja isti
+func tamper(t *testing.T, token string) string {
+	parts := strings.Split(token, ".")
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body map[string]any
+	if err := json.Unmarshal(payload, &body); err != nil {
+		t.Fatal(err)
+	}
+	body["cred"].(map[string]any)["fullName"] = "Marko Markovic"
+	payload, _ = json.Marshal(body)
+	parts[1] = base64.RawURLEncoding.EncodeToString(payload)
+	return strings.Join(parts, ".")
+}
+
+func TestSignAndVerify(t *testing.T) {
+	signer, other := newSigner(t), newSigner(t)
+	issuedAt := time.Now().Add(-time.Hour).Truncate(time.Second)
+	grad := Graduation{FullName: "Ana Petrovic", IndexNo: "RA1/2020", Program: "Racunarstvo", GraduationDate: "2026-07-01", Ects: 240}
+
+	valid, err := signer.Sign("cred-1", "student-1", grad, issuedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	registered := func(issuer, id string) jwt.RegisteredClaims {
+		return jwt.RegisteredClaims{ID: id, Issuer: issuer, Subject: "student-1", IssuedAt: jwt.NewNumericDate(issuedAt)}
+	}
+	typed := grad
+	typed.Type = GraduationType
+
+	keys := map[string]ed25519.PublicKey{signer.KeyID: signer.PublicKey()}
+
+	tests := []struct {
+		name    string
+		token   string
+		keys    map[string]ed25519.PublicKey
+		wantErr error
+	}{
+		{"valid", valid, keys, nil},
+		{"unknown kid", valid, map[string]ed25519.PublicKey{other.KeyID: other.PublicKey()}, ErrUnknownKey},
+		{"no keys", valid, nil, ErrUnknownKey},
+		{"kid with another key", valid, map[string]ed25519.PublicKey{signer.KeyID: other.PublicKey()}, ErrInvalidCredential},
+		{"tampered payload", tamper(t, valid), keys, ErrInvalidCredential},
+		{"wrong issuer", signClaims(t, signer, Claims{RegisteredClaims: registered("someone-else", "cred-1"), Credential: typed}), keys, ErrInvalidCredential},
+		{"wrong type", signClaims(t, signer, Claims{RegisteredClaims: registered(Issuer, "cred-1"), Credential: grad}), keys, ErrInvalidCredential},
+		{"missing id", signClaims(t, signer, Claims{RegisteredClaims: registered(Issuer, ""), Credential: typed}), keys, ErrInvalidCredential},
+		{"not a jwt", "not-a-credential", keys, ErrInvalidCredential},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := Verify(tt.token, tt.keys)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Verify error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if claims.ID != "cred-1" || claims.Subject != "student-1" || claims.Credential != typed {
+				t.Errorf("claims = %+v", claims)
+			}
+			if !claims.IssuedAt.Time.Equal(issuedAt) {
+				t.Errorf("IssuedAt = %v, want %v", claims.IssuedAt.Time, issuedAt)
+			}
+		})
+	}
+}
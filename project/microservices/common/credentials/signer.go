@@ -0,0 +1,76 @@
+package credentials
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Signer potpisuje diplome privatnim kljucem univerziteta
+type Signer struct {
+	key   ed25519.PrivateKey
+	KeyID string
+}
+
+// LoadSigner cita Ed25519 kljuc iz PEM (PKCS#8) fajla; bez fajla se pravi
+// privremeni kljuc (javni kljucevi ostaju sacuvani u bazi, pa ranije izdate
+// diplome i dalje prolaze provjeru, ali u produkciji kljuc treba biti stalan)
+func LoadSigner(path string) (*Signer, error) {
+	var key ed25519.PrivateKey
+	if path == "" {
+		log.Println("CREDENTIAL_SIGNING_KEY_FILE not set, generating ephemeral credential signing key")
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		key = priv
+	} else {
+		pemBytes, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		block, _ := pem.Decode(pemBytes)
+		if block == nil {
+			return nil, fmt.Errorf("no PEM block found")
+		}
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		edKey, ok := parsed.(ed25519.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("credential signing key is not an Ed25519 key")
+		}
+		key = edKey
+	}
+	return &Signer{key: key, KeyID: KeyID(key.Public().(ed25519.PublicKey))}, nil
+}
+
+func (s *Signer) PublicKey() ed25519.PublicKey {
+	return s.key.Public().(ed25519.PublicKey)
+}
+
+// Sign izdaje diplomu sa ID-jem id za studenta studentID
+func (s *Signer) Sign(id, studentID string, g Graduation, issuedAt time.Time) (string, error) {
+	g.Type = GraduationType
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        id,
+			Issuer:    Issuer,
+			Subject:   studentID,
+			IssuedAt:  jwt.NewNumericDate(issuedAt),
+			NotBefore: jwt.NewNumericDate(issuedAt),
+		},
+		Credential: g,
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = s.KeyID
+	return token.SignedString(s.key)
+}
@@ -0,0 +1,151 @@
+package credentials
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Verifier provjerava diplome bez poziva univerziteta: javni kljucevi i lista
+// opozvanih diploma se kesiraju i periodicno osvjezavaju
+type Verifier struct {
+	universityURL string
+	client        *http.Client
+	interval      time.Duration
+
+	mu      sync.RWMutex
+	keys    map[string]ed25519.PublicKey
+	revoked map[string]struct{}
+}
+
+func NewVerifier(universityURL string, interval time.Duration) *Verifier {
+	return &Verifier{
+		universityURL: universityURL,
+		client:        &http.Client{Timeout: 5 * time.Second},
+		interval:      interval,
+		keys:          map[string]ed25519.PublicKey{},
+		revoked:       map[string]struct{}{},
+	}
+}
+
+// Start ucitava kljuceve i pokrece periodicno osvjezavanje dok se ctx ne otkaze
+func (v *Verifier) Start(ctx context.Context) {
+	if err := v.Refresh(ctx); err != nil {
+		log.Println("credential verifier: initial refresh failed:", err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(v.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := v.Refresh(ctx); err != nil {
+					log.Println("credential verifier: refresh failed:", err)
+				}
+			}
+		}
+	}()
+}
+
+// Refresh preuzima kljuceve i listu opozvanih diploma; ako univerzitet nije
+// dostupan zadrzavaju se prethodno kesirane vrijednosti
+func (v *Verifier) Refresh(ctx context.Context) error {
+	var keysBody struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Crv string `json:"crv"`
+			Kid string `json:"kid"`
+			X   string `json:"x"`
+		} `json:"keys"`
+	}
+	if err := v.getJSON(ctx, "/api/v1/university/credentials/keys", &keysBody); err != nil {
+		return err
+	}
+	var revBody struct {
+		Revoked []struct {
+			ID string `json:"id"`
+		} `json:"revoked"`
+	}
+	if err := v.getJSON(ctx, "/api/v1/university/credentials/revocations", &revBody); err != nil {
+		return err
+	}
+
+	keys := make(map[string]ed25519.PublicKey, len(keysBody.Keys))
+	for _, k := range keysBody.Keys {
+		if k.Kty != "OKP" || k.Crv != "Ed25519" {
+			continue
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			continue
+		}
+		keys[k.Kid] = ed25519.PublicKey(x)
+	}
+	revoked := make(map[string]struct{}, len(revBody.Revoked))
+	for _, c := range revBody.Revoked {
+		revoked[c.ID] = struct{}{}
+	}
+
+	v.mu.Lock()
+	v.keys = keys
+	v.revoked = revoked
+	v.mu.Unlock()
+	return nil
+}
+
+// Verify provjerava potpis diplome kesiranim kljucevima i da nije opozvana
+func (v *Verifier) Verify(token string) (*Claims, error) {
+	v.mu.RLock()
+	keys := v.keys
+	v.mu.RUnlock()
+
+	claims, err := Verify(token, keys)
+	if errors.Is(err, ErrUnknownKey) {
+		// univerzitet je mozda u medjuvremenu objavio novi kljuc
+		if rerr := v.Refresh(context.Background()); rerr != nil {
+			return nil, err
+		}
+		v.mu.RLock()
+		keys = v.keys
+		v.mu.RUnlock()
+		claims, err = Verify(token, keys)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	v.mu.RLock()
+	_, revoked := v.revoked[claims.ID]
+	v.mu.RUnlock()
+	if revoked {
+		return nil, ErrCredentialRevoked
+	}
+	return claims, nil
+}
+
+func (v *Verifier) getJSON(ctx context.Context, path string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.universityURL+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: unexpected status %d", path, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
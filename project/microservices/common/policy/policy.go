@@ -0,0 +1,171 @@
+// Package policy je evaluator pravila autorizacije. Pravila definise auth
+// servis, a isti evaluator koriste auth (provjera i objava politike) i
+// ostali servisi (lokalna provjera kesirane politike).
+package policy
+
+import (
+	"strings"
+)
+
+// Owner opisuje pravilo vlasnistva: vrijednost path parametra Param mora biti
+// jednaka claim-u Claim ("sub" ili "email") iz tokena
+type Owner struct {
+	Param string `json:"param"`
+	Claim string `json:"claim"`
+}
+
+// ServiceRole je uloga service tokena (client credentials) kojima servisi
+// pozivaju jedni druge; nijedan korisnicki nalog je ne moze imati
+const ServiceRole = "service"
+
+// EmployerRole je uloga zahtjeva sa API kljucem poslodavca; vazi samo ono sto
+// dozvoljavaju dozvole kljuca
+const EmployerRole = "employer"
+
+// Rule dozvoljava pristup ruti (metoda + sablon putanje u mux formatu).
+// Uloge iz Roles i korisnici sa nekom od dozvola iz Perms imaju pristup bez
+// ogranicenja, a uloge iz OwnRoles samo nad resursom koji im pripada prema
+// Owner pravilu. Service tokeni imaju pristup samo rutama cije Scopes sadrze
+// neki od scope-ova tokena, a API kljucevi poslodavaca rutama cije Perms
+// sadrze neku od dozvola kljuca; interne rute (samo Scopes) odbijaju
+// korisnicke tokene.
+type Rule struct {
+	Method   string   `json:"method"`
+	Path     string   `json:"path"`
+	Roles    []string `json:"roles,omitempty"`
+	OwnRoles []string `json:"ownRoles,omitempty"`
+	Owner    *Owner   `json:"owner,omitempty"`
+	Perms    []string `json:"perms,omitempty"`
+	Scopes   []string `json:"scopes,omitempty"`
+}
+
+// Policy je skup pravila jednog servisa; izvor je auth servis
+// (GET /api/v1/auth/policy)
+type Policy struct {
+	Service string `json:"service"`
+	Rules   []Rule `json:"rules"`
+}
+
+// Subject su podaci iz tokena potrebni za odluku
+type Subject struct {
+	UserID string
+	Email  string
+	Role   string
+	Perms  []string
+	Scopes []string
+}
+
+// Decision je rezultat provjere; Rule je nil ako ruta nije pokrivena politikom
+type Decision struct {
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason,omitempty"`
+	Rule    *Rule  `json:"rule,omitempty"`
+}
+
+// Authorize pronalazi pravilo za metodu i putanju i provjerava ulogu i
+// vlasnistvo. Rute koje nisu u politici su zabranjene.
+func (p *Policy) Authorize(method, path string, sub Subject) Decision {
+	rule, params := p.match(method, path)
+	if rule == nil {
+		return Decision{Allowed: false, Reason: "no policy for route"}
+	}
+
+	if sub.Role == ServiceRole {
+		if hasAny(rule.Scopes, sub.Scopes) {
+			return Decision{Allowed: true, Rule: rule}
+		}
+		return Decision{Allowed: false, Reason: "insufficient scope", Rule: rule}
+	}
+	if sub.Role == EmployerRole {
+		if hasAny(rule.Perms, sub.Perms) {
+			return Decision{Allowed: true, Rule: rule}
+		}
+		return Decision{Allowed: false, Reason: "insufficient permission", Rule: rule}
+	}
+	if len(rule.Roles) == 0 && len(rule.OwnRoles) == 0 && len(rule.Perms) == 0 {
+		return Decision{Allowed: false, Reason: "service token required", Rule: rule}
+	}
+
+	if hasRole(rule.Roles, sub.Role) || hasAny(rule.Perms, sub.Perms) {
+		return Decision{Allowed: true, Rule: rule}
+	}
+	if hasRole(rule.OwnRoles, sub.Role) && rule.Owner != nil {
+		var claim string
+		switch rule.Owner.Claim {
+		case "sub":
+			claim = sub.UserID
+		case "email":
+			claim = sub.Email
+		}
+		if claim != "" && strings.EqualFold(params[rule.Owner.Param], claim) {
+			return Decision{Allowed: true, Rule: rule}
+		}
+		return Decision{Allowed: false, Reason: "not resource owner", Rule: rule}
+	}
+	return Decision{Allowed: false, Reason: "insufficient role", Rule: rule}
+}
+
+// match vraca pravilo koje odgovara zahtjevu i vrijednosti path parametara.
+// Kad vise sablona odgovara (npr. /students/by-email i /students/{id}),
+// bira se onaj sa vise literalnih segmenata.
+func (p *Policy) match(method, path string) (*Rule, map[string]string) {
+	segs := splitPath(path)
+
+	var (
+		best       *Rule
+		bestParams map[string]string
+		bestScore  = -1
+	)
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if !strings.EqualFold(rule.Method, method) {
+			continue
+		}
+		params, score, ok := matchTemplate(splitPath(rule.Path), segs)
+		if ok && score > bestScore {
+			best, bestParams, bestScore = rule, params, score
+		}
+	}
+	return best, bestParams
+}
+
+func matchTemplate(tmpl, segs []string) (map[string]string, int, bool) {
+	if len(tmpl) != len(segs) {
+		return nil, 0, false
+	}
+	params := map[string]string{}
+	literals := 0
+	for i, t := range tmpl {
+		if strings.HasPrefix(t, "{") && strings.HasSuffix(t, "}") {
+			params[t[1:len(t)-1]] = segs[i]
+			continue
+		}
+		if t != segs[i] {
+			return nil, 0, false
+		}
+		literals++
+	}
+	return params, literals, true
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+func hasAny(allowed, held []string) bool {
+	for _, h := range held {
+		if hasRole(allowed, h) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasRole(roles []string, role string) bool {
+	for _, r := range roles {
+		if strings.EqualFold(r, role) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package policy
+
+import "testing"
+
+func testPolicy() *Policy {
+	own := &Owner{Param: "id", Claim: "sub"}
+	ownEmail := &Owner{Param: "email", Claim: "email"}
+	return &Policy{Service: "test", Rules: []Rule{
+		{Method: "GET", Path: "/students", Roles: []string{"facultyadmin"}},
+		{Method: "GET", Path: "/students/{id}", Roles: []string{"facultyadmin"}, OwnRoles: []string{"student"}, Owner: own},
+		{Method: "GET", Path: "/students/by-email/{email}", Roles: []string{"professor"}, OwnRoles: []string{"student"}, Owner: ownEmail},
+		{Method: "GET", Path: "/students/by-email", Roles: []string{"professor"}},
+		{Method: "PUT", Path: "/students/{id}", Roles: []string{"facultyadmin"}, Perms: []string{"students:update"}},
+		{Method: "GET", Path: "/internal/students/{id}", Scopes: []string{"students.read"}},
+		{Method: "GET", Path: "/jobs", Roles: []string{"candidate"}, Perms: []string{"jobs:read"}, Scopes: []string{"jobs.read"}},
+	}}
+}
+
+func TestAuthorize(t *testing.T) {
+	const ownerID = "3f1c2b1e-0000-0000-0000-000000000001"
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		sub     Subject
+		allowed bool
+		reason  string
+		rule    string
+	}{
+		// uloge
+		{"role allowed", "GET", "/students", Subject{Role: "facultyadmin"}, true, "", "/students"},
+		{"role case-insensitive", "GET", "/students", Subject{Role: "FacultyAdmin"}, true, "", "/students"},
+		{"role denied", "GET", "/students", Subject{Role: "student"}, false, "insufficient role", "/students"},
+		{"method must match", "POST", "/students", Subject{Role: "facultyadmin"}, false, "no policy for route", ""},
+		{"permission instead of role", "PUT", "/students/" + ownerID, Subject{Role: "professor", Perms: []string{"students:update"}}, true, "", "/students/{id}"},
+		{"permission missing", "PUT", "/students/" + ownerID, Subject{Role: "professor", Perms: []string{"students:read"}}, false, "insufficient role", "/students/{id}"},
+
+		// vlasnistvo
+		{"owner by sub", "GET", "/students/" + ownerID, Subject{UserID: ownerID, Role: "student"}, true, "", "/students/{id}"},
+		{"owner by sub, other id", "GET", "/students/other", Subject{UserID: ownerID, Role: "student"}, false, "not resource owner", "/students/{id}"},
+		{"owner without sub claim", "GET", "/students/" + ownerID, Subject{Role: "student"}, false, "not resource owner", "/students/{id}"},
+		{"owner by email, case-insensitive", "GET", "/students/by-email/Ana@Uni.rs", Subject{Email: "ana@uni.rs", Role: "student"}, true, "", "/students/by-email/{email}"},
+		{"owner by email, other email", "GET", "/students/by-email/marko@uni.rs", Subject{Email: "ana@uni.rs", Role: "student"}, false, "not resource owner", "/students/by-email/{email}"},
+		{"owner role is not full role", "GET", "/students", Subject{UserID: ownerID, Role: "student"}, false, "insufficient role", "/students"},
+
+		// literalni segment ima prednost nad {param}
+		{"literal beats param", "GET", "/students/by-email", Subject{Role: "professor"}, true, "", "/students/by-email"},
+		{"literal rule applies its own roles", "GET", "/students/by-email", Subject{Role: "facultyadmin"}, false, "insufficient role", "/students/by-email"},
+		{"param rule for other values", "GET", "/students/by-id", Subject{Role: "facultyadmin"}, true, "", "/students/{id}"},
+		{"trailing slash", "GET", "/students/", Subject{Role: "facultyadmin"}, true, "", "/students"},
+
+		// rute van politike
+		{"unknown route", "GET", "/courses", Subject{Role: "facultyadmin"}, false, "no policy for route", ""},
+		{"unknown deeper route", "GET", "/students/" + ownerID + "/grades", Subject{Role: "facultyadmin"}, false, "no policy for route", ""},
+
+		// service tokeni
+		{"service with scope", "GET", "/internal/students/1", Subject{Role: ServiceRole, Scopes: []string{"students.read"}}, true, "", "/internal/students/{id}"},
+		{"service without scope", "GET", "/internal/students/1", Subject{Role: ServiceRole, Scopes: []string{"jobs.read"}}, false, "insufficient scope", "/internal/students/{id}"},
+		{"service ignores roles", "GET", "/students", Subject{Role: ServiceRole, Scopes: []string{"students.read"}}, false, "insufficient scope", "/students"},
+		{"user on internal route", "GET", "/internal/students/1", Subject{Role: "facultyadmin", Perms: []string{"students.read"}}, false, "service token required", "/internal/students/{id}"},
+
+		// API kljucevi poslodavaca
+		{"employer with perm", "GET", "/jobs", Subject{Role: EmployerRole, Perms: []string{"jobs:read"}}, true, "", "/jobs"},
+		{"employer without perm", "GET", "/jobs", Subject{Role: EmployerRole, Perms: []string{"jobs:write"}}, false, "insufficient permission", "/jobs"},
+		{"employer scopes are ignored", "GET", "/jobs", Subject{Role: EmployerRole, Scopes: []string{"jobs.read"}}, false, "insufficient permission", "/jobs"},
+		{"employer never gets roles", "GET", "/students", Subject{Role: EmployerRole, Perms: []string{"facultyadmin"}}, false, "insufficient permission", "/students"},
+	}
+
+	p := testPolicy()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := p.Authorize(tt.method, tt.path, tt.sub)
+			if d.Allowed != tt.allowed {
+				t.Fatalf("Allowed = %v, want %v (reason %q)", d.Allowed, tt.allowed, d.Reason)
+			}
+			if d.Reason != tt.reason {
+				t.Errorf("Reason = %q, want %q", d.Reason, tt.reason)
+			}
+			switch {
+			case tt.rule == "" && d.Rule != nil:
+				t.Errorf("Rule = %s, want none", d.Rule.Path)
+			case tt.rule != "" && (d.Rule == nil || d.Rule.Path != tt.rule):
+				t.Errorf("Rule = %v, want %s", d.Rule, tt.rule)
+			}
+		})
+	}
+}
+
+func TestMatchParams(t *testing.T) {
+	p := &Policy{Rules: []Rule{
+		{Method: "PUT", Path: "/programs/{id}/curriculum/{courseId}"},
+	}}
+
+	rule, params := p.match("put", "/programs/p1/curriculum/c1")
+	if rule == nil {
+		t.Fatal("expected a matching rule")
+	}
+	if params["id"] != "p1" || params["courseId"] != "c1" {
+		t.Errorf("params = %v", params)
+	}
+	if rule, _ := p.match("PUT", "/programs/p1/curriculum"); rule != nil {
+		t.Errorf("matched %s for a shorter path", rule.Path)
+	}
+}
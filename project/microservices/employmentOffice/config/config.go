@@ -13,18 +13,31 @@ type Config struct {
 	DBUser string
 	DBName string
 	DBPass string
+	// bazni URL auth servisa (JWKS i lista opozvanih tokena)
+	AuthURL string
+	// kredencijali servisa za service tokene (pozivi drugih servisa)
+	ServiceClientID     string
+	ServiceClientSecret string
+	// bazni URL university servisa (javni kljucevi i opozvane diplome)
+	UniversityURL string
 }
 
 func GetConfig() Config {
 
 	return Config{
-		Host:   os.Getenv("HOST_PORT"),
-		Port:   os.Getenv("EMPLOYMENT_OFFICE_PORT"),
-		DBHost: os.Getenv("DB_HOST"),
-		DBUser: os.Getenv("DB_USER"),
-		DBPort: os.Getenv("DB_PORT"),
-		DBPass: os.Getenv("DB_PASS"),
-		DBName: os.Getenv("DB_NAME"),
+		Host:    os.Getenv("HOST_PORT"),
+		Port:    os.Getenv("EMPLOYMENT_OFFICE_PORT"),
+		DBHost:  os.Getenv("DB_HOST"),
+		DBUser:  os.Getenv("DB_USER"),
+		DBPort:  os.Getenv("DB_PORT"),
+		DBPass:  os.Getenv("DB_PASS"),
+		DBName:  os.Getenv("DB_NAME"),
+		AuthURL: getEnv("AUTH_URL", "http://auth:8083"),
+
+		ServiceClientID:     getEnv("SERVICE_CLIENT_ID", "employmentOffice"),
+		ServiceClientSecret: os.Getenv("SERVICE_CLIENT_SECRET"),
+
+		UniversityURL: getEnv("UNIVERSITY_URL", "http://university:8081"),
 	}
 }
 
@@ -34,3 +47,10 @@ func (c Config) DatabaseURL() string {
 		c.DBUser, c.DBPass, c.DBHost, c.DBPort, c.DBName,
 	)
 }
+
+func getEnv(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
@@ -3,11 +3,13 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"io"
 	"net/http"
 	"strconv"
-	"io"
 	"time"
 
+	"github.com/Bijelic03/eAdministration/project/microservices/common/audit"
+	"github.com/Bijelic03/eAdministration/project/microservices/common/auth"
 	"github.com/Bijelic03/eAdministration/project/microservices/employmentOffice/repositories"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
@@ -22,35 +24,33 @@ type CandidateListResponse struct {
 }
 
 type CandidateHandler struct {
-	repo *repositories.CandidateRepository
+	repo   *repositories.CandidateRepository
+	tokens *auth.TokenSource // service token za pozive university servisa
 }
 
-func NewCandidateHandler(repo *repositories.CandidateRepository) *CandidateHandler {
-	return &CandidateHandler{repo: repo}
+func NewCandidateHandler(repo *repositories.CandidateRepository, tokens *auth.TokenSource) *CandidateHandler {
+	return &CandidateHandler{repo: repo, tokens: tokens}
 }
 
 // Create candidate
 func (h *CandidateHandler) CreateCandidate(w http.ResponseWriter, r *http.Request) {
-
-	role, _ := r.Context().Value("role").(string)
-
-	if role != "sszadmin" {
-		http.Error(w, "only sszadmin can create candidates", http.StatusForbidden)
-		return
-	}
-
 	var emp repositories.Candidate
 	if err := json.NewDecoder(r.Body).Decode(&emp); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	// uloge se dodjeljuju samo kroz auth servis
+	emp.Role = "candidate"
+
 	created, err := h.repo.Add(r.Context(), &emp)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	audit.Record(r.Context(), "candidate.create", "candidate", created.ID.String(), nil, created)
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(created)
 }
@@ -104,9 +104,10 @@ func (h *CandidateHandler) GetCandidateByEmail(w http.ResponseWriter, r *http.Re
 func (h *CandidateHandler) GetIndexAll(w http.ResponseWriter, r *http.Request) {
 	client := &http.Client{Timeout: 5 * time.Second}
 
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		r.Context(),
 		"GET",
-		"http://university:8081/api/v1/university/students/get/indexno/all",
+		"http://university:8081/api/v1/university/internal/students/indexno",
 		nil,
 	)
 	if err != nil {
@@ -116,9 +117,9 @@ func (h *CandidateHandler) GetIndexAll(w http.ResponseWriter, r *http.Request) {
 
 	req.Header.Set("Content-Type", "application/json")
 
-	// Prosleđivanje Authorization header-a ako postoji
-	if authHeader := r.Header.Get("Authorization"); authHeader != "" {
-		req.Header.Set("Authorization", authHeader)
+	if err := h.tokens.AuthorizeRequest(req); err != nil {
+		http.Error(w, "service token unavailable", http.StatusServiceUnavailable)
+		return
 	}
 
 	resp, err := client.Do(req)
@@ -187,11 +188,9 @@ func (h *CandidateHandler) GetAllCandidates(w http.ResponseWriter, r *http.Reque
 
 // Update candidate
 func (h *CandidateHandler) UpdateCandidate(w http.ResponseWriter, r *http.Request) {
-
-	role, _ := r.Context().Value("role").(string)
-
-	if role != "sszadmin" && role != "candidate" {
-		http.Error(w, "only sszadmin and candidates can update candidate", http.StatusForbidden)
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
 		return
 	}
 
@@ -201,26 +200,26 @@ func (h *CandidateHandler) UpdateCandidate(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	// azurira se zapis iz putanje (na njega se odnosi provjera vlasnistva);
+	// uloge se mijenjaju samo kroz auth servis
+	emp.ID = id
+	emp.Role = "candidate"
+
+	before, _ := h.repo.GetByID(r.Context(), id)
 	updated, err := h.repo.Update(r.Context(), &emp)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	audit.Record(r.Context(), "candidate.update", "candidate", updated.ID.String(), before, updated)
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(updated)
 }
 
 // Delete candidate
 func (h *CandidateHandler) DeleteCandidate(w http.ResponseWriter, r *http.Request) {
-
-	role, _ := r.Context().Value("role").(string)
-
-	if role != "sszadmin" {
-		http.Error(w, "only sszadmin can delete candidate", http.StatusForbidden)
-		return
-	}
-
 	vars := mux.Vars(r)
 	idStr := vars["id"]
 	id, err := uuid.Parse(idStr)
@@ -229,10 +228,13 @@ func (h *CandidateHandler) DeleteCandidate(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	before, _ := h.repo.GetByID(r.Context(), id)
 	if err := h.repo.Delete(r.Context(), id); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	audit.Record(r.Context(), "candidate.delete", "candidate", id.String(), before, nil)
+
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/Bijelic03/eAdministration/project/microservices/common/credentials"
+)
+
+type CredentialHandler struct {
+	credentials *credentials.Verifier
+}
+
+func NewCredentialHandler(credentials *credentials.Verifier) *CredentialHandler {
+	return &CredentialHandler{credentials: credentials}
+}
+
+// POST /api/v1/employmentOffice/credentials/verify
+// Provjera diplome kandidata kesiranim javnim kljucevima univerziteta
+func (h *CredentialHandler) VerifyCredential(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Credential string `json:"credential"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Credential == "" {
+		http.Error(w, "credential is required", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	claims, err := h.credentials.Verify(strings.TrimSpace(req.Credential))
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]any{"valid": false, "reason": err.Error()})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]any{"valid": true, "id": claims.ID, "issuedAt": claims.IssuedAt, "credential": claims.Credential})
+}
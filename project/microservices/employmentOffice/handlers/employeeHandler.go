@@ -8,6 +8,8 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/Bijelic03/eAdministration/project/microservices/common/audit"
+	"github.com/Bijelic03/eAdministration/project/microservices/common/auth"
 	"github.com/Bijelic03/eAdministration/project/microservices/employmentOffice/repositories"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
@@ -22,35 +24,33 @@ type EmployeeListResponse struct {
 }
 
 type EmployeeHandler struct {
-	repo *repositories.EmployeeRepository
+	repo   *repositories.EmployeeRepository
+	tokens *auth.TokenSource // service token za pozive university servisa
 }
 
-func NewEmployeeHandler(repo *repositories.EmployeeRepository) *EmployeeHandler {
-	return &EmployeeHandler{repo: repo}
+func NewEmployeeHandler(repo *repositories.EmployeeRepository, tokens *auth.TokenSource) *EmployeeHandler {
+	return &EmployeeHandler{repo: repo, tokens: tokens}
 }
 
 // Create employee
 func (h *EmployeeHandler) CreateEmployee(w http.ResponseWriter, r *http.Request) {
-
-	role, _ := r.Context().Value("role").(string)
-
-	if role != "sszadmin" {
-		http.Error(w, "only sszadmin can create employee", http.StatusForbidden)
-		return
-	}
-
 	var emp repositories.Employee
 	if err := json.NewDecoder(r.Body).Decode(&emp); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	// uloge se dodjeljuju samo kroz auth servis
+	emp.Role = "employee"
+
 	created, err := h.repo.Add(r.Context(), &emp)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	audit.Record(r.Context(), "employee.create", "employee", created.ID.String(), nil, created)
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(created)
 }
@@ -119,8 +119,8 @@ func (h *EmployeeHandler) GetAllEmployees(w http.ResponseWriter, r *http.Request
 		}
 	}
 
-	role, _ := r.Context().Value("role").(string)
-	email, _ := r.Context().Value("email").(string)
+	role := auth.ClaimsFromContext(r.Context()).Role
+	email := auth.ClaimsFromContext(r.Context()).Email
 
 	var emp *repositories.Employee
 
@@ -192,9 +192,10 @@ func (h *EmployeeHandler) GetAllEmployees(w http.ResponseWriter, r *http.Request
 // get all pofesors
 func (h *EmployeeHandler) GetAllProfessorsFromOtherService(w http.ResponseWriter, r *http.Request) {
 	client := &http.Client{Timeout: 5 * time.Second}
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		r.Context(),
 		"GET",
-		"http://university:8081/api/v1/university/professors",
+		"http://university:8081/api/v1/university/internal/professors",
 		nil,
 	)
 	if err != nil {
@@ -203,8 +204,9 @@ func (h *EmployeeHandler) GetAllProfessorsFromOtherService(w http.ResponseWriter
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	if authHeader := r.Header.Get("Authorization"); authHeader != "" {
-		req.Header.Set("Authorization", authHeader)
+	if err := h.tokens.AuthorizeRequest(req); err != nil {
+		http.Error(w, "service token unavailable", http.StatusServiceUnavailable)
+		return
 	}
 
 	resp, err := client.Do(req)
@@ -225,11 +227,9 @@ func (h *EmployeeHandler) GetAllProfessorsFromOtherService(w http.ResponseWriter
 
 // Update employee
 func (h *EmployeeHandler) UpdateEmployee(w http.ResponseWriter, r *http.Request) {
-
-	role, _ := r.Context().Value("role").(string)
-
-	if role != "employee" && role != "sszadmin" {
-		http.Error(w, "only sszadmin and employees can update employees", http.StatusForbidden)
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
 		return
 	}
 
@@ -239,26 +239,26 @@ func (h *EmployeeHandler) UpdateEmployee(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// azurira se zapis iz putanje (na njega se odnosi provjera vlasnistva);
+	// uloge se mijenjaju samo kroz auth servis
+	emp.ID = id
+	emp.Role = "employee"
+
+	before, _ := h.repo.GetByID(r.Context(), id)
 	updated, err := h.repo.Update(r.Context(), &emp)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	audit.Record(r.Context(), "employee.update", "employee", updated.ID.String(), before, updated)
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(updated)
 }
 
 // Delete employee
 func (h *EmployeeHandler) DeleteEmployee(w http.ResponseWriter, r *http.Request) {
-
-	role, _ := r.Context().Value("role").(string)
-
-	if role != "sszadmin" {
-		http.Error(w, "only sszadmin can delete employees", http.StatusForbidden)
-		return
-	}
-
 	vars := mux.Vars(r)
 	idStr := vars["id"]
 	id, err := uuid.Parse(idStr)
@@ -267,20 +267,23 @@ func (h *EmployeeHandler) DeleteEmployee(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	before, _ := h.repo.GetByID(r.Context(), id)
 	if err := h.repo.Delete(r.Context(), id); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	audit.Record(r.Context(), "employee.delete", "employee", id.String(), before, nil)
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
 // Delete employee
 func (h *EmployeeHandler) QuitJob(w http.ResponseWriter, r *http.Request) {
 
-	email, _ := r.Context().Value("email").(string)
+	email := auth.ClaimsFromContext(r.Context()).Email
 
-	_, err := h.repo.GetByEmail(r.Context(), email)
+	before, err := h.repo.GetByEmail(r.Context(), email)
 	if err != nil {
 		http.Error(w, "not found", http.StatusNotFound)
 		return
@@ -291,6 +294,9 @@ func (h *EmployeeHandler) QuitJob(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	audit.Record(r.Context(), "employee.quit", "employee", before.ID.String(), before,
+		map[string]any{"role": "candidate", "jobid": nil})
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
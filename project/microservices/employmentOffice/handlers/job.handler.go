@@ -11,6 +11,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Bijelic03/eAdministration/project/microservices/common/audit"
+	"github.com/Bijelic03/eAdministration/project/microservices/common/auth"
+	"github.com/Bijelic03/eAdministration/project/microservices/common/credentials"
 	"github.com/Bijelic03/eAdministration/project/microservices/employmentOffice/repositories"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
@@ -44,27 +47,41 @@ type JobHandler struct {
 	jobAppsRepo   *repositories.JobApplicationRepository
 	candidateRepo *repositories.CandidateRepository
 	interviewRepo *repositories.InterviewRepository
+	tokens        *auth.TokenSource // service token za pozive university servisa
+	credentials   *credentials.Verifier
 }
 
-func NewJobHandler(repo *repositories.JobRepository, jobAppsRepo *repositories.JobApplicationRepository, candidateRepo *repositories.CandidateRepository, interviewRepo *repositories.InterviewRepository) *JobHandler {
-	return &JobHandler{repo: repo, jobAppsRepo: jobAppsRepo, candidateRepo: candidateRepo, interviewRepo: interviewRepo}
+func NewJobHandler(repo *repositories.JobRepository, jobAppsRepo *repositories.JobApplicationRepository, candidateRepo *repositories.CandidateRepository, interviewRepo *repositories.InterviewRepository, tokens *auth.TokenSource, credentials *credentials.Verifier) *JobHandler {
+	return &JobHandler{repo: repo, jobAppsRepo: jobAppsRepo, candidateRepo: candidateRepo, interviewRepo: interviewRepo, tokens: tokens, credentials: credentials}
 }
 
-// Create job
-func (h *JobHandler) CreateJob(w http.ResponseWriter, r *http.Request) {
-
-	role, _ := r.Context().Value("role").(string)
-
-	if role != "sszadmin" {
-		http.Error(w, "only sszadmin can create job", http.StatusForbidden)
-		return
+// apiKeyEmployer vraca poslodavca ciji API kljuc je poslao zahtjev; za
+// korisnicke tokene ok je false
+func apiKeyEmployer(r *http.Request) (employerID uuid.UUID, ok bool) {
+	c := auth.ClaimsFromContext(r.Context())
+	if c.Role != auth.EmployerRole {
+		return uuid.Nil, false
 	}
+	id, _ := uuid.Parse(c.EmployerID)
+	return id, true
+}
 
+// ownsJob provjerava da API kljuc poslodavca radi samo sa njegovim oglasima
+func ownsJob(r *http.Request, job *repositories.Job) bool {
+	employerID, ok := apiKeyEmployer(r)
+	return !ok || (job != nil && employerID != uuid.Nil && job.EmployerID == employerID)
+}
+
+// Create job
+func (h *JobHandler) CreateJob(w http.ResponseWriter, r *http.Request) {
 	var emp repositories.Job
 	if err := json.NewDecoder(r.Body).Decode(&emp); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if employerID, ok := apiKeyEmployer(r); ok {
+		emp.EmployerID = employerID
+	}
 
 	created, err := h.repo.Add(r.Context(), &emp)
 	if err != nil {
@@ -72,6 +89,8 @@ func (h *JobHandler) CreateJob(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	audit.Record(r.Context(), "job.create", "job", created.ID.String(), nil, created)
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(created)
 }
@@ -145,40 +164,34 @@ func (h *JobHandler) GetAllJobs(w http.ResponseWriter, r *http.Request) {
 
 // Update job
 func (h *JobHandler) UpdateJob(w http.ResponseWriter, r *http.Request) {
-
-	role, _ := r.Context().Value("role").(string)
-
-	if role != "sszadmin" {
-		http.Error(w, "only sszadmin can update job", http.StatusForbidden)
-		return
-	}
-
 	var emp repositories.Job
 	if err := json.NewDecoder(r.Body).Decode(&emp); err != nil {
 		http.Error(w, "invalid request", http.StatusBadRequest)
 		return
 	}
 
+	before, _ := h.repo.GetByID(r.Context(), emp.ID)
+	if !ownsJob(r, before) {
+		http.Error(w, "job belongs to another employer", http.StatusForbidden)
+		return
+	}
+	if employerID, ok := apiKeyEmployer(r); ok {
+		emp.EmployerID = employerID
+	}
 	updated, err := h.repo.Update(r.Context(), &emp)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	audit.Record(r.Context(), "job.update", "job", updated.ID.String(), before, updated)
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(updated)
 }
 
 // Delete job
 func (h *JobHandler) DeleteJob(w http.ResponseWriter, r *http.Request) {
-
-	role, _ := r.Context().Value("role").(string)
-
-	if role != "sszadmin" {
-		http.Error(w, "only sszadmin can delete job", http.StatusForbidden)
-		return
-	}
-
 	vars := mux.Vars(r)
 	idStr := vars["id"]
 	id, err := uuid.Parse(idStr)
@@ -187,23 +200,22 @@ func (h *JobHandler) DeleteJob(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before, _ := h.repo.GetByID(r.Context(), id)
+	if !ownsJob(r, before) {
+		http.Error(w, "job belongs to another employer", http.StatusForbidden)
+		return
+	}
 	if err := h.repo.Delete(r.Context(), id); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	audit.Record(r.Context(), "job.delete", "job", id.String(), before, nil)
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
 func (h *JobHandler) ApplyForJob(w http.ResponseWriter, r *http.Request) {
-
-	role, _ := r.Context().Value("role").(string)
-
-	if role != "candidate" {
-		http.Error(w, "only candidates can apply for job", http.StatusForbidden)
-		return
-	}
-
 	vars := mux.Vars(r)
 	idStr := vars["id"]
 	candidateEmail := vars["email"]
@@ -232,54 +244,75 @@ func (h *JobHandler) ApplyForJob(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		
-		client := &http.Client{Timeout: 5 * time.Second}
-		req, err := http.NewRequest(
-			"GET",
-			fmt.Sprintf("http://university:8081/api/v1/university/students/verify-graduation/%s", *candidate.IndexNo),
-			nil,
-		)
-		if err != nil {
-			http.Error(w, "failed to build request:", http.StatusNotFound)
-			return
+		// diploma predata uz prijavu provjerava se potpisom, bez poziva univerziteta
+		var payload struct {
+			Credential string `json:"credential"`
 		}
-		req.Header.Set("Content-Type", "application/json")
-		if authHeader := r.Header.Get("Authorization"); authHeader != "" {
-			req.Header.Set("Authorization", authHeader)
+		if r.Body != nil {
+			json.NewDecoder(r.Body).Decode(&payload)
 		}
+		if payload.Credential != "" {
+			claims, err := h.credentials.Verify(strings.TrimSpace(payload.Credential))
+			if err != nil {
+				http.Error(w, "Verifikacija nije uspjesna - "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			if claims.Credential.IndexNo != *candidate.IndexNo {
+				http.Error(w, "Verifikacija nije uspjesna - diploma ne pripada kandidatu", http.StatusBadRequest)
+				return
+			}
+		} else {
+			client := &http.Client{Timeout: 5 * time.Second}
+			req, err := http.NewRequestWithContext(
+				r.Context(),
+				"GET",
+				fmt.Sprintf("http://university:8081/api/v1/university/internal/students/verify-graduation/%s", *candidate.IndexNo),
+				nil,
+			)
+			if err != nil {
+				http.Error(w, "failed to build request:", http.StatusNotFound)
+				return
+			}
+			req.Header.Set("Content-Type", "application/json")
+			if err := h.tokens.AuthorizeRequest(req); err != nil {
+				http.Error(w, "service token unavailable", http.StatusServiceUnavailable)
+				return
+			}
 
-		resp, err := client.Do(req)
-		if err != nil {
-			http.Error(w, "university service unreachable:", http.StatusNotFound)
-			return
-		}
-		defer resp.Body.Close()
+			resp, err := client.Do(req)
+			if err != nil {
+				http.Error(w, "university service unreachable:", http.StatusNotFound)
+				return
+			}
+			defer resp.Body.Close()
 
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			http.Error(w, "failed to read response body:", http.StatusNotFound)
-			return
-		}
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				http.Error(w, "failed to read response body:", http.StatusNotFound)
+				return
+			}
 
-		// Dekodiranje u mapu
-		var stud map[string]interface{}
-		if err := json.Unmarshal(body, &stud); err != nil {
-			http.Error(w, "failed to decode student response:", http.StatusNotFound)
-			return
-		}
+			// Dekodiranje u mapu
+			var stud map[string]interface{}
+			if err := json.Unmarshal(body, &stud); err != nil {
+				http.Error(w, "failed to decode student response:", http.StatusNotFound)
+				return
+			}
 
-		graduated := false
-		if statusVal, ok := stud["status"]; ok {
-			switch status := statusVal.(type) {
-			case bool:
-				graduated = status
-			case string:
-				graduated = strings.ToUpper(status) == "TRUE" || strings.ToUpper(status) == "GRADUATED"
+			graduated := false
+			if statusVal, ok := stud["status"]; ok {
+				switch status := statusVal.(type) {
+				case bool:
+					graduated = status
+				case string:
+					graduated = strings.ToUpper(status) == "TRUE" || strings.ToUpper(status) == "GRADUATED"
+				}
 			}
-		}
 
-		if !graduated {
-			http.Error(w, "Verifikacija nije uspjesna - nemate diplomu", http.StatusNotFound)
-			return
+			if !graduated {
+				http.Error(w, "Verifikacija nije uspjesna - nemate diplomu", http.StatusNotFound)
+				return
+			}
 		}
 			}
 
@@ -304,6 +337,8 @@ func (h *JobHandler) ApplyForJob(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	audit.Record(r.Context(), "job.apply", "job_application", created.ID.String(), nil, created)
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(created)
 }
@@ -327,7 +362,17 @@ func (h *JobHandler) GetAllJobApplications(w http.ResponseWriter, r *http.Reques
 		}
 	}
 
-	jobs, totalItems, err := h.jobAppsRepo.GetAllJobApplications(r.Context(), page, limit)
+	// API kljuc poslodavca vidi samo prijave na svoje oglase
+	var employer *uuid.UUID
+	if employerID, ok := apiKeyEmployer(r); ok {
+		if employerID == uuid.Nil {
+			http.Error(w, "api key is not bound to an employer", http.StatusForbidden)
+			return
+		}
+		employer = &employerID
+	}
+
+	jobs, totalItems, err := h.jobAppsRepo.GetAllJobApplications(r.Context(), page, limit, employer)
 	if err != nil {
 		resp := JobApplicationsListResponse{
 			JobApplications: nil,
@@ -356,14 +401,6 @@ func (h *JobHandler) GetAllJobApplications(w http.ResponseWriter, r *http.Reques
 }
 
 func (h *JobHandler) GetCandidatesForJob(w http.ResponseWriter, r *http.Request) {
-
-	role, _ := r.Context().Value("role").(string)
-
-	if role != "sszadmin" {
-		http.Error(w, "only sszadmin can get candidates for job", http.StatusForbidden)
-		return
-	}
-
 	vars := mux.Vars(r)
 	jobIDStr := vars["id"]
 	jobID, err := uuid.Parse(jobIDStr)
@@ -371,6 +408,13 @@ func (h *JobHandler) GetCandidatesForJob(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "invalid job id", http.StatusBadRequest)
 		return
 	}
+	if _, ok := apiKeyEmployer(r); ok {
+		job, _ := h.repo.GetByID(r.Context(), jobID)
+		if !ownsJob(r, job) {
+			http.Error(w, "job belongs to another employer", http.StatusForbidden)
+			return
+		}
+	}
 
 	indices, err := h.jobAppsRepo.GetStudentIndicesByJobID(r.Context(), jobID)
 	if err != nil {
@@ -387,9 +431,10 @@ func (h *JobHandler) GetCandidatesForJob(w http.ResponseWriter, r *http.Request)
 	body, _ := json.Marshal(reqBody)
 
 	client := &http.Client{Timeout: 5 * time.Second}
-	req, err := http.NewRequest(
+	req, err := http.NewRequestWithContext(
+		r.Context(),
 		"POST",
-		"http://university:8081/api/v1/university/students/avg-grades",
+		"http://university:8081/api/v1/university/internal/students/avg-grades",
 		bytes.NewBuffer(body),
 	)
 	if err != nil {
@@ -398,8 +443,9 @@ func (h *JobHandler) GetCandidatesForJob(w http.ResponseWriter, r *http.Request)
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	if authHeader := r.Header.Get("Authorization"); authHeader != "" {
-		req.Header.Set("Authorization", authHeader)
+	if err := h.tokens.AuthorizeRequest(req); err != nil {
+		http.Error(w, "service token unavailable", http.StatusServiceUnavailable)
+		return
 	}
 
 	resp, err := client.Do(req)
@@ -421,14 +467,6 @@ func (h *JobHandler) GetCandidatesForJob(w http.ResponseWriter, r *http.Request)
 
 // Delete jobapplication
 func (h *JobHandler) DeleteJobApplication(w http.ResponseWriter, r *http.Request) {
-
-	role, _ := r.Context().Value("role").(string)
-
-	if role != "sszadmin" && role != "candidate" {
-		http.Error(w, "only sszadmin and candidates can delete job application", http.StatusForbidden)
-		return
-	}
-
 	vars := mux.Vars(r)
 	idStr := vars["id"]
 	id, err := uuid.Parse(idStr)
@@ -442,19 +480,13 @@ func (h *JobHandler) DeleteJobApplication(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	audit.Record(r.Context(), "job_application.delete", "job_application", id.String(), nil, nil)
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
 // Schedule interview
 func (h *JobHandler) ScheduleInterview(w http.ResponseWriter, r *http.Request) {
-
-	role, _ := r.Context().Value("role").(string)
-
-	if role != "sszadmin" {
-		http.Error(w, "only sszadmin can schedule interview", http.StatusForbidden)
-		return
-	}
-
 	var emp repositories.Interview
 	if err := json.NewDecoder(r.Body).Decode(&emp); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -486,6 +518,8 @@ func (h *JobHandler) ScheduleInterview(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	audit.Record(r.Context(), "interview.schedule", "interview", created.ID.String(), nil, created)
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(created)
 }
@@ -509,8 +543,8 @@ func (h *JobHandler) GetAllInterviews(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	role, _ := r.Context().Value("role").(string)
-	email, _ := r.Context().Value("email").(string)
+	role := auth.ClaimsFromContext(r.Context()).Role
+	email := auth.ClaimsFromContext(r.Context()).Email
 
 	if role == "sszadmin" {
 		ints, totalItems, err := h.interviewRepo.GetAllInterviews(r.Context(), page, limit)
@@ -593,6 +627,8 @@ func (h *JobHandler) DeleteInterview(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	audit.Record(r.Context(), "interview.delete", "interview", id.String(), nil, nil)
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -611,6 +647,8 @@ func (h *JobHandler) Odbij(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	audit.Record(r.Context(), "interview.reject", "candidate", id.String(), nil, nil)
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -632,11 +670,15 @@ func (h *JobHandler) Zaposli(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before, _ := h.candidateRepo.GetByID(r.Context(), id)
 	if err := h.interviewRepo.Zaposli(r.Context(), id, jobid); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	audit.Record(r.Context(), "candidate.hire", "candidate", id.String(), before,
+		map[string]any{"role": "employee", "jobid": jobid})
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -657,5 +699,7 @@ func (h *JobHandler) AcceptInterview(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	audit.Record(r.Context(), "interview.accept", "interview", id.String(), nil, map[string]any{"accepted": true})
+
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -2,15 +2,15 @@ package main
 
 import (
 	"context"
-	"encoding/json"
-	"io"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
-	"strings"
 	"time"
 
+	"github.com/Bijelic03/eAdministration/project/microservices/common/audit"
+	"github.com/Bijelic03/eAdministration/project/microservices/common/auth"
+	"github.com/Bijelic03/eAdministration/project/microservices/common/credentials"
 	"github.com/Bijelic03/eAdministration/project/microservices/employmentOffice/config"
 	"github.com/Bijelic03/eAdministration/project/microservices/employmentOffice/db"
 	"github.com/Bijelic03/eAdministration/project/microservices/employmentOffice/handlers"
@@ -20,29 +20,37 @@ import (
 	"github.com/gorilla/mux"
 )
 
-const authServiceURL = "http://auth:8083/api/v1/auth/verify"
-
-type VerifyResponse struct {
-	Ok    bool   `json:"ok"`
-	Email string `json:"email,omitempty"`
-	Role  string `json:"role,omitempty"`
-	Error string `json:"error,omitempty"`
-}
-
 func main() {
 	cfg := config.GetConfig()
 
 	conn, err := db.Connect(cfg.DatabaseURL())
 	handleErr(err)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// tokeni se verifikuju lokalno, kljucevi se kesiraju iz auth servisa; lista
+	// opozvanih tokena se osvjezava svake 2s, a bez auth servisa tokeni se odbijaju
+	verifier := auth.NewVerifier(cfg.AuthURL, "employmentOffice", 2*time.Second)
+	verifier.Start(ctx)
+	authMiddleware := verifier.Middleware
+
+	// service token za pozive internih ruta university servisa
+	serviceTokens := auth.NewTokenSource(cfg.AuthURL, cfg.ServiceClientID, cfg.ServiceClientSecret)
+
+	// poslodavci pristupaju oglasima API kljucem (X-API-Key), pored JWT-a
+	verifier.EnableAPIKeys(serviceTokens)
+
+	// diplome se provjeravaju lokalno, javni kljucevi se kesiraju iz university servisa
+	credentialVerifier := credentials.NewVerifier(cfg.UniversityURL, 5*time.Minute)
+	credentialVerifier.Start(ctx)
+
 	address := ":8082"
 
 	cors := handler.CORS(
 		handler.AllowedMethods([]string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}),
-		handler.AllowedHeaders([]string{"Authorization", "Content-Type"}),
+		handler.AllowedHeaders([]string{"Authorization", auth.APIKeyHeader, "Content-Type", audit.RequestIDHeader}),
+		handler.ExposedHeaders([]string{audit.RequestIDHeader}),
 		handler.AllowCredentials(),
 		handler.AllowedOrigins([]string{"*"}),
 	)
@@ -51,11 +59,22 @@ func main() {
 	router := mux.NewRouter()
 	router.Use(mux.CORSMethodMiddleware(router))
 
+	// akter u audit logu je korisnik iz verifikovanog tokena
+	auditLog := audit.NewLogger(conn, "employmentOffice", func(ctx context.Context) audit.Actor {
+		c := auth.ClaimsFromContext(ctx)
+		if c.Actor != nil {
+			// impersonacija: akciju izvrsava administrator
+			return audit.Actor{ID: c.Actor.ID, Email: c.Actor.Email, Role: c.Actor.Role}
+		}
+		return audit.Actor{ID: c.UserID, Email: c.Email, Role: c.Role}
+	})
+	router.Use(auditLog.Middleware)
+
 	api := router.PathPrefix("/api/v1/employmentOffice").Subrouter()
 
 	// /api/v1/employmentOffice/employees
 	employeeRepository := repositories.NewEmployeeRepository(conn)
-	employeeHandler := handlers.NewEmployeeHandler(employeeRepository)
+	employeeHandler := handlers.NewEmployeeHandler(employeeRepository, serviceTokens)
 	employees := api.PathPrefix("/employees").Subrouter()
 	employees.Handle("", authMiddleware(http.HandlerFunc(employeeHandler.CreateEmployee))).Methods("POST")
 	employees.Handle("", authMiddleware(http.HandlerFunc(employeeHandler.GetAllEmployees))).Methods("GET")
@@ -67,9 +86,13 @@ func main() {
 	employees.Handle("/professors/all", authMiddleware(http.HandlerFunc(employeeHandler.GetAllProfessorsFromOtherService))).Methods("GET")
 	employees.Handle("/employed/{indexno}", authMiddleware(http.HandlerFunc(employeeHandler.IsEmployedByIndex))).Methods("GET")
 
+	// /api/v1/employmentOffice/internal - samo service tokeni (university)
+	internal := api.PathPrefix("/internal").Subrouter()
+	internal.Handle("/employees/employed/{indexno}", authMiddleware(http.HandlerFunc(employeeHandler.IsEmployedByIndex))).Methods("GET")
+
 	// /api/v1/employmentOffice/candiates
 	candidateRepository := repositories.NewCandidateRepository(conn)
-	candidateHandler := handlers.NewCandidateHandler(candidateRepository)
+	candidateHandler := handlers.NewCandidateHandler(candidateRepository, serviceTokens)
 	candidates := api.PathPrefix("/candidates").Subrouter()
 	candidates.Handle("", authMiddleware(http.HandlerFunc(candidateHandler.CreateCandidate))).Methods("POST")
 	candidates.Handle("", authMiddleware(http.HandlerFunc(candidateHandler.GetAllCandidates))).Methods("GET")
@@ -83,7 +106,7 @@ func main() {
 	jobRepository := repositories.NewJobRepository(conn)
 	jobAppsRepo := repositories.NewJobApplicationRepository(conn)
 	interviewRepo := repositories.NewJobInterviewRepository(conn)
-	jobHandler := handlers.NewJobHandler(jobRepository, jobAppsRepo, candidateRepository, interviewRepo)
+	jobHandler := handlers.NewJobHandler(jobRepository, jobAppsRepo, candidateRepository, interviewRepo, serviceTokens, credentialVerifier)
 	jobs := api.PathPrefix("/jobs").Subrouter()
 	jobapps := api.PathPrefix("/jobapplications").Subrouter()
 	jobinterviews := api.PathPrefix("/interviews").Subrouter()
@@ -103,6 +126,9 @@ func main() {
 	jobinterviews.Handle("/{id}/odbij", authMiddleware(http.HandlerFunc(jobHandler.Odbij))).Methods("DELETE")
 	jobinterviews.Handle("/{candidateid}/zaposli/{jobid}", authMiddleware(http.HandlerFunc(jobHandler.Zaposli))).Methods("PATCH")
 
+	credentialHandler := handlers.NewCredentialHandler(credentialVerifier)
+	api.Handle("/credentials/verify", authMiddleware(http.HandlerFunc(credentialHandler.VerifyCredential))).Methods("POST")
+
 	server := &http.Server{
 		Handler: cors(router),
 		Addr:    address,
@@ -139,54 +165,3 @@ func handleErr(err error) {
 	}
 
 }
-
-func authMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
-			http.Error(w, `{"error":"Missing or invalid Authorization header"}`, http.StatusUnauthorized)
-			return
-		}
-		token := strings.TrimPrefix(authHeader, "Bearer ")
-
-		client := &http.Client{Timeout: 5 * time.Second}
-		req, err := http.NewRequest("GET", authServiceURL, nil)
-		if err != nil {
-			http.Error(w, `{"error":"internal error"}`, http.StatusInternalServerError)
-			return
-		}
-		req.Header.Set("Authorization", "Bearer "+token)
-
-		resp, err := client.Do(req)
-		if err != nil {
-			msg := map[string]string{"error": "auth service unreachable: " + err.Error()}
-			b, _ := json.Marshal(msg)
-			http.Error(w, string(b), http.StatusUnauthorized)
-			return
-		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode != http.StatusOK {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(resp.StatusCode)
-			io.Copy(w, resp.Body)
-			return
-		}
-
-		var verify VerifyResponse
-		if err := json.NewDecoder(resp.Body).Decode(&verify); err != nil {
-			http.Error(w, `{"error":"invalid response from auth service"}`, http.StatusUnauthorized)
-			return
-		}
-		if !verify.Ok {
-			http.Error(w, `{"error":"token not valid"}`, http.StatusUnauthorized)
-			return
-		}
-
-		ctx := context.WithValue(r.Context(), "email", verify.Email)
-		ctx = context.WithValue(ctx, "role", verify.Role)
-		r = r.WithContext(ctx)
-
-		next.ServeHTTP(w, r)
-	})
-}
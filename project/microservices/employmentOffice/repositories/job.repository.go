@@ -344,7 +344,9 @@ func (r *JobApplicationRepository) GetJobApplicationByCandidateIDAndByJobID(ctx
 }
 
 // Get all job apps (paginated)
-func (r *JobApplicationRepository) GetAllJobApplications(ctx context.Context, page, limit int) ([]*JobApplication, int, error) {
+// GetAllJobApplications vraca prijave na oglase; ako je employerID zadat, samo
+// prijave na oglase tog poslodavca (API kljuc poslodavca)
+func (r *JobApplicationRepository) GetAllJobApplications(ctx context.Context, page, limit int, employerID *uuid.UUID) ([]*JobApplication, int, error) {
 	if page < 1 {
 		page = 1
 	}
@@ -353,12 +355,14 @@ func (r *JobApplicationRepository) GetAllJobApplications(ctx context.Context, pa
 	}
 	offset := (page - 1) * limit
 
+	const byEmployer = `($1::uuid IS NULL OR jobid IN (SELECT id FROM jobs WHERE employerid = $1))`
 	query := `SELECT id, jobid, candidateid
 	          FROM jobapplications
+	          WHERE ` + byEmployer + `
 	          ORDER BY jobid
-	          LIMIT $1 OFFSET $2`
+	          LIMIT $2 OFFSET $3`
 
-	rows, err := r.db.Query(ctx, query, limit, offset)
+	rows, err := r.db.Query(ctx, query, employerID, limit, offset)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -379,7 +383,7 @@ func (r *JobApplicationRepository) GetAllJobApplications(ctx context.Context, pa
 
 	// total count
 	var totalItems int
-	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM jobapplications`).Scan(&totalItems); err != nil {
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM jobapplications WHERE `+byEmployer, employerID).Scan(&totalItems); err != nil {
 		return nil, 0, err
 	}
 
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"time"
 )
 
 type Config struct {
@@ -13,18 +14,47 @@ type Config struct {
 	DBUser string
 	DBName string
 	DBPass string
+	// bazni URL auth servisa (JWKS i lista opozvanih tokena)
+	AuthURL string
+	// kredencijali servisa za service tokene (pozivi drugih servisa)
+	ServiceClientID     string
+	ServiceClientSecret string
+	// pozivni linkovi za studente upisane masovnim importom
+	InvitationURL string
+	InvitationTTL time.Duration
+	// javna adresa za provjeru prepisa ocjena (stampa se na PDF-u)
+	TranscriptVerifyURL string
+	// PEM (PKCS#8) Ed25519 kljuc za potpisivanje diploma
+	CredentialSigningKeyFile string
+	// koliko prije termina ispita se zatvaraju prijava i odjava ispita
+	ExamRegistrationCutoff time.Duration
+	ExamWithdrawalCutoff   time.Duration
 }
 
 func GetConfig() Config {
 
 	return Config{
-		Host:   os.Getenv("HOST_PORT"),
-		Port:   os.Getenv("UNIVERSITY_PORT"),
-		DBHost: os.Getenv("DB_HOST"),
-		DBUser: os.Getenv("DB_USER"),
-		DBPort: os.Getenv("DB_PORT"),
-		DBPass: os.Getenv("DB_PASS"),
-		DBName: os.Getenv("DB_NAME"),
+		Host:    os.Getenv("HOST_PORT"),
+		Port:    os.Getenv("UNIVERSITY_PORT"),
+		DBHost:  os.Getenv("DB_HOST"),
+		DBUser:  os.Getenv("DB_USER"),
+		DBPort:  os.Getenv("DB_PORT"),
+		DBPass:  os.Getenv("DB_PASS"),
+		DBName:  os.Getenv("DB_NAME"),
+		AuthURL: getEnv("AUTH_URL", "http://auth:8083"),
+
+		ServiceClientID:     getEnv("SERVICE_CLIENT_ID", "university"),
+		ServiceClientSecret: os.Getenv("SERVICE_CLIENT_SECRET"),
+
+		InvitationURL: getEnv("INVITATION_URL", "http://localhost:3000/auth/reset-password"),
+		InvitationTTL: getDuration("INVITATION_TTL", 7*24*time.Hour),
+
+		TranscriptVerifyURL: getEnv("TRANSCRIPT_VERIFY_URL", "http://localhost:8081/api/v1/university/transcripts/verify"),
+
+		CredentialSigningKeyFile: os.Getenv("CREDENTIAL_SIGNING_KEY_FILE"),
+
+		ExamRegistrationCutoff: getDuration("EXAM_REGISTRATION_CUTOFF", 72*time.Hour),
+		ExamWithdrawalCutoff:   getDuration("EXAM_WITHDRAWAL_CUTOFF", 24*time.Hour),
 	}
 }
 
@@ -34,3 +64,18 @@ func (c Config) DatabaseURL() string {
 		c.DBUser, c.DBPass, c.DBHost, c.DBPort, c.DBName,
 	)
 }
+
+func getEnv(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func getDuration(key string, def time.Duration) time.Duration {
+	d, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || d <= 0 {
+		return def
+	}
+	return d
+}
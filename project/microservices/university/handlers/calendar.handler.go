@@ -0,0 +1,503 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Bijelic03/eAdministration/project/microservices/common/audit"
+	"github.com/Bijelic03/eAdministration/project/microservices/university/repositories"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+const dateLayout = "2006-01-02"
+
+type CalendarHandler struct {
+	repo     *repositories.CalendarRepository
+	examRepo *repositories.ExamRepository
+}
+
+func NewCalendarHandler(repo *repositories.CalendarRepository, examRepo *repositories.ExamRepository) *CalendarHandler {
+	return &CalendarHandler{repo: repo, examRepo: examRepo}
+}
+
+// calendarRequest je tijelo zahtjeva za skolsku godinu, semestar i ispitni
+// rok; datumi su YYYY-MM-DD, a rokovi za prijavu RFC 3339
+type calendarRequest struct {
+	Name                 string    `json:"name"`
+	StartsOn             string    `json:"startson"`
+	EndsOn               string    `json:"endson"`
+	RegistrationOpensAt  time.Time `json:"registrationopensat"`
+	RegistrationClosesAt time.Time `json:"registrationclosesat"`
+}
+
+// dates parsira i provjerava naziv i period [StartsOn, EndsOn]
+func (req *calendarRequest) dates() (time.Time, time.Time, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return time.Time{}, time.Time{}, errors.New("name is required")
+	}
+	start, err := time.Parse(dateLayout, req.StartsOn)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("startson must be a date (YYYY-MM-DD)")
+	}
+	end, err := time.Parse(dateLayout, req.EndsOn)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("endson must be a date (YYYY-MM-DD)")
+	}
+	if end.Before(start) {
+		return time.Time{}, time.Time{}, errors.New("endson must not be before startson")
+	}
+	return start, end, nil
+}
+
+// window provjerava rok za prijavu
+func (req *calendarRequest) window() error {
+	if req.RegistrationOpensAt.IsZero() || req.RegistrationClosesAt.IsZero() {
+		return errors.New("registrationopensat and registrationclosesat are required")
+	}
+	if !req.RegistrationOpensAt.Before(req.RegistrationClosesAt) {
+		return errors.New("registration must open before it closes")
+	}
+	return nil
+}
+
+func withinYear(y *repositories.AcademicYear, start, end time.Time) error {
+	if start.Before(y.StartsOn) || end.After(y.EndsOn) {
+		return fmt.Errorf("dates must fall within academic year %s (%s - %s)",
+			y.Name, y.StartsOn.Format(dateLayout), y.EndsOn.Format(dateLayout))
+	}
+	return nil
+}
+
+func writeCalendarErr(w http.ResponseWriter, err error, what string) {
+	switch {
+	case errors.Is(err, repositories.ErrCalendarNotFound):
+		http.Error(w, what+" not found", http.StatusNotFound)
+	case errors.Is(err, repositories.ErrCalendarConflict):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "failed to save "+what, http.StatusInternalServerError)
+	}
+}
+
+func writeJSONStatus(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// POST /api/v1/university/academic-years
+func (h *CalendarHandler) CreateAcademicYear(w http.ResponseWriter, r *http.Request) {
+	var req calendarRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		return
+	}
+	start, end, err := req.dates()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	created, err := h.repo.CreateYear(r.Context(), &repositories.AcademicYear{Name: req.Name, StartsOn: start, EndsOn: end})
+	if err != nil {
+		if errors.Is(err, repositories.ErrCalendarConflict) {
+			http.Error(w, "academic year already exists", http.StatusConflict)
+			return
+		}
+		writeCalendarErr(w, err, "academic year")
+		return
+	}
+
+	audit.Record(r.Context(), "calendar.year.create", "academic_year", created.ID.String(), nil, created)
+	writeJSONStatus(w, http.StatusCreated, created)
+}
+
+// GET /api/v1/university/academic-years
+func (h *CalendarHandler) GetAcademicYears(w http.ResponseWriter, r *http.Request) {
+	years, err := h.repo.ListYears(r.Context())
+	if err != nil {
+		http.Error(w, "failed to list academic years", http.StatusInternalServerError)
+		return
+	}
+	writeJSONStatus(w, http.StatusOK, map[string]any{"academicyears": years})
+}
+
+// GET /api/v1/university/academic-years/{id}
+func (h *CalendarHandler) GetAcademicYear(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	y, err := h.repo.GetYear(r.Context(), id)
+	if err != nil {
+		writeCalendarErr(w, err, "academic year")
+		return
+	}
+	writeJSONStatus(w, http.StatusOK, y)
+}
+
+// POST /api/v1/university/academic-years/{id}/semesters
+func (h *CalendarHandler) CreateSemester(w http.ResponseWriter, r *http.Request) {
+	s, year, ok := h.decodeEntry(w, r)
+	if !ok {
+		return
+	}
+
+	created, err := h.repo.CreateSemester(r.Context(), &repositories.Semester{
+		AcademicYearID: year.ID, Name: s.Name, StartsOn: s.StartsOn, EndsOn: s.EndsOn,
+		RegistrationOpensAt: s.RegistrationOpensAt, RegistrationClosesAt: s.RegistrationClosesAt,
+	})
+	if err != nil {
+		writeCalendarErr(w, err, "semester")
+		return
+	}
+
+	audit.Record(r.Context(), "calendar.semester.create", "semester", created.ID.String(), nil, created)
+	writeJSONStatus(w, http.StatusCreated, created)
+}
+
+// PUT /api/v1/university/semesters/{id}
+func (h *CalendarHandler) UpdateSemester(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	before, err := h.repo.GetSemester(r.Context(), id)
+	if err != nil {
+		writeCalendarErr(w, err, "semester")
+		return
+	}
+	s, _, ok := h.decodeEntryFor(w, r, before.AcademicYearID)
+	if !ok {
+		return
+	}
+
+	updated, err := h.repo.UpdateSemester(r.Context(), &repositories.Semester{
+		ID: id, Name: s.Name, StartsOn: s.StartsOn, EndsOn: s.EndsOn,
+		RegistrationOpensAt: s.RegistrationOpensAt, RegistrationClosesAt: s.RegistrationClosesAt,
+	})
+	if err != nil {
+		writeCalendarErr(w, err, "semester")
+		return
+	}
+
+	audit.Record(r.Context(), "calendar.semester.update", "semester", id.String(), before, updated)
+	writeJSONStatus(w, http.StatusOK, updated)
+}
+
+// DELETE /api/v1/university/semesters/{id}
+func (h *CalendarHandler) DeleteSemester(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	before, _ := h.repo.GetSemester(r.Context(), id)
+	if err := h.repo.DeleteSemester(r.Context(), id); err != nil {
+		writeCalendarErr(w, err, "semester")
+		return
+	}
+
+	audit.Record(r.Context(), "calendar.semester.delete", "semester", id.String(), before, nil)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST /api/v1/university/academic-years/{id}/exam-periods
+func (h *CalendarHandler) CreateExamPeriod(w http.ResponseWriter, r *http.Request) {
+	p, year, ok := h.decodeEntry(w, r)
+	if !ok {
+		return
+	}
+
+	created, err := h.repo.CreateExamPeriod(r.Context(), &repositories.ExamPeriod{
+		AcademicYearID: year.ID, Name: p.Name, StartsOn: p.StartsOn, EndsOn: p.EndsOn,
+		RegistrationOpensAt: p.RegistrationOpensAt, RegistrationClosesAt: p.RegistrationClosesAt,
+	})
+	if err != nil {
+		writeCalendarErr(w, err, "exam period")
+		return
+	}
+
+	audit.Record(r.Context(), "calendar.examperiod.create", "exam_period", created.ID.String(), nil, created)
+	writeJSONStatus(w, http.StatusCreated, created)
+}
+
+// PUT /api/v1/university/exam-periods/{id}
+func (h *CalendarHandler) UpdateExamPeriod(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	before, err := h.repo.GetExamPeriod(r.Context(), id)
+	if err != nil {
+		writeCalendarErr(w, err, "exam period")
+		return
+	}
+	p, _, ok := h.decodeEntryFor(w, r, before.AcademicYearID)
+	if !ok {
+		return
+	}
+
+	updated, err := h.repo.UpdateExamPeriod(r.Context(), &repositories.ExamPeriod{
+		ID: id, Name: p.Name, StartsOn: p.StartsOn, EndsOn: p.EndsOn,
+		RegistrationOpensAt: p.RegistrationOpensAt, RegistrationClosesAt: p.RegistrationClosesAt,
+	})
+	if err != nil {
+		writeCalendarErr(w, err, "exam period")
+		return
+	}
+
+	audit.Record(r.Context(), "calendar.examperiod.update", "exam_period", id.String(), before, updated)
+	writeJSONStatus(w, http.StatusOK, updated)
+}
+
+// DELETE /api/v1/university/exam-periods/{id}
+func (h *CalendarHandler) DeleteExamPeriod(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	before, _ := h.repo.GetExamPeriod(r.Context(), id)
+	if err := h.repo.DeleteExamPeriod(r.Context(), id); err != nil {
+		writeCalendarErr(w, err, "exam period")
+		return
+	}
+
+	audit.Record(r.Context(), "calendar.examperiod.delete", "exam_period", id.String(), before, nil)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /api/v1/university/calendar/current
+// Tekuca skolska godina, semestar i ispitni rok, uz stanje rokova za prijavu
+func (h *CalendarHandler) GetCurrent(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	resp := map[string]any{"now": now}
+
+	year, err := h.repo.CurrentYear(r.Context(), now)
+	if err != nil && !errors.Is(err, repositories.ErrCalendarNotFound) {
+		http.Error(w, "failed to load academic calendar", http.StatusInternalServerError)
+		return
+	}
+	resp["academicyear"] = year
+
+	semester, err := h.repo.CurrentSemester(r.Context(), now)
+	if err != nil && !errors.Is(err, repositories.ErrCalendarNotFound) {
+		http.Error(w, "failed to load academic calendar", http.StatusInternalServerError)
+		return
+	}
+	resp["semester"] = semester
+
+	open, err := h.repo.OpenSemester(r.Context(), now)
+	if err != nil && !errors.Is(err, repositories.ErrCalendarNotFound) {
+		http.Error(w, "failed to load academic calendar", http.StatusInternalServerError)
+		return
+	}
+	courseReg := map[string]any{"open": open != nil, "semester": open}
+	if open == nil {
+		if next, err := h.repo.NextSemester(r.Context(), now); err == nil {
+			courseReg["semester"] = next
+			courseReg["opensat"] = next.RegistrationOpensAt
+		}
+	}
+	resp["courseregistration"] = courseReg
+
+	period, err := h.repo.CurrentExamPeriod(r.Context(), now)
+	if err != nil && !errors.Is(err, repositories.ErrCalendarNotFound) {
+		http.Error(w, "failed to load academic calendar", http.StatusInternalServerError)
+		return
+	}
+	resp["examperiod"] = period
+	resp["examregistration"] = map[string]any{"open": period != nil && period.RegistrationOpen(now)}
+
+	writeJSONStatus(w, http.StatusOK, resp)
+}
+
+// GET /api/v1/university/exam-periods/{id}/exams
+// Ispiti roka; id "current" oznacava tekuci (ili sljedeci) ispitni rok
+func (h *CalendarHandler) GetExamPeriodExams(w http.ResponseWriter, r *http.Request) {
+	var (
+		period *repositories.ExamPeriod
+		err    error
+	)
+	if idStr := mux.Vars(r)["id"]; idStr == "current" {
+		period, err = h.repo.CurrentExamPeriod(r.Context(), time.Now())
+	} else {
+		id, perr := uuid.Parse(idStr)
+		if perr != nil {
+			http.Error(w, "invalid id", http.StatusBadRequest)
+			return
+		}
+		period, err = h.repo.GetExamPeriod(r.Context(), id)
+	}
+	if err != nil {
+		if errors.Is(err, repositories.ErrCalendarNotFound) {
+			http.Error(w, "exam period not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "failed to load exam period", http.StatusInternalServerError)
+		return
+	}
+
+	exams, err := h.examRepo.GetByPeriod(r.Context(), period.ID)
+	if err != nil {
+		http.Error(w, "failed to list exams", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSONStatus(w, http.StatusOK, map[string]any{
+		"examperiod":       period,
+		"registrationopen": period.RegistrationOpen(time.Now()),
+		"exams":            exams,
+	})
+}
+
+// calendarEntry je provjeren semestar ili ispitni rok iz zahtjeva
+type calendarEntry struct {
+	Name                 string
+	StartsOn             time.Time
+	EndsOn               time.Time
+	RegistrationOpensAt  time.Time
+	RegistrationClosesAt time.Time
+}
+
+// decodeEntry cita semestar/ispitni rok za skolsku godinu iz {id}
+func (h *CalendarHandler) decodeEntry(w http.ResponseWriter, r *http.Request) (*calendarEntry, *repositories.AcademicYear, bool) {
+	yearID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid academic year id", http.StatusBadRequest)
+		return nil, nil, false
+	}
+	return h.decodeEntryFor(w, r, yearID)
+}
+
+func (h *CalendarHandler) decodeEntryFor(w http.ResponseWriter, r *http.Request, yearID uuid.UUID) (*calendarEntry, *repositories.AcademicYear, bool) {
+	year, err := h.repo.GetYear(r.Context(), yearID)
+	if err != nil {
+		writeCalendarErr(w, err, "academic year")
+		return nil, nil, false
+	}
+
+	var req calendarRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		return nil, nil, false
+	}
+	start, end, err := req.dates()
+	if err == nil {
+		err = req.window()
+	}
+	if err == nil {
+		err = withinYear(year, start, end)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	return &calendarEntry{
+		Name:                 req.Name,
+		StartsOn:             start,
+		EndsOn:               end,
+		RegistrationOpensAt:  req.RegistrationOpensAt,
+		RegistrationClosesAt: req.RegistrationClosesAt,
+	}, year, true
+}
+
+// courseRegistrationClosed vraca razlog ako upis kurseva u trenutku now nije
+// otvoren; dok semestri nisu podeseni upis nije ogranicen
+func courseRegistrationClosed(ctx context.Context, cal *repositories.CalendarRepository, now time.Time) (string, error) {
+	if _, err := cal.OpenSemester(ctx, now); err == nil {
+		return "", nil
+	} else if !errors.Is(err, repositories.ErrCalendarNotFound) {
+		return "", err
+	}
+
+	configured, err := cal.HasSemesters(ctx)
+	if err != nil || !configured {
+		return "", err
+	}
+	next, err := cal.NextSemester(ctx, now)
+	if errors.Is(err, repositories.ErrCalendarNotFound) {
+		return "course registration is closed", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("course registration is closed; registration for %s opens at %s",
+		next.Name, next.RegistrationOpensAt.Format(time.RFC3339)), nil
+}
+
+// examRegistrationClosed vraca razlog ako prijava ispita u trenutku now nije
+// otvorena za ispitni rok kome ispit pripada; dok ispitni rokovi nisu
+// podeseni prijava nije ogranicena
+func examRegistrationClosed(ctx context.Context, cal *repositories.CalendarRepository, exam *repositories.Exam, now time.Time) (string, error) {
+	var (
+		period *repositories.ExamPeriod
+		err    error
+	)
+	if exam.ExamPeriodID != nil {
+		period, err = cal.GetExamPeriod(ctx, *exam.ExamPeriodID)
+	} else {
+		period, err = cal.ExamPeriodOn(ctx, exam.ExamTime)
+	}
+	if errors.Is(err, repositories.ErrCalendarNotFound) {
+		configured, err := cal.HasExamPeriods(ctx)
+		if err != nil || !configured {
+			return "", err
+		}
+		return "exam is not scheduled in any exam period", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case now.Before(period.RegistrationOpensAt):
+		return fmt.Sprintf("registration for the %s exam period opens at %s",
+			period.Name, period.RegistrationOpensAt.Format(time.RFC3339)), nil
+	case !now.Before(period.RegistrationClosesAt):
+		return fmt.Sprintf("registration for the %s exam period closed at %s",
+			period.Name, period.RegistrationClosesAt.Format(time.RFC3339)), nil
+	}
+	return "", nil
+}
+
+// assignExamPeriod povezuje ispit sa ispitnim rokom: zadati rok mora
+// obuhvatati termin ispita, a bez zadatog se trazi rok po datumu ispita
+func assignExamPeriod(ctx context.Context, cal *repositories.CalendarRepository, exam *repositories.Exam) (string, error) {
+	if exam.ExamPeriodID == nil {
+		period, err := cal.ExamPeriodOn(ctx, exam.ExamTime)
+		if err == nil {
+			exam.ExamPeriodID = &period.ID
+			return "", nil
+		}
+		if errors.Is(err, repositories.ErrCalendarNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	period, err := cal.GetExamPeriod(ctx, *exam.ExamPeriodID)
+	if errors.Is(err, repositories.ErrCalendarNotFound) {
+		return "exam period not found", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if !period.Contains(exam.ExamTime) {
+		return fmt.Sprintf("exam time must fall within the %s exam period (%s - %s)",
+			period.Name, period.StartsOn.Format(dateLayout), period.EndsOn.Format(dateLayout)), nil
+	}
+	return "", nil
+}
@@ -6,6 +6,8 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/Bijelic03/eAdministration/project/microservices/common/audit"
+	"github.com/Bijelic03/eAdministration/project/microservices/common/auth"
 	"github.com/Bijelic03/eAdministration/project/microservices/university/repositories"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
@@ -28,28 +30,25 @@ type ProgramListResponse struct {
 }
 
 type CourseHandler struct {
-	repo *repositories.CourseRepository
+	repo          *repositories.CourseRepository
+	registrations *repositories.CourseRegistrationRepository
 }
 
-func NewCourseHandler(repo *repositories.CourseRepository) *CourseHandler {
-	return &CourseHandler{repo: repo}
+func NewCourseHandler(repo *repositories.CourseRepository, registrations *repositories.CourseRegistrationRepository) *CourseHandler {
+	return &CourseHandler{repo: repo, registrations: registrations}
 }
 
 // Create course
 func (h *CourseHandler) CreateCourse(w http.ResponseWriter, r *http.Request) {
-
-	role, _ := r.Context().Value("role").(string)
-
-	if role != "facultyadmin" {
-		http.Error(w, "only facultyadmi can create courses", http.StatusForbidden)
-		return
-	}
-
 	var emp repositories.Course
 	if err := json.NewDecoder(r.Body).Decode(&emp); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if emp.Capacity != nil && *emp.Capacity < 0 {
+		http.Error(w, "capacity must not be negative", http.StatusBadRequest)
+		return
+	}
 
 	created, err := h.repo.Add(r.Context(), &emp)
 	if err != nil {
@@ -57,6 +56,8 @@ func (h *CourseHandler) CreateCourse(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	audit.Record(r.Context(), "course.create", "course", created.ID.String(), nil, created)
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(created)
 }
@@ -101,8 +102,8 @@ func (h *CourseHandler) GetAllCourses(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 👇 Uzimamo iz contexta email i rolu
-	email, _ := r.Context().Value("email").(string)
-	role, _ := r.Context().Value("role").(string)
+	email := auth.ClaimsFromContext(r.Context()).Email
+	role := auth.ClaimsFromContext(r.Context()).Role
 
 	var (
 		courses    []*repositories.Course
@@ -146,40 +147,39 @@ func (h *CourseHandler) GetAllCourses(w http.ResponseWriter, r *http.Request) {
 
 // Update course
 func (h *CourseHandler) UpdateCourse(w http.ResponseWriter, r *http.Request) {
-
-	role, _ := r.Context().Value("role").(string)
-
-	if role != "facultyadmin" {
-		http.Error(w, "only facultyadmin can update courses", http.StatusForbidden)
-		return
-	}
-
 	var emp repositories.Course
 	if err := json.NewDecoder(r.Body).Decode(&emp); err != nil {
 		http.Error(w, "invalid request", http.StatusBadRequest)
 		return
 	}
+	if emp.Capacity != nil && *emp.Capacity < 0 {
+		http.Error(w, "capacity must not be negative", http.StatusBadRequest)
+		return
+	}
 
+	before, _ := h.repo.GetByID(r.Context(), emp.ID)
 	updated, err := h.repo.Update(r.Context(), &emp)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	audit.Record(r.Context(), "course.update", "course", updated.ID.String(), before, updated)
+
+	// povecan kapacitet popunjava se sa liste cekanja
+	promoted, err := h.registrations.FillFromWaitlist(r.Context(), updated.ID)
+	if err != nil {
+		http.Error(w, "course updated, but failed to promote students from the waitlist", http.StatusInternalServerError)
+		return
+	}
+	recordPromotions(r, promoted)
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(updated)
 }
 
 // Delete course
 func (h *CourseHandler) DeleteCourse(w http.ResponseWriter, r *http.Request) {
-
-	role, _ := r.Context().Value("role").(string)
-
-	if role != "facultyadmin" {
-		http.Error(w, "only facultyadmin can delete courses", http.StatusForbidden)
-		return
-	}
-
 	vars := mux.Vars(r)
 	idStr := vars["id"]
 	id, err := uuid.Parse(idStr)
@@ -188,11 +188,14 @@ func (h *CourseHandler) DeleteCourse(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before, _ := h.repo.GetByID(r.Context(), id)
 	if err := h.repo.Delete(r.Context(), id); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	audit.Record(r.Context(), "course.delete", "course", id.String(), before, nil)
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -2,8 +2,12 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"time"
 
+	"github.com/Bijelic03/eAdministration/project/microservices/common/audit"
+	"github.com/Bijelic03/eAdministration/project/microservices/common/auth"
 	"github.com/Bijelic03/eAdministration/project/microservices/university/repositories"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
@@ -12,25 +16,21 @@ import (
 type CourseRegistrationHandler struct {
 	repo     *repositories.CourseRegistrationRepository
 	studRepo *repositories.StudentRepository
+	calendar *repositories.CalendarRepository
+	rules    *repositories.EnrollmentRepository
 }
 
-func NewCourseRegistrationHandler(repo *repositories.CourseRegistrationRepository, studRepo *repositories.StudentRepository) *CourseRegistrationHandler {
-	return &CourseRegistrationHandler{repo: repo, studRepo: studRepo}
+func NewCourseRegistrationHandler(repo *repositories.CourseRegistrationRepository, studRepo *repositories.StudentRepository, calendar *repositories.CalendarRepository, rules *repositories.EnrollmentRepository) *CourseRegistrationHandler {
+	return &CourseRegistrationHandler{repo: repo, studRepo: studRepo, calendar: calendar, rules: rules}
 }
 
 // Register student for course
 func (h *CourseRegistrationHandler) RegisterCourse(w http.ResponseWriter, r *http.Request) {
-	email, _ := r.Context().Value("email").(string)
-	role, _ := r.Context().Value("role").(string)
-
+	email := auth.ClaimsFromContext(r.Context()).Email
 	if email == "" {
 		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
-	if role != "student" {
-		http.Error(w, "only students can register for courses", http.StatusForbidden)
-		return
-	}
 
 	vars := mux.Vars(r)
 	courseIDStr := vars["id"]
@@ -40,6 +40,15 @@ func (h *CourseRegistrationHandler) RegisterCourse(w http.ResponseWriter, r *htt
 		return
 	}
 
+	// upis kurseva je moguc samo dok je otvoren rok za upis semestra
+	if reason, err := courseRegistrationClosed(r.Context(), h.calendar, time.Now()); err != nil {
+		http.Error(w, "error checking course registration period", http.StatusInternalServerError)
+		return
+	} else if reason != "" {
+		http.Error(w, reason, http.StatusForbidden)
+		return
+	}
+
 	// prvo dohvatimo studenta iz emaila
 	stud, err := h.studRepo.GetByEmail(r.Context(), email)
 	if err != nil {
@@ -60,27 +69,167 @@ func (h *CourseRegistrationHandler) RegisterCourse(w http.ResponseWriter, r *htt
 		return
 	}
 
-	reg, err := h.repo.Register(r.Context(), courseID, email)
+	// program, godina studija i polozeni preduslovi
+	unmet, err := h.rules.Check(r.Context(), studentID, courseID)
+	if err != nil {
+		http.Error(w, "error checking enrollment requirements", http.StatusInternalServerError)
+		return
+	}
+	if len(unmet) > 0 {
+		writeJSONStatus(w, http.StatusConflict, map[string]any{"error": "enrollment requirements not met", "unmet": unmet})
+		return
+	}
+
+	// kada je kurs popunjen student ide na listu cekanja
+	result, err := h.repo.Enroll(r.Context(), courseID, studentID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeRegistrationErr(w, err)
+		return
+	}
+
+	if result.Waitlist != nil {
+		audit.Record(r.Context(), "course.waitlist.join", "course_waitlist", result.Waitlist.ID.String(), nil, result.Waitlist)
+		writeJSONStatus(w, http.StatusAccepted, result.Waitlist)
 		return
 	}
 
+	audit.Record(r.Context(), "course.register", "course_registration", result.Registration.ID.String(), nil, result.Registration)
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(reg)
+	json.NewEncoder(w).Encode(result.Registration)
 }
 
-// Get all course registrations for student
-func (h *CourseRegistrationHandler) GetMyCourseRegistrations(w http.ResponseWriter, r *http.Request) {
-	email, _ := r.Context().Value("email").(string)
-	role, _ := r.Context().Value("role").(string)
+// Withdraw student from course (or from its waitlist)
+func (h *CourseRegistrationHandler) WithdrawCourse(w http.ResponseWriter, r *http.Request) {
+	courseID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid course id", http.StatusBadRequest)
+		return
+	}
+
+	// odjava je moguca samo dok je otvoren rok za upis semestra
+	if reason, err := courseRegistrationClosed(r.Context(), h.calendar, time.Now()); err != nil {
+		http.Error(w, "error checking course registration period", http.StatusInternalServerError)
+		return
+	} else if reason != "" {
+		http.Error(w, reason, http.StatusForbidden)
+		return
+	}
 
+	stud, ok := h.currentStudent(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := h.repo.Withdraw(r.Context(), courseID, stud.ID)
+	if err != nil {
+		writeRegistrationErr(w, err)
+		return
+	}
+
+	if result.Waitlist != nil {
+		audit.Record(r.Context(), "course.waitlist.leave", "course_waitlist", result.Waitlist.ID.String(), result.Waitlist, nil)
+	} else {
+		audit.Record(r.Context(), "course.withdraw", "course_registration", result.Registration.ID.String(), result.Registration, nil)
+	}
+	recordPromotions(r, result.Promoted)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Get the student's waitlist position for a course
+func (h *CourseRegistrationHandler) GetMyWaitlistPosition(w http.ResponseWriter, r *http.Request) {
+	courseID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid course id", http.StatusBadRequest)
+		return
+	}
+	stud, ok := h.currentStudent(w, r)
+	if !ok {
+		return
+	}
+
+	entry, err := h.repo.WaitlistPosition(r.Context(), courseID, stud.ID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrNotRegistered) {
+			http.Error(w, "you are not on the waitlist for this course", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "failed to load waitlist", http.StatusInternalServerError)
+		return
+	}
+	writeJSONStatus(w, http.StatusOK, entry)
+}
+
+// Get all waitlists the student is on
+func (h *CourseRegistrationHandler) GetMyWaitlist(w http.ResponseWriter, r *http.Request) {
+	stud, ok := h.currentStudent(w, r)
+	if !ok {
+		return
+	}
+
+	entries, err := h.repo.StudentWaitlist(r.Context(), stud.ID)
+	if err != nil {
+		http.Error(w, "failed to load waitlist", http.StatusInternalServerError)
+		return
+	}
+	writeJSONStatus(w, http.StatusOK, map[string]any{"waitlist": entries})
+}
+
+// Get the ordered waitlist of a course
+func (h *CourseRegistrationHandler) GetCourseWaitlist(w http.ResponseWriter, r *http.Request) {
+	courseID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid course id", http.StatusBadRequest)
+		return
+	}
+
+	entries, err := h.repo.CourseWaitlist(r.Context(), courseID)
+	if err != nil {
+		http.Error(w, "failed to load waitlist", http.StatusInternalServerError)
+		return
+	}
+	writeJSONStatus(w, http.StatusOK, map[string]any{"waitlist": entries})
+}
+
+func (h *CourseRegistrationHandler) currentStudent(w http.ResponseWriter, r *http.Request) (*repositories.Student, bool) {
+	email := auth.ClaimsFromContext(r.Context()).Email
 	if email == "" {
 		http.Error(w, "unauthorized", http.StatusUnauthorized)
-		return
+		return nil, false
 	}
-	if role != "student" {
-		http.Error(w, "only students can view their course registrations", http.StatusForbidden)
+	stud, err := h.studRepo.GetByEmail(r.Context(), email)
+	if err != nil {
+		http.Error(w, "student not found", http.StatusNotFound)
+		return nil, false
+	}
+	return stud, true
+}
+
+func writeRegistrationErr(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrRegistrationCourseNotFound), errors.Is(err, repositories.ErrNotRegistered):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, repositories.ErrAlreadyRegistered), errors.Is(err, repositories.ErrAlreadyWaitlisted),
+		errors.Is(err, repositories.ErrCoursePassed):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}
+}
+
+// recordPromotions upisuje u audit log prelaske sa liste cekanja na kurs
+func recordPromotions(r *http.Request, promoted []*repositories.CourseRegistration) {
+	for _, reg := range promoted {
+		audit.Record(r.Context(), "course.waitlist.promote", "course_registration", reg.ID.String(), nil, reg)
+	}
+}
+
+// Get all course registrations for student
+func (h *CourseRegistrationHandler) GetMyCourseRegistrations(w http.ResponseWriter, r *http.Request) {
+	email := auth.ClaimsFromContext(r.Context()).Email
+	if email == "" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
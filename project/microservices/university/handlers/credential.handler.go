@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Bijelic03/eAdministration/project/microservices/common/audit"
+	"github.com/Bijelic03/eAdministration/project/microservices/common/credentials"
+	"github.com/Bijelic03/eAdministration/project/microservices/university/repositories"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// maksimalna duzina razloga opoziva diplome
+const maxRevocationReason = 500
+
+type CredentialHandler struct {
+	repo        *repositories.CredentialRepository
+	students    *repositories.StudentRepository
+	transcripts *repositories.TranscriptRepository
+	signer      *credentials.Signer
+}
+
+func NewCredentialHandler(repo *repositories.CredentialRepository, students *repositories.StudentRepository, transcripts *repositories.TranscriptRepository, signer *credentials.Signer) *CredentialHandler {
+	return &CredentialHandler{repo: repo, students: students, transcripts: transcripts, signer: signer}
+}
+
+// POST /api/v1/university/students/{id}/credentials
+// Izdaje potpisanu diplomu diplomiranom studentu: program, datum diplomiranja
+// (posljednji polozeni ispit), prosjek i ukupan broj ESPB
+func (h *CredentialHandler) IssueCredential(w http.ResponseWriter, r *http.Request) {
+	studentID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	stud, err := h.students.GetByID(r.Context(), studentID)
+	if err != nil || stud.Role != "student" {
+		http.Error(w, "student not found", http.StatusNotFound)
+		return
+	}
+	if stud.Status == nil || *stud.Status != repositories.StudentGraduated {
+		http.Error(w, "student has not graduated", http.StatusConflict)
+		return
+	}
+
+	t, err := h.transcripts.Assemble(r.Context(), studentID)
+	if err != nil {
+		http.Error(w, "failed to assemble transcript", http.StatusInternalServerError)
+		return
+	}
+	if len(t.Entries) == 0 {
+		http.Error(w, "student has no passed exams", http.StatusConflict)
+		return
+	}
+	graduated := t.Entries[len(t.Entries)-1].Date
+
+	now := time.Now().UTC().Truncate(time.Second)
+	c := repositories.GraduationCredential{
+		ID:             uuid.New(),
+		StudentID:      studentID,
+		KeyID:          h.signer.KeyID,
+		Program:        t.Program,
+		GraduationDate: graduated,
+		FinalAverage:   t.WeightedAverage,
+		Ects:           t.TotalEcts,
+		IssuedBy:       issuerID(r),
+		IssuedAt:       now,
+	}
+	c.Token, err = h.signer.Sign(c.ID.String(), studentID.String(), credentials.Graduation{
+		FullName:       t.FullName,
+		IndexNo:        deref(t.IndexNo),
+		Program:        deref(t.Program),
+		GraduationDate: graduated.Format("2006-01-02"),
+		FinalAverage:   t.WeightedAverage,
+		Ects:           t.TotalEcts,
+	}, now)
+	if err != nil {
+		http.Error(w, "failed to sign credential", http.StatusInternalServerError)
+		return
+	}
+
+	created, err := h.repo.Create(r.Context(), &c)
+	if err != nil {
+		if errors.Is(err, repositories.ErrCredentialExists) {
+			http.Error(w, err.Error()+", revoke it first", http.StatusConflict)
+			return
+		}
+		http.Error(w, "failed to issue credential", http.StatusInternalServerError)
+		return
+	}
+
+	audit.Record(r.Context(), "credential.issue", "graduation_credential", created.ID.String(), nil, map[string]any{
+		"studentid":      created.StudentID,
+		"kid":            created.KeyID,
+		"graduationdate": created.GraduationDate,
+		"finalaverage":   created.FinalAverage,
+		"ects":           created.Ects,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+// GET /api/v1/university/students/{id}/credentials
+func (h *CredentialHandler) ListCredentials(w http.ResponseWriter, r *http.Request) {
+	studentID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	creds, err := h.repo.ListByStudent(r.Context(), studentID)
+	if err != nil {
+		http.Error(w, "failed to list credentials", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"credentials": creds})
+}
+
+// POST /api/v1/university/credentials/{id}/revoke
+func (h *CredentialHandler) RevokeCredential(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" || len(req.Reason) > maxRevocationReason {
+		http.Error(w, "reason is required (at most 500 characters)", http.StatusBadRequest)
+		return
+	}
+
+	revoked, err := h.repo.Revoke(r.Context(), id, issuerID(r), req.Reason)
+	if err != nil {
+		if errors.Is(err, repositories.ErrCredentialNotFound) {
+			http.Error(w, "active credential not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "failed to revoke credential", http.StatusInternalServerError)
+		return
+	}
+
+	audit.Record(r.Context(), "credential.revoke", "graduation_credential", id.String(), nil, map[string]any{
+		"studentid": revoked.StudentID,
+		"reason":    req.Reason,
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /api/v1/university/credentials/keys  (javno)
+// Javni kljucevi za provjeru diploma bez poziva univerziteta (JWKS)
+func (h *CredentialHandler) Keys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.repo.Keys(r.Context())
+	if err != nil {
+		http.Error(w, "failed to load keys", http.StatusInternalServerError)
+		return
+	}
+
+	jwks := make([]map[string]string, 0, len(keys))
+	for _, k := range keys {
+		jwks = append(jwks, map[string]string{
+			"kty": "OKP",
+			"crv": "Ed25519",
+			"alg": "EdDSA",
+			"use": "sig",
+			"kid": k.KeyID,
+			"x":   k.PublicKey,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(map[string]any{"keys": jwks})
+}
+
+// GET /api/v1/university/credentials/revocations  (javno)
+func (h *CredentialHandler) Revocations(w http.ResponseWriter, r *http.Request) {
+	revoked, err := h.repo.Revoked(r.Context())
+	if err != nil {
+		http.Error(w, "failed to load revocations", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=60")
+	json.NewEncoder(w).Encode(map[string]any{"revoked": revoked})
+}
+
+// POST /api/v1/university/credentials/verify  (javno)
+// Provjera diplome na mrezi: potpis i da diploma nije opozvana
+func (h *CredentialHandler) VerifyCredential(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Credential string `json:"credential"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Credential == "" {
+		http.Error(w, "credential is required", http.StatusBadRequest)
+		return
+	}
+
+	keys, err := h.repo.Keys(r.Context())
+	if err != nil {
+		http.Error(w, "failed to load keys", http.StatusInternalServerError)
+		return
+	}
+	pub := map[string]ed25519.PublicKey{}
+	for _, k := range keys {
+		if x, err := base64.RawURLEncoding.DecodeString(k.PublicKey); err == nil && len(x) == ed25519.PublicKeySize {
+			pub[k.KeyID] = ed25519.PublicKey(x)
+		}
+	}
+
+	claims, err := credentials.Verify(strings.TrimSpace(req.Credential), pub)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"valid": false, "reason": err.Error()})
+		return
+	}
+
+	resp := map[string]any{"valid": true, "id": claims.ID, "issuedAt": claims.IssuedAt, "credential": claims.Credential}
+	if id, err := uuid.Parse(claims.ID); err == nil {
+		// diploma bez zapisa u bazi nije opozvana; svaka druga greska znaci
+		// da opoziv ne mozemo provjeriti, pa diploma ne smije proci kao vazeca
+		c, err := h.repo.GetByID(r.Context(), id)
+		switch {
+		case err == nil && c.RevokedAt != nil:
+			resp["valid"] = false
+			resp["reason"] = credentials.ErrCredentialRevoked.Error()
+			resp["revokedAt"] = c.RevokedAt
+		case err != nil && !errors.Is(err, repositories.ErrCredentialNotFound):
+			http.Error(w, "failed to check revocation", http.StatusInternalServerError)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Bijelic03/eAdministration/project/microservices/common/audit"
+	"github.com/Bijelic03/eAdministration/project/microservices/university/repositories"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// najvisa godina studija (isto ogranicenje kao u bazi)
+const maxYearOfStudy = 6
+
+type EnrollmentHandler struct {
+	repo *repositories.EnrollmentRepository
+}
+
+func NewEnrollmentHandler(repo *repositories.EnrollmentRepository) *EnrollmentHandler {
+	return &EnrollmentHandler{repo: repo}
+}
+
+func writeEnrollmentErr(w http.ResponseWriter, err error) {
+	var cycle *repositories.PrerequisiteCycleError
+	switch {
+	case errors.As(err, &cycle):
+		writeJSONStatus(w, http.StatusConflict, map[string]any{"error": "prerequisites would create a cycle", "cycle": cycle.Path})
+	case errors.Is(err, repositories.ErrEnrollmentCourseNotFound),
+		errors.Is(err, repositories.ErrEnrollmentProgramNotFound),
+		errors.Is(err, repositories.ErrEnrollmentStudentNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}
+}
+
+func validYear(year *int) bool {
+	return year == nil || (*year >= 1 && *year <= maxYearOfStudy)
+}
+
+// GET /api/v1/university/courses/{id}/prerequisites
+func (h *EnrollmentHandler) GetPrerequisites(w http.ResponseWriter, r *http.Request) {
+	courseID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid course id", http.StatusBadRequest)
+		return
+	}
+
+	prereqs, err := h.repo.Prerequisites(r.Context(), courseID)
+	if err != nil {
+		http.Error(w, "failed to load prerequisites", http.StatusInternalServerError)
+		return
+	}
+	writeJSONStatus(w, http.StatusOK, map[string]any{"prerequisites": prereqs})
+}
+
+// PUT /api/v1/university/courses/{id}/prerequisites
+// Zamjenjuje preduslove kursa: {"prerequisites": ["<courseId>", ...]}
+func (h *EnrollmentHandler) SetPrerequisites(w http.ResponseWriter, r *http.Request) {
+	courseID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid course id", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Prerequisites []uuid.UUID `json:"prerequisites"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	before, _ := h.repo.Prerequisites(r.Context(), courseID)
+	if err := h.repo.SetPrerequisites(r.Context(), courseID, req.Prerequisites); err != nil {
+		writeEnrollmentErr(w, err)
+		return
+	}
+	after, err := h.repo.Prerequisites(r.Context(), courseID)
+	if err != nil {
+		http.Error(w, "failed to load prerequisites", http.StatusInternalServerError)
+		return
+	}
+
+	audit.Record(r.Context(), "course.prerequisites.update", "course", courseID.String(), before, after)
+	writeJSONStatus(w, http.StatusOK, map[string]any{"prerequisites": after})
+}
+
+// GET /api/v1/university/programs/{id}/curriculum
+func (h *EnrollmentHandler) GetCurriculum(w http.ResponseWriter, r *http.Request) {
+	programID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid program id", http.StatusBadRequest)
+		return
+	}
+
+	courses, err := h.repo.Curriculum(r.Context(), programID)
+	if err != nil {
+		http.Error(w, "failed to load curriculum", http.StatusInternalServerError)
+		return
+	}
+	writeJSONStatus(w, http.StatusOK, map[string]any{"courses": courses})
+}
+
+// PUT /api/v1/university/programs/{id}/curriculum/{courseId}
+// Oznake kursa u planu programa: {"mandatory": true, "yearofstudy": 2}
+func (h *EnrollmentHandler) SetProgramCourse(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	programID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		http.Error(w, "invalid program id", http.StatusBadRequest)
+		return
+	}
+	courseID, err := uuid.Parse(vars["courseId"])
+	if err != nil {
+		http.Error(w, "invalid course id", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Mandatory   *bool `json:"mandatory"`
+		YearOfStudy *int  `json:"yearofstudy"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if !validYear(req.YearOfStudy) {
+		http.Error(w, "yearofstudy must be between 1 and 6", http.StatusBadRequest)
+		return
+	}
+	mandatory := req.Mandatory == nil || *req.Mandatory
+
+	if err := h.repo.SetProgramCourse(r.Context(), programID, courseID, mandatory, req.YearOfStudy); err != nil {
+		writeEnrollmentErr(w, err)
+		return
+	}
+
+	after := map[string]any{"mandatory": mandatory, "yearofstudy": req.YearOfStudy}
+	audit.Record(r.Context(), "program.course.set", "program", programID.String(), nil, map[string]any{"courseid": courseID, "entry": after})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DELETE /api/v1/university/programs/{id}/curriculum/{courseId}
+func (h *EnrollmentHandler) RemoveProgramCourse(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	programID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		http.Error(w, "invalid program id", http.StatusBadRequest)
+		return
+	}
+	courseID, err := uuid.Parse(vars["courseId"])
+	if err != nil {
+		http.Error(w, "invalid course id", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.RemoveProgramCourse(r.Context(), programID, courseID); err != nil {
+		writeEnrollmentErr(w, err)
+		return
+	}
+
+	audit.Record(r.Context(), "program.course.remove", "program", programID.String(), map[string]any{"courseid": courseID}, nil)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PUT /api/v1/university/students/{id}/year-of-study
+func (h *EnrollmentHandler) SetYearOfStudy(w http.ResponseWriter, r *http.Request) {
+	studentID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		YearOfStudy *int `json:"yearofstudy"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if !validYear(req.YearOfStudy) {
+		http.Error(w, "yearofstudy must be between 1 and 6", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.repo.SetYearOfStudy(r.Context(), studentID, req.YearOfStudy); err != nil {
+		writeEnrollmentErr(w, err)
+		return
+	}
+
+	audit.Record(r.Context(), "student.yearofstudy.update", "student", studentID.String(), nil, map[string]any{"yearofstudy": req.YearOfStudy})
+	w.WriteHeader(http.StatusNoContent)
+}
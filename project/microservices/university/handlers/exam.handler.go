@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/Bijelic03/eAdministration/project/microservices/common/audit"
 	"github.com/Bijelic03/eAdministration/project/microservices/university/repositories"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
@@ -23,22 +24,15 @@ type ExamHandler struct {
 	repo       *repositories.ExamRepository
 	courseRepo *repositories.CourseRepository
 	profRepo   *repositories.ProfessorRepository
+	calendar   *repositories.CalendarRepository
 }
 
-func NewExamHandler(repo *repositories.ExamRepository, courseRepo *repositories.CourseRepository, profRepo *repositories.ProfessorRepository) *ExamHandler {
-	return &ExamHandler{repo: repo, courseRepo: courseRepo, profRepo: profRepo}
+func NewExamHandler(repo *repositories.ExamRepository, courseRepo *repositories.CourseRepository, profRepo *repositories.ProfessorRepository, calendar *repositories.CalendarRepository) *ExamHandler {
+	return &ExamHandler{repo: repo, courseRepo: courseRepo, profRepo: profRepo, calendar: calendar}
 }
 
 // Create exam
 func (h *ExamHandler) CreateExam(w http.ResponseWriter, r *http.Request) {
-
-	role, _ := r.Context().Value("role").(string)
-
-	if role != "professor" {
-		http.Error(w, "only professors can create exams", http.StatusForbidden)
-		return
-	}
-
 	var exam repositories.Exam
 	if err := json.NewDecoder(r.Body).Decode(&exam); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -81,12 +75,23 @@ func (h *ExamHandler) CreateExam(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// ispit se vezuje za ispitni rok u koji pada
+	if reason, err := assignExamPeriod(r.Context(), h.calendar, &exam); err != nil {
+		http.Error(w, "error checking exam period", http.StatusInternalServerError)
+		return
+	} else if reason != "" {
+		http.Error(w, reason, http.StatusBadRequest)
+		return
+	}
+
 	created, err := h.repo.Add(r.Context(), &exam)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	audit.Record(r.Context(), "exam.create", "exam", created.ID.String(), nil, created)
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(created)
 }
@@ -160,40 +165,35 @@ func (h *ExamHandler) GetAllExams(w http.ResponseWriter, r *http.Request) {
 
 // Update exam
 func (h *ExamHandler) UpdateExam(w http.ResponseWriter, r *http.Request) {
-
-	role, _ := r.Context().Value("role").(string)
-
-	if role != "professor" {
-		http.Error(w, "only professors can update exams", http.StatusForbidden)
-		return
-	}
-
 	var exam repositories.Exam
 	if err := json.NewDecoder(r.Body).Decode(&exam); err != nil {
 		http.Error(w, "invalid request", http.StatusBadRequest)
 		return
 	}
 
+	if reason, err := assignExamPeriod(r.Context(), h.calendar, &exam); err != nil {
+		http.Error(w, "error checking exam period", http.StatusInternalServerError)
+		return
+	} else if reason != "" {
+		http.Error(w, reason, http.StatusBadRequest)
+		return
+	}
+
+	before, _ := h.repo.GetByID(r.Context(), exam.ID)
 	updated, err := h.repo.Update(r.Context(), &exam)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	audit.Record(r.Context(), "exam.update", "exam", updated.ID.String(), before, updated)
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(updated)
 }
 
 // Delete exam
 func (h *ExamHandler) DeleteExam(w http.ResponseWriter, r *http.Request) {
-
-	role, _ := r.Context().Value("role").(string)
-
-	if role != "professor" {
-		http.Error(w, "only professors can delete exams", http.StatusForbidden)
-		return
-	}
-
 	vars := mux.Vars(r)
 	idStr := vars["id"]
 	id, err := uuid.Parse(idStr)
@@ -202,10 +202,13 @@ func (h *ExamHandler) DeleteExam(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before, _ := h.repo.GetByID(r.Context(), id)
 	if err := h.repo.Delete(r.Context(), id); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	audit.Record(r.Context(), "exam.delete", "exam", id.String(), before, nil)
+
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -2,10 +2,16 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/Bijelic03/eAdministration/project/microservices/common/audit"
+	"github.com/Bijelic03/eAdministration/project/microservices/common/auth"
 	"github.com/Bijelic03/eAdministration/project/microservices/university/repositories"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
@@ -16,26 +22,41 @@ type ExamRegistrationHandler struct {
 	studentRepo *repositories.StudentRepository
 	coursesRepo *repositories.CourseRegistrationRepository
 	examRepo    *repositories.ExamRepository
+	calendar    *repositories.CalendarRepository
+	// prijava i odjava se zatvaraju toliko prije termina ispita
+	registrationCutoff time.Duration
+	withdrawalCutoff   time.Duration
 }
 
 func NewExamRegistrationHandler(repo *repositories.ExamRegistrationRepository, studentRepo *repositories.StudentRepository, coursesRepo *repositories.CourseRegistrationRepository,
-	examRepo *repositories.ExamRepository) *ExamRegistrationHandler {
-	return &ExamRegistrationHandler{repo: repo, studentRepo: studentRepo, coursesRepo: coursesRepo, examRepo: examRepo}
+	examRepo *repositories.ExamRepository, calendar *repositories.CalendarRepository, registrationCutoff, withdrawalCutoff time.Duration) *ExamRegistrationHandler {
+	return &ExamRegistrationHandler{repo: repo, studentRepo: studentRepo, coursesRepo: coursesRepo, examRepo: examRepo, calendar: calendar,
+		registrationCutoff: registrationCutoff, withdrawalCutoff: withdrawalCutoff}
+}
+
+// examProfessor javlja da li je pozivalac profesor koji drzi ispit
+func examProfessor(r *http.Request, exam *repositories.Exam) bool {
+	userID := auth.ClaimsFromContext(r.Context()).UserID
+	return userID != "" && strings.EqualFold(exam.ProfessorID, userID)
+}
+
+// cutoffPassed vraca poruku ako je rok (cutoff prije termina ispita) istekao
+func cutoffPassed(action string, exam *repositories.Exam, cutoff time.Duration, now time.Time) string {
+	deadline := exam.ExamTime.Add(-cutoff)
+	if now.Before(deadline) {
+		return ""
+	}
+	return fmt.Sprintf("exam %s closed at %s (%s before the exam)", action, deadline.Format(time.RFC3339), cutoff)
 }
 
 // Register student for exam
 func (h *ExamRegistrationHandler) RegisterExam(w http.ResponseWriter, r *http.Request) {
 
-	email, _ := r.Context().Value("email").(string)
-	role, _ := r.Context().Value("role").(string)
+	email := auth.ClaimsFromContext(r.Context()).Email
 	if email == "" {
 		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
-	if role != "student" {
-		http.Error(w, "only students can register for exams", http.StatusForbidden)
-		return
-	}
 
 	vars := mux.Vars(r)
 	examIDStr := vars["id"]
@@ -51,6 +72,19 @@ func (h *ExamRegistrationHandler) RegisterExam(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	// prijava je moguca samo dok je otvoren rok za prijavu ispitnog roka
+	if reason, err := examRegistrationClosed(r.Context(), h.calendar, exam, time.Now()); err != nil {
+		http.Error(w, "error checking exam period", http.StatusInternalServerError)
+		return
+	} else if reason != "" {
+		http.Error(w, reason, http.StatusForbidden)
+		return
+	}
+	if reason := cutoffPassed("registration", exam, h.registrationCutoff, time.Now()); reason != "" {
+		http.Error(w, reason, http.StatusForbidden)
+		return
+	}
+
 	stud, err := h.studentRepo.GetByEmail(r.Context(), email)
 	if err != nil {
 		http.Error(w, "student not found", http.StatusNotFound)
@@ -93,21 +127,119 @@ func (h *ExamRegistrationHandler) RegisterExam(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	audit.Record(r.Context(), "exam.register", "exam_registration", reg.ID.String(), nil, reg)
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(reg)
 }
 
-// Get examregistrations for profesr
-func (h *ExamRegistrationHandler) GetExamRegistrations(w http.ResponseWriter, r *http.Request) {
-	email, _ := r.Context().Value("email").(string)
-	role, _ := r.Context().Value("role").(string)
-
+// DELETE /api/v1/university/exams/{id}/register
+// Odjava ispita je moguca do roka za odjavu i dok nije upisan ishod
+func (h *ExamRegistrationHandler) WithdrawExam(w http.ResponseWriter, r *http.Request) {
+	email := auth.ClaimsFromContext(r.Context()).Email
 	if email == "" {
 		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
-	if role != "professor" {
-		http.Error(w, "only professors can view their examregistrations", http.StatusForbidden)
+
+	examID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid exam id", http.StatusBadRequest)
+		return
+	}
+
+	exam, err := h.examRepo.GetByID(r.Context(), examID)
+	if err != nil {
+		http.Error(w, "exam not found", http.StatusNotFound)
+		return
+	}
+	if reason := cutoffPassed("withdrawal", exam, h.withdrawalCutoff, time.Now()); reason != "" {
+		http.Error(w, reason, http.StatusForbidden)
+		return
+	}
+
+	stud, err := h.studentRepo.GetByEmail(r.Context(), email)
+	if err != nil {
+		http.Error(w, "student not found", http.StatusNotFound)
+		return
+	}
+
+	reg, err := h.repo.Withdraw(r.Context(), examID, stud.ID)
+	if err != nil {
+		writeExamRegistrationErr(w, err)
+		return
+	}
+
+	audit.Record(r.Context(), "exam.withdraw", "exam_registration", reg.ID.String(), reg, nil)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PUT /api/v1/university/exams/{id}/absent
+// Profesor ispita oznacava da student nije izasao na ispit: {"studentid": "..."}
+// Pogresna oznaka se ispravlja zahtjevom za izmjenu ocjene.
+func (h *ExamRegistrationHandler) MarkAbsent(w http.ResponseWriter, r *http.Request) {
+	examID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid exam id", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		StudentID string `json:"studentid"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		return
+	}
+	studentID, err := uuid.Parse(req.StudentID)
+	if err != nil {
+		http.Error(w, "invalid student id", http.StatusBadRequest)
+		return
+	}
+
+	exam, err := h.examRepo.GetByID(r.Context(), examID)
+	if err != nil {
+		http.Error(w, "exam not found", http.StatusNotFound)
+		return
+	}
+	if !examProfessor(r, exam) {
+		http.Error(w, "only the exam's professor can mark students absent", http.StatusForbidden)
+		return
+	}
+	if time.Now().Before(exam.ExamTime) {
+		http.Error(w, "exam has not taken place yet", http.StatusConflict)
+		return
+	}
+
+	before, _ := h.repo.GetByStudentIDAndExamID(r.Context(), studentID, examID)
+	reg, err := h.repo.MarkAbsent(r.Context(), examID, studentID)
+	if err != nil {
+		writeExamRegistrationErr(w, err)
+		return
+	}
+
+	audit.Record(r.Context(), "exam.absent", "exam_registration", reg.ID.String(), before, reg)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reg)
+}
+
+func writeExamRegistrationErr(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrExamRegistrationNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, repositories.ErrExamOutcomeRecorded):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}
+}
+
+// Get examregistrations for profesr
+func (h *ExamRegistrationHandler) GetExamRegistrations(w http.ResponseWriter, r *http.Request) {
+	email := auth.ClaimsFromContext(r.Context()).Email
+	if email == "" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
@@ -131,17 +263,11 @@ func (h *ExamRegistrationHandler) GetExamRegistrations(w http.ResponseWriter, r
 
 // Get all registrations for student
 func (h *ExamRegistrationHandler) GetMyRegistrations(w http.ResponseWriter, r *http.Request) {
-	email, _ := r.Context().Value("email").(string)
-	role, _ := r.Context().Value("role").(string)
-
+	email := auth.ClaimsFromContext(r.Context()).Email
 	if email == "" {
 		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
-	if role != "student" {
-		http.Error(w, "only students can view their registrations", http.StatusForbidden)
-		return
-	}
 
 	registrations, err := h.repo.GetByStudentEmail(r.Context(), email)
 	if err != nil {
@@ -153,12 +279,6 @@ func (h *ExamRegistrationHandler) GetMyRegistrations(w http.ResponseWriter, r *h
 	json.NewEncoder(w).Encode(registrations)
 }
 func (h *ExamRegistrationHandler) EnterGrade(w http.ResponseWriter, r *http.Request) {
-	role, _ := r.Context().Value("role").(string)
-	if role != "professor" {
-		http.Error(w, "only professors can enter grades", http.StatusForbidden)
-		return
-	}
-
 	vars := mux.Vars(r)
 	examIDStr := vars["id"]
 	examID, err := uuid.Parse(examIDStr)
@@ -203,12 +323,15 @@ func (h *ExamRegistrationHandler) EnterGrade(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	before, _ := h.repo.GetByStudentIDAndExamID(r.Context(), studentID, examID)
 	reg, err := h.repo.EnterGrade(r.Context(), examID, studentID, grade)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	audit.Record(r.Context(), "exam.grade", "exam_registration", reg.ID.String(), before, reg)
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(reg)
 }
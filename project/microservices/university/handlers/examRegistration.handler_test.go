@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bijelic03/eAdministration/project/microservices/common/auth"
+	"github.com/Bijelic03/eAdministration/project/microservices/university/repositories"
+)
+
+func TestCutoffPassed(t *testing.T) {
+	examTime := time.Date(2026, 6, 15, 10, 0, 0, 0, time.UTC)
+	exam := &repositories.Exam{ExamTime: examTime}
+
+	tests := []struct {
+		name   string
+		cutoff time.Duration
+		now    time.Time
+		closed bool
+	}{
+		{"well before the deadline", 48 * time.Hour, examTime.Add(-72 * time.Hour), false},
+		{"just before the deadline", 48 * time.Hour, examTime.Add(-48*time.Hour - time.Second), false},
+		{"at the deadline", 48 * time.Hour, examTime.Add(-48 * time.Hour), true},
+		{"after the deadline", 48 * time.Hour, examTime.Add(-time.Hour), true},
+		{"after the exam", 48 * time.Hour, examTime.Add(time.Hour), true},
+		{"no cutoff, before the exam", 0, examTime.Add(-time.Minute), false},
+		{"no cutoff, at the exam", 0, examTime, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason := cutoffPassed("registration", exam, tt.cutoff, tt.now)
+			if (reason != "") != tt.closed {
+				t.Fatalf("cutoffPassed = %q, want closed = %v", reason, tt.closed)
+			}
+			if tt.closed && !strings.HasPrefix(reason, "exam registration closed at ") {
+				t.Errorf("reason = %q", reason)
+			}
+		})
+	}
+}
+
+func TestExamProfessor(t *testing.T) {
+	const profID = "3f1c2b1e-0000-0000-0000-000000000001"
+
+	tests := []struct {
+		name      string
+		professor string
+		claims    *auth.TokenClaims
+		want      bool
+	}{
+		{"exam's professor", profID, &auth.TokenClaims{UserID: profID}, true},
+		{"upper-case id", profID, &auth.TokenClaims{UserID: strings.ToUpper(profID)}, true},
+		{"another professor", profID, &auth.TokenClaims{UserID: "3f1c2b1e-0000-0000-0000-000000000002"}, false},
+		{"no claims", profID, &auth.TokenClaims{}, false},
+		{"exam without professor", "", &auth.TokenClaims{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("PUT", "/api/v1/university/exams/x/absent", nil)
+			r = r.WithContext(auth.WithClaims(r.Context(), tt.claims))
+			exam := &repositories.Exam{ProfessorID: tt.professor}
+			if got := examProfessor(r, exam); got != tt.want {
+				t.Errorf("examProfessor = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/Bijelic03/eAdministration/project/microservices/common/audit"
+	"github.com/Bijelic03/eAdministration/project/microservices/university/repositories"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// maksimalna duzina obrazlozenja zahtjeva i odluke
+const maxGradeChangeText = 500
+
+type GradeChangeHandler struct {
+	repo     *repositories.GradeChangeRepository
+	examRepo *repositories.ExamRepository
+}
+
+func NewGradeChangeHandler(repo *repositories.GradeChangeRepository, examRepo *repositories.ExamRepository) *GradeChangeHandler {
+	return &GradeChangeHandler{repo: repo, examRepo: examRepo}
+}
+
+func writeGradeChangeErr(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrGradeChangeNotFound),
+		errors.Is(err, repositories.ErrExamRegistrationNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, repositories.ErrGradeChangeNotGraded),
+		errors.Is(err, repositories.ErrGradeChangeSameGrade),
+		errors.Is(err, repositories.ErrGradeChangePending),
+		errors.Is(err, repositories.ErrGradeChangeDecided),
+		errors.Is(err, repositories.ErrGradeChangeStale),
+		errors.Is(err, repositories.ErrGradeChangeCredential):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}
+}
+
+// POST /api/v1/university/exams/{id}/grade-changes
+// Profesor ispita trazi izmjenu upisane ocjene ili oznake izostanka:
+// {"studentid": "...", "newgrade": 8, "reason": "..."}
+func (h *GradeChangeHandler) RequestGradeChange(w http.ResponseWriter, r *http.Request) {
+	examID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid exam id", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		StudentID string `json:"studentid"`
+		NewGrade  int    `json:"newgrade"`
+		Reason    string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		return
+	}
+	studentID, err := uuid.Parse(req.StudentID)
+	if err != nil {
+		http.Error(w, "invalid student id", http.StatusBadRequest)
+		return
+	}
+	if req.NewGrade < 1 || req.NewGrade > 10 {
+		http.Error(w, "grade must be between 1 and 10", http.StatusBadRequest)
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" || len(req.Reason) > maxGradeChangeText {
+		http.Error(w, "reason is required (at most 500 characters)", http.StatusBadRequest)
+		return
+	}
+
+	// izmjenu moze traziti samo profesor koji drzi ispit
+	exam, err := h.examRepo.GetByID(r.Context(), examID)
+	if err != nil {
+		http.Error(w, "exam not found", http.StatusNotFound)
+		return
+	}
+	if !examProfessor(r, exam) {
+		http.Error(w, "only the exam's professor can request a grade change", http.StatusForbidden)
+		return
+	}
+
+	g, err := h.repo.Create(r.Context(), examID, studentID, req.NewGrade, req.Reason, issuerID(r))
+	if err != nil {
+		writeGradeChangeErr(w, err)
+		return
+	}
+
+	audit.Record(r.Context(), "grade.change.request", "grade_change_request", g.ID.String(), nil, g)
+	writeJSONStatus(w, http.StatusCreated, g)
+}
+
+// GET /api/v1/university/exams/{id}/grade-changes
+func (h *GradeChangeHandler) GetExamGradeChanges(w http.ResponseWriter, r *http.Request) {
+	examID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid exam id", http.StatusBadRequest)
+		return
+	}
+
+	changes, err := h.repo.List(r.Context(), repositories.GradeChangeFilter{ExamID: &examID})
+	if err != nil {
+		http.Error(w, "failed to list grade changes", http.StatusInternalServerError)
+		return
+	}
+	writeJSONStatus(w, http.StatusOK, map[string]any{"gradechanges": changes})
+}
+
+// GET /api/v1/university/grade-changes?status=PENDING&studentid=...
+func (h *GradeChangeHandler) GetGradeChanges(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := repositories.GradeChangeFilter{Status: repositories.GradeChangeStatus(strings.ToUpper(q.Get("status")))}
+	switch filter.Status {
+	case "", repositories.GradeChangePending, repositories.GradeChangeApproved, repositories.GradeChangeRejected:
+	default:
+		http.Error(w, "status must be PENDING, APPROVED or REJECTED", http.StatusBadRequest)
+		return
+	}
+	if s := q.Get("studentid"); s != "" {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			http.Error(w, "invalid student id", http.StatusBadRequest)
+			return
+		}
+		filter.StudentID = &id
+	}
+
+	changes, err := h.repo.List(r.Context(), filter)
+	if err != nil {
+		http.Error(w, "failed to list grade changes", http.StatusInternalServerError)
+		return
+	}
+	writeJSONStatus(w, http.StatusOK, map[string]any{"gradechanges": changes})
+}
+
+// POST /api/v1/university/grade-changes/{id}/approve
+// Odobrava izmjenu; ocjena, passed, ESPB i status diplomiranja se usaglasavaju
+func (h *GradeChangeHandler) ApproveGradeChange(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Note string `json:"note"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid JSON", http.StatusBadRequest)
+			return
+		}
+	}
+	req.Note = strings.TrimSpace(req.Note)
+	if len(req.Note) > maxGradeChangeText {
+		http.Error(w, "note must be at most 500 characters", http.StatusBadRequest)
+		return
+	}
+	var note *string
+	if req.Note != "" {
+		note = &req.Note
+	}
+
+	before, err := h.repo.GetByID(r.Context(), id)
+	if err != nil {
+		writeGradeChangeErr(w, err)
+		return
+	}
+	result, err := h.repo.Approve(r.Context(), id, issuerID(r), note)
+	if err != nil {
+		writeGradeChangeErr(w, err)
+		return
+	}
+
+	audit.Record(r.Context(), "grade.change.approve", "grade_change_request", id.String(), before, result.Request)
+	audit.Record(r.Context(), "exam.grade.change", "exam_registration", result.Registration.ID.String(),
+		map[string]any{"grade": before.OldGrade, "absent": before.OldGrade == nil},
+		map[string]any{"grade": result.Registration.Grade, "passed": result.Registration.Passed, "absent": result.Registration.Absent,
+			"studentects": result.StudentEcts, "studentstatus": result.Status})
+
+	writeJSONStatus(w, http.StatusOK, result)
+}
+
+// POST /api/v1/university/grade-changes/{id}/reject
+func (h *GradeChangeHandler) RejectGradeChange(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Note string `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		return
+	}
+	req.Note = strings.TrimSpace(req.Note)
+	if req.Note == "" || len(req.Note) > maxGradeChangeText {
+		http.Error(w, "note is required (at most 500 characters)", http.StatusBadRequest)
+		return
+	}
+
+	g, err := h.repo.Reject(r.Context(), id, issuerID(r), req.Note)
+	if err != nil {
+		writeGradeChangeErr(w, err)
+		return
+	}
+
+	audit.Record(r.Context(), "grade.change.reject", "grade_change_request", id.String(), nil, g)
+	writeJSONStatus(w, http.StatusOK, g)
+}
@@ -22,6 +22,8 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/Bijelic03/eAdministration/project/microservices/common/audit"
+	"github.com/Bijelic03/eAdministration/project/microservices/common/auth"
 	"github.com/Bijelic03/eAdministration/project/microservices/university/repositories"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
@@ -45,26 +47,23 @@ func NewProfessorHandler(repo *repositories.ProfessorRepository) *ProfessorHandl
 
 // Create professor
 func (h *ProfessorHandler) CreateProfessor(w http.ResponseWriter, r *http.Request) {
-
-	role, _ := r.Context().Value("role").(string)
-
-	if role != "facultyadmin" {
-		http.Error(w, "only facultyadmin can create professor", http.StatusForbidden)
-		return
-	}
-
 	var emp repositories.Professor
 	if err := json.NewDecoder(r.Body).Decode(&emp); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	// uloge se dodjeljuju samo kroz auth servis
+	emp.Role = "professor"
+
 	created, err := h.repo.Add(r.Context(), &emp)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	audit.Record(r.Context(), "professor.create", "professor", created.ID.String(), nil, created)
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(created)
 }
@@ -133,8 +132,8 @@ func (h *ProfessorHandler) GetAllProfessors(w http.ResponseWriter, r *http.Reque
 		}
 	}
 
-	role, _ := r.Context().Value("role").(string)
-	email, _ := r.Context().Value("email").(string)
+	role := auth.ClaimsFromContext(r.Context()).Role
+	email := auth.ClaimsFromContext(r.Context()).Email
 
 	// Ako je profesor -> vrati samo njega
 	if role == "professor" {
@@ -188,13 +187,11 @@ func (h *ProfessorHandler) GetAllProfessors(w http.ResponseWriter, r *http.Reque
 
 // Update professor
 func (h *ProfessorHandler) UpdateProfessor(w http.ResponseWriter, r *http.Request) {
-
-	// role, _ := r.Context().Value("role").(string)
-
-	// // if role != "professor" {
-	// // 	http.Error(w, "only professors can update professor", http.StatusForbidden)
-	// // 	return
-	// // }
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
 
 	var emp repositories.Professor
 	if err := json.NewDecoder(r.Body).Decode(&emp); err != nil {
@@ -202,12 +199,20 @@ func (h *ProfessorHandler) UpdateProfessor(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	// azurira se zapis iz putanje (na njega se odnosi provjera vlasnistva);
+	// uloge se mijenjaju samo kroz auth servis
+	emp.ID = id
+	emp.Role = "professor"
+
+	before, _ := h.repo.GetByID(r.Context(), id)
 	updated, err := h.repo.Update(r.Context(), &emp)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	audit.Record(r.Context(), "professor.update", "professor", updated.ID.String(), before, updated)
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(updated)
 }
@@ -222,10 +227,13 @@ func (h *ProfessorHandler) DeleteProfessor(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	before, _ := h.repo.GetByID(r.Context(), id)
 	if err := h.repo.Delete(r.Context(), id); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	audit.Record(r.Context(), "professor.delete", "professor", id.String(), before, nil)
+
 	w.WriteHeader(http.StatusNoContent)
 }
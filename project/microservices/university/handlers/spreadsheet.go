@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// readCSV cita CSV tabelu; separator je zarez ili tacka-zarez (Excel sa
+// lokalnim podesavanjima), a red i u tabeli odgovara redu i+1 u fajlu
+func readCSV(data []byte) ([][]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	header, _, _ := bytes.Cut(data, []byte("\n"))
+	cr := csv.NewReader(bytes.NewReader(data))
+	if bytes.Count(header, []byte(";")) > bytes.Count(header, []byte(",")) {
+		cr.Comma = ';'
+	}
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	var rows [][]string
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+		for len(rows) < line-1 {
+			rows = append(rows, nil)
+		}
+		rows = append(rows, rec)
+	}
+	return rows, nil
+}
+
+// XLSX je zip arhiva, pa velicina uploada ne ogranicava raspakovani sadrzaj;
+// svaki dio arhive i dimenzije lista se zato ogranicavaju posebno
+const (
+	maxXLSXPartSize = 20 << 20
+	maxXLSXRows     = maxImportRows + 1
+	maxXLSXColumns  = 256
+)
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		RID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Items []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// xlsxText je tekst celije, cijeli ili podijeljen u formatirane dijelove
+type xlsxText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.T
+	}
+	var sb strings.Builder
+	for _, r := range t.Runs {
+		sb.WriteString(r.T)
+	}
+	return sb.String()
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxText `xml:"si"`
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			Ref    string   `xml:"r,attr"`
+			Type   string   `xml:"t,attr"`
+			Value  string   `xml:"v"`
+			Inline xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// readXLSX cita prvi list XLSX radne sveske (Office Open XML) kao tabelu
+// tekstualnih vrijednosti; formule se citaju kao posljednja izracunata vrijednost
+func readXLSX(data []byte) ([][]string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("not a valid xlsx file: %w", err)
+	}
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	var shared xlsxSharedStrings
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := decodeZipXML(f, &shared); err != nil {
+			return nil, err
+		}
+	}
+
+	f, ok := files[firstSheetPath(files)]
+	if !ok {
+		return nil, errors.New("xlsx file has no worksheets")
+	}
+	var sheet xlsxSheet
+	if err := decodeZipXML(f, &sheet); err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	for _, row := range sheet.Rows {
+		if row.R > maxXLSXRows || len(rows) >= maxXLSXRows {
+			return nil, fmt.Errorf("sheet has too many rows, at most %d", maxXLSXRows)
+		}
+		if row.R > 0 {
+			for len(rows) < row.R-1 {
+				rows = append(rows, nil)
+			}
+		}
+		var rec []string
+		for _, c := range row.Cells {
+			col := len(rec)
+			if c.Ref != "" {
+				col = xlsxColumn(c.Ref)
+			}
+			if col >= maxXLSXColumns {
+				return nil, fmt.Errorf("sheet has too many columns, at most %d", maxXLSXColumns)
+			}
+			for len(rec) < col {
+				rec = append(rec, "")
+			}
+
+			var v string
+			switch c.Type {
+			case "s":
+				i, err := strconv.Atoi(c.Value)
+				if err != nil || i < 0 || i >= len(shared.Items) {
+					return nil, fmt.Errorf("invalid shared string in cell %s", c.Ref)
+				}
+				v = shared.Items[i].String()
+			case "inlineStr":
+				v = c.Inline.String()
+			default:
+				v = c.Value
+			}
+			rec = append(rec, v)
+		}
+		rows = append(rows, rec)
+	}
+	return rows, nil
+}
+
+// firstSheetPath pronalazi putanju prvog lista preko workbook.xml i njegovih veza
+func firstSheetPath(files map[string]*zip.File) string {
+	const fallback = "xl/worksheets/sheet1.xml"
+
+	var wb xlsxWorkbook
+	var rels xlsxRelationships
+	wf, ok1 := files["xl/workbook.xml"]
+	rf, ok2 := files["xl/_rels/workbook.xml.rels"]
+	if !ok1 || !ok2 || decodeZipXML(wf, &wb) != nil || decodeZipXML(rf, &rels) != nil || len(wb.Sheets) == 0 {
+		return fallback
+	}
+	for _, rel := range rels.Items {
+		if rel.ID != wb.Sheets[0].RID {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/")
+		}
+		return path.Join("xl", rel.Target)
+	}
+	return fallback
+}
+
+// decodeZipXML dekodira XML dio arhive; deklarisana velicina moze biti
+// lazna, pa se i samo citanje prekida na maxXLSXPartSize
+func decodeZipXML(f *zip.File, v any) error {
+	tooLarge := fmt.Errorf("xlsx part %s is too large (at most %d MB uncompressed)", f.Name, maxXLSXPartSize>>20)
+	if f.UncompressedSize64 > maxXLSXPartSize {
+		return tooLarge
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	lr := &io.LimitedReader{R: rc, N: maxXLSXPartSize + 1}
+	err = xml.NewDecoder(lr).Decode(v)
+	if lr.N <= 0 {
+		return tooLarge
+	}
+	if err != nil {
+		return fmt.Errorf("invalid xlsx part %s: %w", f.Name, err)
+	}
+	return nil
+}
+
+// xlsxColumn pretvara oznaku celije (npr. "C12") u indeks kolone od nule
+func xlsxColumn(ref string) int {
+	col := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A'+1)
+	}
+	return col - 1
+}
@@ -44,6 +44,8 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/Bijelic03/eAdministration/project/microservices/common/audit"
+	"github.com/Bijelic03/eAdministration/project/microservices/common/auth"
 	"github.com/Bijelic03/eAdministration/project/microservices/university/repositories"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
@@ -58,35 +60,33 @@ type StudentListResponse struct {
 }
 
 type StudentHandler struct {
-	repo *repositories.StudentRepository
+	repo   *repositories.StudentRepository
+	tokens *auth.TokenSource // service token za pozive employmentOffice-a
 }
 
-func NewStudentHandler(repo *repositories.StudentRepository) *StudentHandler {
-	return &StudentHandler{repo: repo}
+func NewStudentHandler(repo *repositories.StudentRepository, tokens *auth.TokenSource) *StudentHandler {
+	return &StudentHandler{repo: repo, tokens: tokens}
 }
 
 // Create Student
 func (h *StudentHandler) CreateStudent(w http.ResponseWriter, r *http.Request) {
-
-	role, _ := r.Context().Value("role").(string)
-
-	if role != "facultyadmin" {
-		http.Error(w, "only facultyadmin can create student", http.StatusForbidden)
-		return
-	}
-
 	var stud repositories.Student
 	if err := json.NewDecoder(r.Body).Decode(&stud); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	// uloge se dodjeljuju samo kroz auth servis
+	stud.Role = "student"
+
 	created, err := h.repo.Add(r.Context(), &stud)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	audit.Record(r.Context(), "student.create", "student", created.ID.String(), nil, created)
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(created)
 }
@@ -194,8 +194,8 @@ func (h *StudentHandler) GetAllStudents(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
-	role, _ := r.Context().Value("role").(string)
-	email, _ := r.Context().Value("email").(string)
+	role := auth.ClaimsFromContext(r.Context()).Role
+	email := auth.ClaimsFromContext(r.Context()).Email
 
 	client := &http.Client{Timeout: 3 * time.Second}
 
@@ -209,13 +209,13 @@ func (h *StudentHandler) GetAllStudents(w http.ResponseWriter, r *http.Request)
 
 		// employment provjera
 		if student.IndexNo != nil && *student.IndexNo != "" {
-			url := "http://employment-office:8082/api/v1/employmentOffice/employees/employed/" + *student.IndexNo
-			req, _ := http.NewRequest("GET", url, nil)
-			if authHeader := r.Header.Get("Authorization"); authHeader != "" {
-				req.Header.Set("Authorization", authHeader)
+			url := "http://employment-office:8082/api/v1/employmentOffice/internal/employees/employed/" + *student.IndexNo
+			req, _ := http.NewRequestWithContext(r.Context(), "GET", url, nil)
+			err := h.tokens.AuthorizeRequest(req)
+			var respEmp *http.Response
+			if err == nil {
+				respEmp, err = client.Do(req)
 			}
-
-			respEmp, err := client.Do(req)
 			if err == nil && respEmp.StatusCode == http.StatusOK {
 				defer respEmp.Body.Close()
 				var employedResp struct {
@@ -264,14 +264,15 @@ func (h *StudentHandler) GetAllStudents(w http.ResponseWriter, r *http.Request)
 			continue
 		}
 
-		url := "http://employment-office:8082/api/v1/employmentOffice/employees/employed/" + *s.IndexNo
-		req, err := http.NewRequest("GET", url, nil)
+		url := "http://employment-office:8082/api/v1/employmentOffice/internal/employees/employed/" + *s.IndexNo
+		req, err := http.NewRequestWithContext(r.Context(), "GET", url, nil)
 		if err != nil {
 			s.Employed = false
 			continue
 		}
-		if authHeader := r.Header.Get("Authorization"); authHeader != "" {
-			req.Header.Set("Authorization", authHeader)
+		if err := h.tokens.AuthorizeRequest(req); err != nil {
+			s.Employed = false
+			continue
 		}
 
 		respEmp, err := client.Do(req)
@@ -310,18 +311,32 @@ func (h *StudentHandler) GetAllStudents(w http.ResponseWriter, r *http.Request)
 
 // Update student
 func (h *StudentHandler) UpdateStudent(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
 	var stud repositories.Student
 	if err := json.NewDecoder(r.Body).Decode(&stud); err != nil {
 		http.Error(w, "invalid request", http.StatusBadRequest)
 		return
 	}
 
+	// azurira se zapis iz putanje (na njega se odnosi provjera vlasnistva);
+	// uloge se mijenjaju samo kroz auth servis
+	stud.ID = id
+	stud.Role = "student"
+
+	before, _ := h.repo.GetByID(r.Context(), id)
 	updated, err := h.repo.Update(r.Context(), &stud)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	audit.Record(r.Context(), "student.update", "student", updated.ID.String(), before, updated)
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(updated)
 }
@@ -336,11 +351,14 @@ func (h *StudentHandler) DeleteStudent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before, _ := h.repo.GetByID(r.Context(), id)
 	if err := h.repo.Delete(r.Context(), id); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	audit.Record(r.Context(), "student.delete", "student", id.String(), before, nil)
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
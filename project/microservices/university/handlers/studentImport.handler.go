@@ -0,0 +1,389 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"mime"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Bijelic03/eAdministration/project/microservices/common/audit"
+	"github.com/Bijelic03/eAdministration/project/microservices/university/repositories"
+	"github.com/google/uuid"
+)
+
+const (
+	maxImportSize = 10 << 20
+	maxImportRows = 5000
+)
+
+// kolone koje fajl za import mora imati u zaglavlju
+var importColumns = []string{"fullname", "email", "indexno", "program"}
+
+type StudentImportHandler struct {
+	students  *repositories.StudentRepository
+	courses   *repositories.CourseRepository
+	inviteURL string
+	inviteTTL time.Duration
+}
+
+func NewStudentImportHandler(students *repositories.StudentRepository, courses *repositories.CourseRepository, inviteURL string, inviteTTL time.Duration) *StudentImportHandler {
+	return &StudentImportHandler{students: students, courses: courses, inviteURL: inviteURL, inviteTTL: inviteTTL}
+}
+
+// ImportRow je izvjestaj za jedan red fajla; lozinka i pozivni link se
+// prikazuju samo u odgovoru na uspjesan import
+type ImportRow struct {
+	Row             int        `json:"row"`
+	FullName        string     `json:"fullname"`
+	Email           string     `json:"email"`
+	IndexNo         string     `json:"indexno"`
+	Program         string     `json:"program"`
+	Errors          []string   `json:"errors,omitempty"`
+	ID              *uuid.UUID `json:"id,omitempty"`
+	InitialPassword string     `json:"initialPassword,omitempty"`
+	InviteURL       string     `json:"inviteUrl,omitempty"`
+
+	programID uuid.UUID
+}
+
+type ImportReport struct {
+	DryRun  bool        `json:"dryRun"`
+	Total   int         `json:"total"`
+	Valid   int         `json:"valid"`
+	Invalid int         `json:"invalid"`
+	Created int         `json:"created"`
+	Rows    []ImportRow `json:"rows"`
+}
+
+// POST /api/v1/university/students/import?dryRun=&generatePasswords=&invite=
+// Masovni upis studenata iz CSV ili XLSX fajla (multipart polje "file" ili
+// tijelo zahtjeva) sa kolonama fullname, email, indexno, program. Svaki red se
+// validira; uz dryRun se vraca samo izvjestaj, inace se svi validni redovi
+// upisuju u jednoj transakciji. generatePasswords vraca pocetne lozinke, a
+// invite pravi jednokratne linkove za postavljanje lozinke.
+func (h *StudentImportHandler) ImportStudents(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	dryRun := q.Get("dryRun") == "true"
+	generatePasswords := q.Get("generatePasswords") == "true"
+	invite := q.Get("invite") == "true"
+
+	table, err := readImportFile(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rows, err := parseImportRows(table)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.validate(r, rows); err != nil {
+		http.Error(w, "failed to validate rows: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	report := ImportReport{DryRun: dryRun, Total: len(rows), Rows: rows}
+	var valid []int
+	for i := range rows {
+		if len(rows[i].Errors) == 0 {
+			valid = append(valid, i)
+		}
+	}
+	report.Valid = len(valid)
+	report.Invalid = report.Total - report.Valid
+
+	if dryRun {
+		writeImportReport(w, http.StatusOK, report)
+		return
+	}
+	if len(valid) == 0 {
+		writeImportReport(w, http.StatusUnprocessableEntity, report)
+		return
+	}
+
+	studs := make([]repositories.ImportStudent, len(valid))
+	invites := make([]string, len(valid))
+	for n, i := range valid {
+		row := &rows[i]
+		password, err := newInitialPassword()
+		if err != nil {
+			http.Error(w, "failed to generate password", http.StatusInternalServerError)
+			return
+		}
+		studs[n] = repositories.ImportStudent{
+			FullName:  row.FullName,
+			Email:     row.Email,
+			IndexNo:   row.IndexNo,
+			ProgramID: row.programID,
+			Password:  password,
+		}
+		if generatePasswords {
+			row.InitialPassword = password
+		}
+		if invite {
+			token, err := newInviteToken()
+			if err != nil {
+				http.Error(w, "failed to generate invitation", http.StatusInternalServerError)
+				return
+			}
+			sum := sha256.Sum256([]byte(token))
+			studs[n].InviteHash = hex.EncodeToString(sum[:])
+			studs[n].InviteExpiresAt = time.Now().Add(h.inviteTTL)
+			invites[n] = token
+		}
+	}
+
+	ids, err := h.students.Import(r.Context(), studs)
+	if err != nil {
+		if errors.Is(err, repositories.ErrImportConflict) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, "failed to import students: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	created := make([]string, len(ids))
+	for n, i := range valid {
+		rows[i].ID = &ids[n]
+		if invites[n] != "" {
+			rows[i].InviteURL = h.inviteURL + "?token=" + url.QueryEscape(invites[n])
+		}
+		created[n] = ids[n].String()
+	}
+	report.Created = len(ids)
+
+	audit.Record(r.Context(), "student.import", "student", "", nil, map[string]any{
+		"created":           created,
+		"invalid":           report.Invalid,
+		"generatePasswords": generatePasswords,
+		"invite":            invite,
+	})
+
+	writeImportReport(w, http.StatusCreated, report)
+}
+
+// validate provjerava redove medjusobno i prema bazi i dopisuje greske u redove
+func (h *StudentImportHandler) validate(r *http.Request, rows []ImportRow) error {
+	programs, err := h.courses.ListPrograms(r.Context())
+	if err != nil {
+		return err
+	}
+	byName := map[string]uuid.UUID{}
+	byID := map[uuid.UUID]bool{}
+	for _, p := range programs {
+		byName[strings.ToLower(p.Name)] = p.ID
+		byID[p.ID] = true
+	}
+
+	var emails, indices []string
+	for _, row := range rows {
+		emails = append(emails, strings.ToLower(row.Email))
+		indices = append(indices, row.IndexNo)
+	}
+	takenEmails, err := h.students.ExistingEmails(r.Context(), emails)
+	if err != nil {
+		return err
+	}
+	takenIndices, err := h.students.ExistingIndexNumbers(r.Context(), indices)
+	if err != nil {
+		return err
+	}
+
+	seenEmails := map[string]int{}
+	seenIndices := map[string]int{}
+	for i := range rows {
+		row := &rows[i]
+		fail := func(msg string) { row.Errors = append(row.Errors, msg) }
+
+		if row.FullName == "" {
+			fail("fullname is required")
+		}
+
+		email := strings.ToLower(row.Email)
+		switch addr, err := mail.ParseAddress(row.Email); {
+		case row.Email == "":
+			fail("email is required")
+		case err != nil || addr.Address != row.Email:
+			fail("invalid email")
+		case takenEmails[email]:
+			fail("email is already in use")
+		case seenEmails[email] > 0:
+			fail("duplicate email (row " + strconv.Itoa(seenEmails[email]) + ")")
+		}
+		if _, ok := seenEmails[email]; !ok && email != "" {
+			seenEmails[email] = row.Row
+		}
+
+		switch {
+		case row.IndexNo == "":
+			fail("indexno is required")
+		case takenIndices[row.IndexNo]:
+			fail("index number is already assigned")
+		case seenIndices[row.IndexNo] > 0:
+			fail("duplicate index number (row " + strconv.Itoa(seenIndices[row.IndexNo]) + ")")
+		}
+		if _, ok := seenIndices[row.IndexNo]; !ok && row.IndexNo != "" {
+			seenIndices[row.IndexNo] = row.Row
+		}
+
+		// program se zadaje nazivom ili ID-jem iz singleton tabele
+		if id, ok := byName[strings.ToLower(row.Program)]; ok {
+			row.programID = id
+		} else if id, err := uuid.Parse(row.Program); err == nil && byID[id] {
+			row.programID = id
+		} else if row.Program == "" {
+			fail("program is required")
+		} else {
+			fail("unknown program " + row.Program)
+		}
+	}
+	return nil
+}
+
+// readImportFile cita fajl iz multipart forme ili tijela zahtjeva i bira
+// format po ekstenziji ili Content-Type zaglavlju
+func readImportFile(w http.ResponseWriter, r *http.Request) ([][]string, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+
+	var data []byte
+	var name, contentType string
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		f, fh, err := r.FormFile("file")
+		if err != nil {
+			return nil, errors.New("file is required")
+		}
+		defer f.Close()
+		if data, err = io.ReadAll(f); err != nil {
+			return nil, errors.New("failed to read file")
+		}
+		name, contentType = fh.Filename, fh.Header.Get("Content-Type")
+	} else {
+		var err error
+		if data, err = io.ReadAll(r.Body); err != nil {
+			return nil, errors.New("failed to read request body")
+		}
+		contentType = mediaType
+	}
+	if len(data) == 0 {
+		return nil, errors.New("file is empty")
+	}
+
+	ext := strings.ToLower(path.Ext(name))
+	switch {
+	case ext == ".xlsx" || strings.Contains(contentType, "spreadsheetml"):
+		return readXLSX(data)
+	case ext == ".csv" || ext == ".txt" || strings.HasPrefix(contentType, "text/") || contentType == "application/csv":
+		return readCSV(data)
+	default:
+		return nil, errors.New("unsupported file type, expected CSV or XLSX")
+	}
+}
+
+// parseImportRows mapira redove tabele na kolone iz zaglavlja (prvi red)
+func parseImportRows(table [][]string) ([]ImportRow, error) {
+	if len(table) == 0 {
+		return nil, errors.New("file has no header row")
+	}
+	cols := map[string]int{}
+	for i, name := range table[0] {
+		cols[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, c := range importColumns {
+		if _, ok := cols[c]; !ok {
+			return nil, errors.New("missing column " + c + ", expected: " + strings.Join(importColumns, ", "))
+		}
+	}
+
+	cell := func(rec []string, col string) string {
+		if i := cols[col]; i < len(rec) {
+			return strings.TrimSpace(rec[i])
+		}
+		return ""
+	}
+
+	rows := []ImportRow{}
+	for i, rec := range table[1:] {
+		if strings.TrimSpace(strings.Join(rec, "")) == "" {
+			continue
+		}
+		rows = append(rows, ImportRow{
+			Row:      i + 2,
+			FullName: cell(rec, "fullname"),
+			Email:    cell(rec, "email"),
+			IndexNo:  cell(rec, "indexno"),
+			Program:  cell(rec, "program"),
+		})
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("file has no data rows")
+	}
+	if len(rows) > maxImportRows {
+		return nil, errors.New("too many rows, at most " + strconv.Itoa(maxImportRows) + " per import")
+	}
+	return rows, nil
+}
+
+func writeImportReport(w http.ResponseWriter, status int, report ImportReport) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
+
+// newInitialPassword pravi nasumicnu lozinku sa malim i velikim slovima i
+// ciframa (bez slicnih znakova poput 0/O i 1/l)
+func newInitialPassword() (string, error) {
+	const (
+		lower  = "abcdefghijkmnpqrstuvwxyz"
+		upper  = "ABCDEFGHJKLMNPQRSTUVWXYZ"
+		digits = "23456789"
+		length = 14
+	)
+	sets := []string{lower, upper, digits}
+	all := lower + upper + digits
+
+	b := make([]byte, length)
+	for i := range b {
+		set := all
+		if i < len(sets) {
+			set = sets[i]
+		}
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(set))))
+		if err != nil {
+			return "", err
+		}
+		b[i] = set[n.Int64()]
+	}
+	// obavezni znakovi ne smiju uvijek biti na pocetku
+	for i := len(b) - 1; i > 0; i-- {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return "", err
+		}
+		b[i], b[j.Int64()] = b[j.Int64()], b[i]
+	}
+	return string(b), nil
+}
+
+// newInviteToken pravi token u istom formatu kao reset tokeni auth servisa
+func newInviteToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Bijelic03/eAdministration/project/microservices/common/auth"
+	"github.com/Bijelic03/eAdministration/project/microservices/university/pdf"
+	"github.com/Bijelic03/eAdministration/project/microservices/university/repositories"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type TranscriptHandler struct {
+	repo      *repositories.TranscriptRepository
+	verifyURL string
+}
+
+func NewTranscriptHandler(repo *repositories.TranscriptRepository, verifyURL string) *TranscriptHandler {
+	return &TranscriptHandler{repo: repo, verifyURL: verifyURL}
+}
+
+// GET /api/v1/university/students/{id}/transcript?format=json|pdf
+// Prepis ocjena: polozeni ispiti sa sifrom i nazivom predmeta, ESPB, ocjenom,
+// datumom, zbirom ESPB i ponderisanim prosjekom. Svaki izdati prepis dobija
+// verifikacioni kod (isti dok se sadrzaj ne promijeni).
+func (h *TranscriptHandler) GetTranscript(w http.ResponseWriter, r *http.Request) {
+	studentID, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	t, err := h.repo.Assemble(r.Context(), studentID)
+	if err != nil {
+		if errors.Is(err, repositories.ErrTranscriptStudentNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "failed to assemble transcript", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.repo.Issue(r.Context(), t, issuerID(r)); err != nil {
+		http.Error(w, "failed to issue transcript", http.StatusInternalServerError)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" && strings.Contains(r.Header.Get("Accept"), "application/pdf") {
+		format = "pdf"
+	}
+	switch format {
+	case "", "json":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(t)
+	case "pdf":
+		name := "transcript"
+		if t.IndexNo != nil {
+			name += "-" + strings.NewReplacer("/", "-", " ", "").Replace(*t.IndexNo)
+		}
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+name+`.pdf"`)
+		w.Write(h.renderPDF(t))
+	default:
+		http.Error(w, "format must be json or pdf", http.StatusBadRequest)
+	}
+}
+
+// GET /api/v1/university/transcripts/verify/{code}  (javno)
+// Vraca prepis kakav je bio pri izdavanju, da primalac dokumenta moze
+// uporediti sadrzaj sa odstampanim
+func (h *TranscriptHandler) VerifyTranscript(w http.ResponseWriter, r *http.Request) {
+	code := strings.ToUpper(strings.TrimSpace(mux.Vars(r)["code"]))
+
+	t, err := h.repo.GetByCode(r.Context(), code)
+	if err != nil {
+		if errors.Is(err, repositories.ErrTranscriptNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "failed to verify transcript", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"valid":      true,
+		"transcript": t,
+	})
+}
+
+// issuerID je korisnik koji izdaje prepis (administrator pri impersonaciji)
+func issuerID(r *http.Request) *uuid.UUID {
+	c := auth.ClaimsFromContext(r.Context())
+	sub := c.UserID
+	if c.Actor != nil {
+		sub = c.Actor.ID
+	}
+	id, err := uuid.Parse(sub)
+	if err != nil {
+		return nil
+	}
+	return &id
+}
+
+func (h *TranscriptHandler) renderPDF(t *repositories.Transcript) []byte {
+	const (
+		left   = 50.0
+		right  = pdf.PageWidth - 50
+		top    = pdf.PageHeight - 60
+		bottom = 90.0
+		rowH   = 16.0
+	)
+	cols := []float64{left, left + 22, left + 90, left + 330, left + 370, left + 410, left + 470}
+
+	doc := pdf.New("Prepis ocjena - " + t.FullName)
+	y := top
+
+	doc.Text(left, y, 18, pdf.Bold, "Prepis ocjena")
+	y -= 28
+	info := [][2]string{{"Student", t.FullName}, {"Broj indeksa", deref(t.IndexNo)}, {"Studijski program", deref(t.Program)}}
+	for _, kv := range info {
+		doc.Text(left, y, 10, pdf.Bold, kv[0]+":")
+		doc.Text(left+110, y, 10, pdf.Regular, kv[1])
+		y -= 15
+	}
+	y -= 10
+
+	header := func() {
+		for i, title := range []string{"#", "Šifra", "Predmet", "ESPB", "Ocjena", "Datum", "Ukupno"} {
+			doc.Text(cols[i], y, 9, pdf.Bold, title)
+		}
+		doc.Line(left, y-4, right, y-4, 0.8)
+		y -= rowH
+	}
+	header()
+
+	for i, e := range t.Entries {
+		if y < bottom {
+			doc.AddPage()
+			y = top
+			header()
+		}
+		row := []string{
+			strconv.Itoa(i + 1),
+			truncate(e.CourseCode, 12),
+			truncate(e.CourseName, 44),
+			strconv.Itoa(e.Ects),
+			strconv.Itoa(e.Grade),
+			e.Date.Format("02.01.2006"),
+			strconv.Itoa(e.RunningEcts),
+		}
+		for c, v := range row {
+			doc.Text(cols[c], y, 9, pdf.Regular, v)
+		}
+		y -= rowH
+	}
+	if len(t.Entries) == 0 {
+		doc.Text(left, y, 9, pdf.Regular, "Nema položenih ispita.")
+		y -= rowH
+	}
+
+	if y < bottom+40 {
+		doc.AddPage()
+		y = top
+	}
+	doc.Line(left, y+8, right, y+8, 0.8)
+	y -= 8
+	average := "-"
+	if t.WeightedAverage != nil {
+		average = fmt.Sprintf("%.2f", *t.WeightedAverage)
+	}
+	doc.Text(left, y, 10, pdf.Bold, "Ukupno ESPB: "+strconv.Itoa(t.TotalEcts))
+	doc.Text(left+200, y, 10, pdf.Bold, "Prosječna ocjena (ponderisana ESPB): "+average)
+
+	// verifikacija je u podnozju posljednje stranice
+	doc.Text(left, 60, 9, pdf.Regular, "Verifikacioni kod: "+t.VerificationCode+"    Izdato: "+t.IssuedAt.Format("02.01.2006 15:04 MST"))
+	doc.Text(left, 46, 8, pdf.Regular, "Provjera autenticnosti: "+h.verifyURL+"/"+t.VerificationCode)
+	doc.Text(left, 34, 8, pdf.Regular, "SHA-256 sadrzaja: "+t.ContentHash)
+
+	return doc.Bytes()
+}
+
+func deref(s *string) string {
+	if s == nil || *s == "" {
+		return "-"
+	}
+	return *s
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
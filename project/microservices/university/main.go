@@ -2,15 +2,16 @@ package main
 
 import (
 	"context"
-	"encoding/json"
-	"io"
+	"encoding/base64"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
-	"strings"
 	"time"
 
+	"github.com/Bijelic03/eAdministration/project/microservices/common/audit"
+	"github.com/Bijelic03/eAdministration/project/microservices/common/auth"
+	"github.com/Bijelic03/eAdministration/project/microservices/common/credentials"
 	"github.com/Bijelic03/eAdministration/project/microservices/university/config"
 	"github.com/Bijelic03/eAdministration/project/microservices/university/db"
 	"github.com/Bijelic03/eAdministration/project/microservices/university/handlers"
@@ -20,29 +21,30 @@ import (
 	"github.com/gorilla/mux"
 )
 
-const authServiceURL = "http://auth:8083/api/v1/auth/verify"
-
-type VerifyResponse struct {
-	Ok    bool   `json:"ok"`
-	Email string `json:"email,omitempty"`
-	Role  string `json:"role,omitempty"`
-	Error string `json:"error,omitempty"`
-}
-
 func main() {
 	cfg := config.GetConfig()
 
 	conn, err := db.Connect(cfg.DatabaseURL())
 	handleErr(err)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// tokeni se verifikuju lokalno, kljucevi se kesiraju iz auth servisa; lista
+	// opozvanih tokena se osvjezava svake 2s, a bez auth servisa tokeni se odbijaju
+	verifier := auth.NewVerifier(cfg.AuthURL, "university", 2*time.Second)
+	verifier.Start(ctx)
+	authMiddleware := verifier.Middleware
+
+	// service token za pozive internih ruta employmentOffice-a
+	serviceTokens := auth.NewTokenSource(cfg.AuthURL, cfg.ServiceClientID, cfg.ServiceClientSecret)
+
 	address := ":8081"
 
 	cors := handler.CORS(
 		handler.AllowedMethods([]string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
-		handler.AllowedHeaders([]string{"Authorization", "Content-Type"}),
+		handler.AllowedHeaders([]string{"Authorization", "Content-Type", audit.RequestIDHeader}),
+		handler.ExposedHeaders([]string{audit.RequestIDHeader}),
 		handler.AllowCredentials(),
 		handler.AllowedOrigins([]string{"*"}),
 	)
@@ -51,6 +53,17 @@ func main() {
 	router := mux.NewRouter()
 	router.Use(mux.CORSMethodMiddleware(router))
 
+	// akter u audit logu je korisnik iz verifikovanog tokena
+	auditLog := audit.NewLogger(conn, "university", func(ctx context.Context) audit.Actor {
+		c := auth.ClaimsFromContext(ctx)
+		if c.Actor != nil {
+			// impersonacija: akciju izvrsava administrator
+			return audit.Actor{ID: c.Actor.ID, Email: c.Actor.Email, Role: c.Actor.Role}
+		}
+		return audit.Actor{ID: c.UserID, Email: c.Email, Role: c.Role}
+	})
+	router.Use(auditLog.Middleware)
+
 	api := router.PathPrefix("/api/v1/university").Subrouter()
 
 	// /api/v1/university/professors
@@ -66,7 +79,7 @@ func main() {
 
 	// /api/v1/university/students
 	studentRepository := repositories.NewStudentRepository(conn)
-	studentHandler := handlers.NewStudentHandler(studentRepository)
+	studentHandler := handlers.NewStudentHandler(studentRepository, serviceTokens)
 	students := api.PathPrefix("/students").Subrouter()
 	students.Handle("", authMiddleware(http.HandlerFunc(studentHandler.CreateStudent))).Methods("POST")
 	students.Handle("", authMiddleware(http.HandlerFunc(studentHandler.GetAllStudents))).Methods("GET")
@@ -79,7 +92,8 @@ func main() {
 
 	// /api/v1/university/courses
 	courseRepository := repositories.NewCourseRepository(conn)
-	courseHandler := handlers.NewCourseHandler(courseRepository)
+	courseRegistrationRepository := repositories.NewCourseRegistrationRepository(conn)
+	courseHandler := handlers.NewCourseHandler(courseRepository, courseRegistrationRepository)
 	courses := api.PathPrefix("/courses").Subrouter()
 	courses.Handle("", authMiddleware(http.HandlerFunc(courseHandler.CreateCourse))).Methods("POST")
 	courses.Handle("", authMiddleware(http.HandlerFunc(courseHandler.GetAllCourses))).Methods("GET")
@@ -91,15 +105,61 @@ func main() {
 	programs.Handle("", authMiddleware(http.HandlerFunc(courseHandler.GetAllPrograms))).Methods("GET")
 	programs.Handle("/{id}/courses", authMiddleware(http.HandlerFunc(courseHandler.GetCoursesByProgram))).Methods("GET")
 
-	courseRegistrationRepository := repositories.NewCourseRegistrationRepository(conn)
-	courseRegistrationHandler := handlers.NewCourseRegistrationHandler(courseRegistrationRepository, studentRepository)
+	// masovni upis studenata iz CSV/XLSX fajla
+	studentImportHandler := handlers.NewStudentImportHandler(studentRepository, courseRepository, cfg.InvitationURL, cfg.InvitationTTL)
+	students.Handle("/import", authMiddleware(http.HandlerFunc(studentImportHandler.ImportStudents))).Methods("POST")
+
+	// akademski kalendar: skolske godine, semestri i ispitni rokovi
+	calendarRepository := repositories.NewCalendarRepository(conn)
+
+	// preduslovi kurseva i plan studijskih programa
+	enrollmentRepository := repositories.NewEnrollmentRepository(conn)
+	enrollmentHandler := handlers.NewEnrollmentHandler(enrollmentRepository)
+	courses.Handle("/{id}/prerequisites", authMiddleware(http.HandlerFunc(enrollmentHandler.GetPrerequisites))).Methods("GET")
+	courses.Handle("/{id}/prerequisites", authMiddleware(http.HandlerFunc(enrollmentHandler.SetPrerequisites))).Methods("PUT")
+	programs.Handle("/{id}/curriculum", authMiddleware(http.HandlerFunc(enrollmentHandler.GetCurriculum))).Methods("GET")
+	programs.Handle("/{id}/curriculum/{courseId}", authMiddleware(http.HandlerFunc(enrollmentHandler.SetProgramCourse))).Methods("PUT")
+	programs.Handle("/{id}/curriculum/{courseId}", authMiddleware(http.HandlerFunc(enrollmentHandler.RemoveProgramCourse))).Methods("DELETE")
+	students.Handle("/{id}/year-of-study", authMiddleware(http.HandlerFunc(enrollmentHandler.SetYearOfStudy))).Methods("PUT")
+
+	courseRegistrationHandler := handlers.NewCourseRegistrationHandler(courseRegistrationRepository, studentRepository, calendarRepository, enrollmentRepository)
 	courses.Handle("/{id}/register", authMiddleware(http.HandlerFunc(courseRegistrationHandler.RegisterCourse))).Methods("POST")
 	courses.Handle("/my-registrations", authMiddleware(http.HandlerFunc(courseRegistrationHandler.GetMyCourseRegistrations))).Methods("GET")
+	courses.Handle("/{id}/register", authMiddleware(http.HandlerFunc(courseRegistrationHandler.WithdrawCourse))).Methods("DELETE")
+	courses.Handle("/{id}/waitlist", authMiddleware(http.HandlerFunc(courseRegistrationHandler.GetCourseWaitlist))).Methods("GET")
+	courses.Handle("/{id}/waitlist/me", authMiddleware(http.HandlerFunc(courseRegistrationHandler.GetMyWaitlistPosition))).Methods("GET")
+	students.Handle("/me/waitlist", authMiddleware(http.HandlerFunc(courseRegistrationHandler.GetMyWaitlist))).Methods("GET")
 	students.Handle("/avg-grades", authMiddleware(http.HandlerFunc(studentHandler.GetStudentsByIndicesWithAvg))).Methods("POST")
 
+	// prepis ocjena; provjera po verifikacionom kodu je javna
+	transcriptRepository := repositories.NewTranscriptRepository(conn)
+	transcriptHandler := handlers.NewTranscriptHandler(transcriptRepository, cfg.TranscriptVerifyURL)
+	students.Handle("/{id}/transcript", authMiddleware(http.HandlerFunc(transcriptHandler.GetTranscript))).Methods("GET")
+	api.HandleFunc("/transcripts/verify/{code}", transcriptHandler.VerifyTranscript).Methods("GET")
+
+	// potpisane diplome; javni kljucevi, lista opozvanih i provjera su javni
+	signer, err := credentials.LoadSigner(cfg.CredentialSigningKeyFile)
+	handleErr(err)
+	credentialRepository := repositories.NewCredentialRepository(conn)
+	handleErr(credentialRepository.RegisterKey(ctx, signer.KeyID, base64.RawURLEncoding.EncodeToString(signer.PublicKey())))
+	credentialHandler := handlers.NewCredentialHandler(credentialRepository, studentRepository, transcriptRepository, signer)
+	students.Handle("/{id}/credentials", authMiddleware(http.HandlerFunc(credentialHandler.IssueCredential))).Methods("POST")
+	students.Handle("/{id}/credentials", authMiddleware(http.HandlerFunc(credentialHandler.ListCredentials))).Methods("GET")
+	api.Handle("/credentials/{id}/revoke", authMiddleware(http.HandlerFunc(credentialHandler.RevokeCredential))).Methods("POST")
+	api.HandleFunc("/credentials/keys", credentialHandler.Keys).Methods("GET")
+	api.HandleFunc("/credentials/revocations", credentialHandler.Revocations).Methods("GET")
+	api.HandleFunc("/credentials/verify", credentialHandler.VerifyCredential).Methods("POST")
+
+	// /api/v1/university/internal - samo service tokeni (employmentOffice)
+	internal := api.PathPrefix("/internal").Subrouter()
+	internal.Handle("/professors", authMiddleware(http.HandlerFunc(professorHandler.GetAllProfessors))).Methods("GET")
+	internal.Handle("/students/indexno", authMiddleware(http.HandlerFunc(studentHandler.GetAllIndexNumbersHandler))).Methods("GET")
+	internal.Handle("/students/avg-grades", authMiddleware(http.HandlerFunc(studentHandler.GetStudentsByIndicesWithAvg))).Methods("POST")
+	internal.Handle("/students/verify-graduation/{indexno}", authMiddleware(http.HandlerFunc(studentHandler.VerifyGraduation))).Methods("GET")
+
 	// /api/v1/university/exams
 	examRepository := repositories.NewExamRepository(conn)
-	examHandler := handlers.NewExamHandler(examRepository, courseRepository, professorRepository)
+	examHandler := handlers.NewExamHandler(examRepository, courseRepository, professorRepository, calendarRepository)
 	exams := api.PathPrefix("/exams").Subrouter()
 	exams.Handle("", authMiddleware(http.HandlerFunc(examHandler.CreateExam))).Methods("POST")
 	exams.Handle("", authMiddleware(http.HandlerFunc(examHandler.GetAllExams))).Methods("GET")
@@ -108,12 +168,37 @@ func main() {
 	exams.Handle("/{id}", authMiddleware(http.HandlerFunc(examHandler.DeleteExam))).Methods("DELETE")
 
 	examRegistrationRepository := repositories.NewExamRegistrationRepository(conn)
-	examRegistrationHandler := handlers.NewExamRegistrationHandler(examRegistrationRepository, studentRepository, courseRegistrationRepository, examRepository)
+	examRegistrationHandler := handlers.NewExamRegistrationHandler(examRegistrationRepository, studentRepository, courseRegistrationRepository, examRepository, calendarRepository,
+		cfg.ExamRegistrationCutoff, cfg.ExamWithdrawalCutoff)
 	exams.Handle("/{id}/register", authMiddleware(http.HandlerFunc(examRegistrationHandler.RegisterExam))).Methods("POST")
+	exams.Handle("/{id}/register", authMiddleware(http.HandlerFunc(examRegistrationHandler.WithdrawExam))).Methods("DELETE")
 	exams.Handle("/{id}/grade", authMiddleware(http.HandlerFunc(examRegistrationHandler.EnterGrade))).Methods("PUT")
+	exams.Handle("/{id}/absent", authMiddleware(http.HandlerFunc(examRegistrationHandler.MarkAbsent))).Methods("PUT")
 	exams.Handle("/my-registrations", authMiddleware(http.HandlerFunc(examRegistrationHandler.GetMyRegistrations))).Methods("GET")
 	exams.Handle("/{id}/examregistrations", authMiddleware(http.HandlerFunc(examRegistrationHandler.GetExamRegistrations))).Methods("GET")
 
+	gradeChangeRepository := repositories.NewGradeChangeRepository(conn)
+	gradeChangeHandler := handlers.NewGradeChangeHandler(gradeChangeRepository, examRepository)
+	exams.Handle("/{id}/grade-changes", authMiddleware(http.HandlerFunc(gradeChangeHandler.RequestGradeChange))).Methods("POST")
+	exams.Handle("/{id}/grade-changes", authMiddleware(http.HandlerFunc(gradeChangeHandler.GetExamGradeChanges))).Methods("GET")
+	api.Handle("/grade-changes", authMiddleware(http.HandlerFunc(gradeChangeHandler.GetGradeChanges))).Methods("GET")
+	api.Handle("/grade-changes/{id}/approve", authMiddleware(http.HandlerFunc(gradeChangeHandler.ApproveGradeChange))).Methods("POST")
+	api.Handle("/grade-changes/{id}/reject", authMiddleware(http.HandlerFunc(gradeChangeHandler.RejectGradeChange))).Methods("POST")
+
+	calendarHandler := handlers.NewCalendarHandler(calendarRepository, examRepository)
+	years := api.PathPrefix("/academic-years").Subrouter()
+	years.Handle("", authMiddleware(http.HandlerFunc(calendarHandler.CreateAcademicYear))).Methods("POST")
+	years.Handle("", authMiddleware(http.HandlerFunc(calendarHandler.GetAcademicYears))).Methods("GET")
+	years.Handle("/{id}", authMiddleware(http.HandlerFunc(calendarHandler.GetAcademicYear))).Methods("GET")
+	years.Handle("/{id}/semesters", authMiddleware(http.HandlerFunc(calendarHandler.CreateSemester))).Methods("POST")
+	years.Handle("/{id}/exam-periods", authMiddleware(http.HandlerFunc(calendarHandler.CreateExamPeriod))).Methods("POST")
+	api.Handle("/semesters/{id}", authMiddleware(http.HandlerFunc(calendarHandler.UpdateSemester))).Methods("PUT")
+	api.Handle("/semesters/{id}", authMiddleware(http.HandlerFunc(calendarHandler.DeleteSemester))).Methods("DELETE")
+	api.Handle("/exam-periods/{id}", authMiddleware(http.HandlerFunc(calendarHandler.UpdateExamPeriod))).Methods("PUT")
+	api.Handle("/exam-periods/{id}", authMiddleware(http.HandlerFunc(calendarHandler.DeleteExamPeriod))).Methods("DELETE")
+	api.Handle("/exam-periods/{id}/exams", authMiddleware(http.HandlerFunc(calendarHandler.GetExamPeriodExams))).Methods("GET")
+	api.Handle("/calendar/current", authMiddleware(http.HandlerFunc(calendarHandler.GetCurrent))).Methods("GET")
+
 	// Set up the server
 	server := &http.Server{
 		Handler: cors(router),
@@ -151,54 +236,3 @@ func handleErr(err error) {
 	}
 
 }
-
-func authMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
-			http.Error(w, `{"error":"Missing or invalid Authorization header"}`, http.StatusUnauthorized)
-			return
-		}
-		token := strings.TrimPrefix(authHeader, "Bearer ")
-
-		client := &http.Client{Timeout: 5 * time.Second}
-		req, err := http.NewRequest("GET", authServiceURL, nil)
-		if err != nil {
-			http.Error(w, `{"error":"internal error"}`, http.StatusInternalServerError)
-			return
-		}
-		req.Header.Set("Authorization", "Bearer "+token)
-
-		resp, err := client.Do(req)
-		if err != nil {
-			msg := map[string]string{"error": "auth service unreachable: " + err.Error()}
-			b, _ := json.Marshal(msg)
-			http.Error(w, string(b), http.StatusUnauthorized)
-			return
-		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode != http.StatusOK {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(resp.StatusCode)
-			io.Copy(w, resp.Body)
-			return
-		}
-
-		var verify VerifyResponse
-		if err := json.NewDecoder(resp.Body).Decode(&verify); err != nil {
-			http.Error(w, `{"error":"invalid response from auth service"}`, http.StatusUnauthorized)
-			return
-		}
-		if !verify.Ok {
-			http.Error(w, `{"error":"token not valid"}`, http.StatusUnauthorized)
-			return
-		}
-
-		ctx := context.WithValue(r.Context(), "email", verify.Email)
-		ctx = context.WithValue(ctx, "role", verify.Role)
-		r = r.WithContext(ctx)
-
-		next.ServeHTTP(w, r)
-	})
-}
@@ -0,0 +1,348 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrCalendarNotFound = errors.New("not found in academic calendar")
+	ErrCalendarConflict = errors.New("name already used in this academic year")
+)
+
+// AcademicYear je skolska godina (npr. "2025/2026") sa semestrima i ispitnim rokovima
+type AcademicYear struct {
+	ID          uuid.UUID     `json:"id"`
+	Name        string        `json:"name"`
+	StartsOn    time.Time     `json:"startson"`
+	EndsOn      time.Time     `json:"endson"`
+	Semesters   []*Semester   `json:"semesters,omitempty"`
+	ExamPeriods []*ExamPeriod `json:"examperiods,omitempty"`
+}
+
+// Semester ima rok za upis kurseva [RegistrationOpensAt, RegistrationClosesAt)
+type Semester struct {
+	ID                   uuid.UUID `json:"id"`
+	AcademicYearID       uuid.UUID `json:"academicyearid"`
+	Name                 string    `json:"name"`
+	StartsOn             time.Time `json:"startson"`
+	EndsOn               time.Time `json:"endson"`
+	RegistrationOpensAt  time.Time `json:"registrationopensat"`
+	RegistrationClosesAt time.Time `json:"registrationclosesat"`
+}
+
+// ExamPeriod je ispitni rok (npr. januarski) sa rokom za prijavu ispita
+type ExamPeriod struct {
+	ID                   uuid.UUID `json:"id"`
+	AcademicYearID       uuid.UUID `json:"academicyearid"`
+	Name                 string    `json:"name"`
+	StartsOn             time.Time `json:"startson"`
+	EndsOn               time.Time `json:"endson"`
+	RegistrationOpensAt  time.Time `json:"registrationopensat"`
+	RegistrationClosesAt time.Time `json:"registrationclosesat"`
+}
+
+// RegistrationOpen vraca da li je prijava otvorena u trenutku t
+func (s *Semester) RegistrationOpen(t time.Time) bool {
+	return !t.Before(s.RegistrationOpensAt) && t.Before(s.RegistrationClosesAt)
+}
+
+// RegistrationOpen vraca da li je prijava ispita otvorena u trenutku t
+func (p *ExamPeriod) RegistrationOpen(t time.Time) bool {
+	return !t.Before(p.RegistrationOpensAt) && t.Before(p.RegistrationClosesAt)
+}
+
+// Contains vraca da li dan t pada u ispitni rok
+func (p *ExamPeriod) Contains(t time.Time) bool {
+	day := t.Format("2006-01-02")
+	return day >= p.StartsOn.Format("2006-01-02") && day <= p.EndsOn.Format("2006-01-02")
+}
+
+type CalendarRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewCalendarRepository(db *pgxpool.Pool) *CalendarRepository {
+	return &CalendarRepository{db: db}
+}
+
+const (
+	semesterColumns   = `id, academic_year_id, name, starts_on, ends_on, registration_opens_at, registration_closes_at`
+	examPeriodColumns = `id, academic_year_id, name, starts_on, ends_on, registration_opens_at, registration_closes_at`
+)
+
+func scanYear(row pgx.Row) (*AcademicYear, error) {
+	var y AcademicYear
+	if err := row.Scan(&y.ID, &y.Name, &y.StartsOn, &y.EndsOn); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrCalendarNotFound
+		}
+		return nil, err
+	}
+	return &y, nil
+}
+
+func scanSemester(row pgx.Row) (*Semester, error) {
+	var s Semester
+	err := row.Scan(&s.ID, &s.AcademicYearID, &s.Name, &s.StartsOn, &s.EndsOn, &s.RegistrationOpensAt, &s.RegistrationClosesAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrCalendarNotFound
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+func scanExamPeriod(row pgx.Row) (*ExamPeriod, error) {
+	var p ExamPeriod
+	err := row.Scan(&p.ID, &p.AcademicYearID, &p.Name, &p.StartsOn, &p.EndsOn, &p.RegistrationOpensAt, &p.RegistrationClosesAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrCalendarNotFound
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+func calendarErr(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrCalendarConflict
+	}
+	return err
+}
+
+// --- skolske godine ---
+
+func (r *CalendarRepository) CreateYear(ctx context.Context, y *AcademicYear) (*AcademicYear, error) {
+	q := `INSERT INTO academic_years (name, starts_on, ends_on) VALUES ($1, $2, $3)
+		RETURNING id, name, starts_on, ends_on`
+	created, err := scanYear(r.db.QueryRow(ctx, q, y.Name, y.StartsOn, y.EndsOn))
+	if err != nil {
+		return nil, calendarErr(err)
+	}
+	return created, nil
+}
+
+func (r *CalendarRepository) ListYears(ctx context.Context) ([]*AcademicYear, error) {
+	rows, err := r.db.Query(ctx, `SELECT id, name, starts_on, ends_on FROM academic_years ORDER BY starts_on DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	years := []*AcademicYear{}
+	for rows.Next() {
+		y, err := scanYear(rows)
+		if err != nil {
+			return nil, err
+		}
+		years = append(years, y)
+	}
+	return years, rows.Err()
+}
+
+// GetYear vraca skolsku godinu sa semestrima i ispitnim rokovima
+func (r *CalendarRepository) GetYear(ctx context.Context, id uuid.UUID) (*AcademicYear, error) {
+	y, err := scanYear(r.db.QueryRow(ctx, `SELECT id, name, starts_on, ends_on FROM academic_years WHERE id = $1`, id))
+	if err != nil {
+		return nil, err
+	}
+	if y.Semesters, err = r.listSemesters(ctx, `WHERE academic_year_id = $1`, id); err != nil {
+		return nil, err
+	}
+	if y.ExamPeriods, err = r.listExamPeriods(ctx, `WHERE academic_year_id = $1`, id); err != nil {
+		return nil, err
+	}
+	return y, nil
+}
+
+// CurrentYear vraca skolsku godinu u koju pada dan t
+func (r *CalendarRepository) CurrentYear(ctx context.Context, t time.Time) (*AcademicYear, error) {
+	q := `SELECT id, name, starts_on, ends_on FROM academic_years
+		WHERE starts_on <= $1::date AND ends_on >= $1::date
+		ORDER BY starts_on DESC LIMIT 1`
+	return scanYear(r.db.QueryRow(ctx, q, t))
+}
+
+// --- semestri ---
+
+func (r *CalendarRepository) CreateSemester(ctx context.Context, s *Semester) (*Semester, error) {
+	q := `INSERT INTO semesters (academic_year_id, name, starts_on, ends_on, registration_opens_at, registration_closes_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + semesterColumns
+	created, err := scanSemester(r.db.QueryRow(ctx, q, s.AcademicYearID, s.Name, s.StartsOn, s.EndsOn,
+		s.RegistrationOpensAt, s.RegistrationClosesAt))
+	if err != nil {
+		return nil, calendarErr(err)
+	}
+	return created, nil
+}
+
+func (r *CalendarRepository) GetSemester(ctx context.Context, id uuid.UUID) (*Semester, error) {
+	return scanSemester(r.db.QueryRow(ctx, `SELECT `+semesterColumns+` FROM semesters WHERE id = $1`, id))
+}
+
+func (r *CalendarRepository) UpdateSemester(ctx context.Context, s *Semester) (*Semester, error) {
+	q := `UPDATE semesters SET name = $2, starts_on = $3, ends_on = $4, registration_opens_at = $5, registration_closes_at = $6
+		WHERE id = $1
+		RETURNING ` + semesterColumns
+	updated, err := scanSemester(r.db.QueryRow(ctx, q, s.ID, s.Name, s.StartsOn, s.EndsOn,
+		s.RegistrationOpensAt, s.RegistrationClosesAt))
+	if err != nil {
+		return nil, calendarErr(err)
+	}
+	return updated, nil
+}
+
+func (r *CalendarRepository) DeleteSemester(ctx context.Context, id uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM semesters WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrCalendarNotFound
+	}
+	return nil
+}
+
+// CurrentSemester vraca semestar u koji pada dan t
+func (r *CalendarRepository) CurrentSemester(ctx context.Context, t time.Time) (*Semester, error) {
+	q := `SELECT ` + semesterColumns + ` FROM semesters
+		WHERE starts_on <= $1::date AND ends_on >= $1::date
+		ORDER BY starts_on DESC LIMIT 1`
+	return scanSemester(r.db.QueryRow(ctx, q, t))
+}
+
+// OpenSemester vraca semestar ciji je upis kurseva otvoren u trenutku t
+func (r *CalendarRepository) OpenSemester(ctx context.Context, t time.Time) (*Semester, error) {
+	q := `SELECT ` + semesterColumns + ` FROM semesters
+		WHERE registration_opens_at <= $1 AND registration_closes_at > $1
+		ORDER BY registration_closes_at LIMIT 1`
+	return scanSemester(r.db.QueryRow(ctx, q, t))
+}
+
+// NextSemester vraca semestar ciji se upis kurseva sljedeci otvara poslije t
+func (r *CalendarRepository) NextSemester(ctx context.Context, t time.Time) (*Semester, error) {
+	q := `SELECT ` + semesterColumns + ` FROM semesters
+		WHERE registration_opens_at > $1
+		ORDER BY registration_opens_at LIMIT 1`
+	return scanSemester(r.db.QueryRow(ctx, q, t))
+}
+
+// HasSemesters vraca da li je kalendar semestara uopste podesen
+func (r *CalendarRepository) HasSemesters(ctx context.Context) (bool, error) {
+	var ok bool
+	err := r.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM semesters)`).Scan(&ok)
+	return ok, err
+}
+
+func (r *CalendarRepository) listSemesters(ctx context.Context, where string, args ...any) ([]*Semester, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+semesterColumns+` FROM semesters `+where+` ORDER BY starts_on`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	semesters := []*Semester{}
+	for rows.Next() {
+		s, err := scanSemester(rows)
+		if err != nil {
+			return nil, err
+		}
+		semesters = append(semesters, s)
+	}
+	return semesters, rows.Err()
+}
+
+// --- ispitni rokovi ---
+
+func (r *CalendarRepository) CreateExamPeriod(ctx context.Context, p *ExamPeriod) (*ExamPeriod, error) {
+	q := `INSERT INTO exam_periods (academic_year_id, name, starts_on, ends_on, registration_opens_at, registration_closes_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + examPeriodColumns
+	created, err := scanExamPeriod(r.db.QueryRow(ctx, q, p.AcademicYearID, p.Name, p.StartsOn, p.EndsOn,
+		p.RegistrationOpensAt, p.RegistrationClosesAt))
+	if err != nil {
+		return nil, calendarErr(err)
+	}
+	return created, nil
+}
+
+func (r *CalendarRepository) GetExamPeriod(ctx context.Context, id uuid.UUID) (*ExamPeriod, error) {
+	return scanExamPeriod(r.db.QueryRow(ctx, `SELECT `+examPeriodColumns+` FROM exam_periods WHERE id = $1`, id))
+}
+
+func (r *CalendarRepository) UpdateExamPeriod(ctx context.Context, p *ExamPeriod) (*ExamPeriod, error) {
+	q := `UPDATE exam_periods SET name = $2, starts_on = $3, ends_on = $4, registration_opens_at = $5, registration_closes_at = $6
+		WHERE id = $1
+		RETURNING ` + examPeriodColumns
+	updated, err := scanExamPeriod(r.db.QueryRow(ctx, q, p.ID, p.Name, p.StartsOn, p.EndsOn,
+		p.RegistrationOpensAt, p.RegistrationClosesAt))
+	if err != nil {
+		return nil, calendarErr(err)
+	}
+	return updated, nil
+}
+
+func (r *CalendarRepository) DeleteExamPeriod(ctx context.Context, id uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM exam_periods WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrCalendarNotFound
+	}
+	return nil
+}
+
+// ExamPeriodOn vraca ispitni rok u koji pada dan t
+func (r *CalendarRepository) ExamPeriodOn(ctx context.Context, t time.Time) (*ExamPeriod, error) {
+	q := `SELECT ` + examPeriodColumns + ` FROM exam_periods
+		WHERE starts_on <= $1::date AND ends_on >= $1::date
+		ORDER BY starts_on LIMIT 1`
+	return scanExamPeriod(r.db.QueryRow(ctx, q, t))
+}
+
+// CurrentExamPeriod vraca ispitni rok koji je u toku u trenutku t (od
+// otvaranja prijave do posljednjeg dana roka), a ako takvog nema sljedeci rok
+func (r *CalendarRepository) CurrentExamPeriod(ctx context.Context, t time.Time) (*ExamPeriod, error) {
+	q := `SELECT ` + examPeriodColumns + ` FROM exam_periods
+		WHERE ends_on >= $1::date
+		ORDER BY (registration_opens_at <= $1) DESC, registration_opens_at
+		LIMIT 1`
+	return scanExamPeriod(r.db.QueryRow(ctx, q, t))
+}
+
+// HasExamPeriods vraca da li su ispitni rokovi uopste podeseni
+func (r *CalendarRepository) HasExamPeriods(ctx context.Context) (bool, error) {
+	var ok bool
+	err := r.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM exam_periods)`).Scan(&ok)
+	return ok, err
+}
+
+func (r *CalendarRepository) listExamPeriods(ctx context.Context, where string, args ...any) ([]*ExamPeriod, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+examPeriodColumns+` FROM exam_periods `+where+` ORDER BY starts_on`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	periods := []*ExamPeriod{}
+	for rows.Next() {
+		p, err := scanExamPeriod(rows)
+		if err != nil {
+			return nil, err
+		}
+		periods = append(periods, p)
+	}
+	return periods, rows.Err()
+}
@@ -18,6 +18,8 @@ type Course struct {
 	Ects        string    `json:"ects" db:"ects"`
 	Active      bool      `json:"active" db:"active"`
 	SingletonID uuid.UUID `json:"singletonid" db:"singleton_id"`
+	// najveci broj upisanih studenata; nil = bez ogranicenja
+	Capacity *int `json:"capacity" db:"capacity"`
 }
 
 type Singleton struct {
@@ -37,9 +39,9 @@ func NewCourseRepository(db *pgxpool.Pool) *CourseRepository {
 // Add new course
 func (r *CourseRepository) Add(ctx context.Context, cou *Course) (*Course, error) {
 	query := `
-		INSERT INTO courses (code, name, ects, active, singleton_id)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, code, name, ects, active, singleton_id
+		INSERT INTO courses (code, name, ects, active, singleton_id, capacity)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, code, name, ects, active, singleton_id, capacity
 	`
 
 	cou.ID = uuid.New()
@@ -51,6 +53,7 @@ func (r *CourseRepository) Add(ctx context.Context, cou *Course) (*Course, error
 		cou.Ects,
 		cou.Active,
 		cou.SingletonID,
+		cou.Capacity,
 	).Scan(
 		&created.ID,
 		&created.Code,
@@ -58,6 +61,7 @@ func (r *CourseRepository) Add(ctx context.Context, cou *Course) (*Course, error
 		&created.Ects,
 		&created.Active,
 		&created.SingletonID,
+		&created.Capacity,
 	)
 	if err != nil {
 		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23505" {
@@ -71,7 +75,7 @@ func (r *CourseRepository) Add(ctx context.Context, cou *Course) (*Course, error
 
 // Get course by ID
 func (r *CourseRepository) GetByID(ctx context.Context, id uuid.UUID) (*Course, error) {
-	query := `SELECT id, code, name, ects, active, singleton_id FROM courses WHERE id = $1`
+	query := `SELECT id, code, name, ects, active, singleton_id, capacity FROM courses WHERE id = $1`
 
 	var cou Course
 	err := r.db.QueryRow(ctx, query, id).Scan(
@@ -81,6 +85,7 @@ func (r *CourseRepository) GetByID(ctx context.Context, id uuid.UUID) (*Course,
 		&cou.Ects,
 		&cou.Active,
 		&cou.SingletonID,
+		&cou.Capacity,
 	)
 	if err != nil {
 		return nil, err
@@ -98,7 +103,7 @@ func (r *CourseRepository) GetAll(ctx context.Context, page, limit int) ([]*Cour
 	}
 	offset := (page - 1) * limit
 
-	query := `SELECT id, code, name, ects, active, singleton_id
+	query := `SELECT id, code, name, ects, active, singleton_id, capacity
 	          FROM courses 
 	          ORDER BY code 
 	          LIMIT $1 OFFSET $2`
@@ -119,6 +124,7 @@ func (r *CourseRepository) GetAll(ctx context.Context, page, limit int) ([]*Cour
 			&cou.Ects,
 			&cou.Active,
 			&cou.SingletonID,
+			&cou.Capacity,
 		); err != nil {
 			return nil, 0, err
 		}
@@ -138,9 +144,9 @@ func (r *CourseRepository) GetAll(ctx context.Context, page, limit int) ([]*Cour
 func (r *CourseRepository) Update(ctx context.Context, cou *Course) (*Course, error) {
 	query := `
 		UPDATE courses
-		SET code = $1, name = $2, ects = $3, active = $4, singleton_id = $5
-		WHERE id = $6
-		RETURNING id, code, name, ects, active, singleton_id
+		SET code = $1, name = $2, ects = $3, active = $4, singleton_id = $5, capacity = $6
+		WHERE id = $7
+		RETURNING id, code, name, ects, active, singleton_id, capacity
 	`
 
 	var updated Course
@@ -150,6 +156,7 @@ func (r *CourseRepository) Update(ctx context.Context, cou *Course) (*Course, er
 		cou.Ects,
 		cou.Active,
 		cou.SingletonID,
+		cou.Capacity,
 		cou.ID,
 	).Scan(
 		&updated.ID,
@@ -158,6 +165,7 @@ func (r *CourseRepository) Update(ctx context.Context, cou *Course) (*Course, er
 		&updated.Ects,
 		&updated.Active,
 		&updated.SingletonID,
+		&updated.Capacity,
 	)
 	if err != nil {
 		return nil, err
@@ -227,7 +235,7 @@ func (r *CourseRepository) GetByProgram(ctx context.Context, programID uuid.UUID
 	}
 	offset := (page - 1) * limit
 
-	query := `SELECT id, code, name, ects, active, singleton_id
+	query := `SELECT id, code, name, ects, active, singleton_id, capacity
 	          FROM courses
 	          WHERE singleton_id = $1
 	          ORDER BY code
@@ -249,6 +257,7 @@ func (r *CourseRepository) GetByProgram(ctx context.Context, programID uuid.UUID
 			&cou.Ects,
 			&cou.Active,
 			&cou.SingletonID,
+			&cou.Capacity,
 		); err != nil {
 			return nil, 0, err
 		}
@@ -275,3 +284,22 @@ func (r *CourseRepository) GetUserProgramID(ctx context.Context, email string) (
 	}
 	return programID, nil
 }
+
+// ListPrograms vraca sve studijske programe (bez paginacije)
+func (r *CourseRepository) ListPrograms(ctx context.Context) ([]*Singleton, error) {
+	rows, err := r.db.Query(ctx, `SELECT id, name, ects FROM singleton ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	programs := make([]*Singleton, 0)
+	for rows.Next() {
+		var prog Singleton
+		if err := rows.Scan(&prog.ID, &prog.Name, &prog.Ects); err != nil {
+			return nil, err
+		}
+		programs = append(programs, &prog)
+	}
+	return programs, rows.Err()
+}
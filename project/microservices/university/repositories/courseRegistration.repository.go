@@ -7,7 +7,6 @@ import (
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -53,41 +52,6 @@ func (r *CourseRegistrationRepository) GetByStudentIDAndCourseID(ctx context.Con
 	return &reg, nil
 }
 
-// Register student to course
-func (r *CourseRegistrationRepository) Register(ctx context.Context, courseID uuid.UUID, studentEmail string) (*CourseRegistration, error) {
-	var studentID uuid.UUID
-	q := `SELECT id FROM users WHERE email = $1 AND role = 'student'`
-	if err := r.db.QueryRow(ctx, q, studentEmail).Scan(&studentID); err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, fmt.Errorf("student with email %s not found", studentEmail)
-		}
-		return nil, err
-	}
-
-	reg := &CourseRegistration{
-		ID:        uuid.New(),
-		CourseID:  courseID,
-		StudentID: studentID,
-		Passed:    false,
-	}
-
-	ins := `
-        INSERT INTO course_registrations (id, courseid, studentid, passed)
-        VALUES ($1, $2, $3, $4)
-        RETURNING id, courseid, studentid, createdat, passed
-    `
-	if err := r.db.QueryRow(ctx, ins,
-		reg.ID, reg.CourseID, reg.StudentID, false,
-	).Scan(&reg.ID, &reg.CourseID, &reg.StudentID, &reg.CreatedAt, &reg.Passed); err != nil {
-		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23505" {
-			return nil, fmt.Errorf("student already registered for this course")
-		}
-		return nil, err
-	}
-
-	return reg, nil
-}
-
 // Get all course registrations by student email
 func (r *CourseRegistrationRepository) GetByStudentEmail(ctx context.Context, email string) ([]*CourseRegistration, error) {
 	var studentID uuid.UUID
@@ -123,3 +87,330 @@ func (r *CourseRegistrationRepository) GetByStudentEmail(ctx context.Context, em
 
 	return regs, nil
 }
+
+var (
+	ErrRegistrationCourseNotFound = errors.New("course not found")
+	ErrAlreadyRegistered          = errors.New("student already registered for this course")
+	ErrAlreadyWaitlisted          = errors.New("student is already on the waitlist for this course")
+	ErrNotRegistered              = errors.New("student is not registered or waitlisted for this course")
+	ErrCoursePassed               = errors.New("cannot withdraw from a passed course")
+)
+
+// WaitlistEntry je mjesto studenta na listi cekanja kursa (Position od 1)
+type WaitlistEntry struct {
+	ID         uuid.UUID `json:"id"`
+	CourseID   uuid.UUID `json:"courseid"`
+	StudentID  uuid.UUID `json:"studentid"`
+	CreatedAt  time.Time `json:"createdat"`
+	Position   int       `json:"position"`
+	CourseCode string    `json:"coursecode,omitempty"`
+	CourseName string    `json:"coursename,omitempty"`
+	FullName   string    `json:"fullname,omitempty"`
+	Email      string    `json:"email,omitempty"`
+}
+
+// EnrollResult je ishod upisa: registracija ako je bilo mjesta, inace mjesto
+// na listi cekanja
+type EnrollResult struct {
+	Registration *CourseRegistration `json:"registration,omitempty"`
+	Waitlist     *WaitlistEntry      `json:"waitlist,omitempty"`
+}
+
+// WithdrawResult je ishod odjave: odjava sa kursa (ili sa liste cekanja) i
+// studenti koji su time presli sa liste cekanja na kurs
+type WithdrawResult struct {
+	Registration *CourseRegistration   `json:"registration,omitempty"`
+	Waitlist     *WaitlistEntry        `json:"waitlist,omitempty"`
+	Promoted     []*CourseRegistration `json:"promoted"`
+}
+
+// lockCourse zakljucava red kursa do kraja transakcije; svi upisi, odjave i
+// prelasci sa liste cekanja jednog kursa se tako izvrsavaju jedan po jedan
+func lockCourse(ctx context.Context, tx pgx.Tx, courseID uuid.UUID) (*int, error) {
+	var capacity *int
+	err := tx.QueryRow(ctx, `SELECT capacity FROM courses WHERE id = $1 FOR UPDATE`, courseID).Scan(&capacity)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrRegistrationCourseNotFound
+	}
+	return capacity, err
+}
+
+// registrationPassed je uslov da je upis kursa (cr) polozen: polozen ispit iz
+// tog kursa ili upis oznacen kao polozen, kao u provjeri preduslova
+const registrationPassed = `(cr.passed OR EXISTS (
+	SELECT 1 FROM exam_registrations er
+	JOIN exams e ON er.examid = e.id
+	WHERE er.studentid = cr.studentid AND er.passed AND e.courseid::uuid = cr.courseid
+))`
+
+// seatsTaken broji upisane studente koji kurs jos nisu polozili
+func seatsTaken(ctx context.Context, tx pgx.Tx, courseID uuid.UUID) (int, error) {
+	var taken int
+	err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM course_registrations cr WHERE cr.courseid = $1 AND NOT `+registrationPassed, courseID).Scan(&taken)
+	return taken, err
+}
+
+func insertRegistration(ctx context.Context, tx pgx.Tx, courseID, studentID uuid.UUID) (*CourseRegistration, error) {
+	reg := &CourseRegistration{}
+	ins := `
+		INSERT INTO course_registrations (id, courseid, studentid, passed)
+		VALUES ($1, $2, $3, FALSE)
+		RETURNING id, courseid, studentid, createdat, passed
+	`
+	err := tx.QueryRow(ctx, ins, uuid.New(), courseID, studentID).
+		Scan(&reg.ID, &reg.CourseID, &reg.StudentID, &reg.CreatedAt, &reg.Passed)
+	if err != nil {
+		return nil, err
+	}
+	return reg, nil
+}
+
+// Enroll upisuje studenta na kurs ako ima slobodnih mjesta, a inace ga
+// stavlja na kraj liste cekanja
+func (r *CourseRegistrationRepository) Enroll(ctx context.Context, courseID, studentID uuid.UUID) (*EnrollResult, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	capacity, err := lockCourse(ctx, tx, courseID)
+	if err != nil {
+		return nil, err
+	}
+
+	var registered, waitlisted bool
+	err = tx.QueryRow(ctx, `
+		SELECT
+			EXISTS (SELECT 1 FROM course_registrations WHERE courseid = $1 AND studentid = $2),
+			EXISTS (SELECT 1 FROM course_waitlist WHERE course_id = $1 AND student_id = $2)
+	`, courseID, studentID).Scan(&registered, &waitlisted)
+	if err != nil {
+		return nil, err
+	}
+	if registered {
+		return nil, ErrAlreadyRegistered
+	}
+	if waitlisted {
+		return nil, ErrAlreadyWaitlisted
+	}
+
+	taken, err := seatsTaken(ctx, tx, courseID)
+	if err != nil {
+		return nil, err
+	}
+	var waiting int
+	if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM course_waitlist WHERE course_id = $1`, courseID).Scan(&waiting); err != nil {
+		return nil, err
+	}
+
+	result := &EnrollResult{}
+	// slobodno mjesto ne preskace studente koji vec cekaju
+	if capacity == nil || (taken < *capacity && waiting == 0) {
+		if result.Registration, err = insertRegistration(ctx, tx, courseID, studentID); err != nil {
+			return nil, err
+		}
+	} else {
+		entry := WaitlistEntry{CourseID: courseID, StudentID: studentID, Position: waiting + 1}
+		err := tx.QueryRow(ctx, `
+			INSERT INTO course_waitlist (course_id, student_id) VALUES ($1, $2)
+			RETURNING id, created_at
+		`, courseID, studentID).Scan(&entry.ID, &entry.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		result.Waitlist = &entry
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Withdraw odjavljuje studenta sa kursa ili sa liste cekanja; oslobodjeno
+// mjesto dobija prvi student sa liste cekanja
+func (r *CourseRegistrationRepository) Withdraw(ctx context.Context, courseID, studentID uuid.UUID) (*WithdrawResult, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	capacity, err := lockCourse(ctx, tx, courseID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &WithdrawResult{Promoted: []*CourseRegistration{}}
+
+	var entry WaitlistEntry
+	err = tx.QueryRow(ctx, `
+		DELETE FROM course_waitlist WHERE course_id = $1 AND student_id = $2
+		RETURNING id, course_id, student_id, created_at
+	`, courseID, studentID).Scan(&entry.ID, &entry.CourseID, &entry.StudentID, &entry.CreatedAt)
+	switch {
+	case err == nil:
+		result.Waitlist = &entry
+	case !errors.Is(err, pgx.ErrNoRows):
+		return nil, err
+	default:
+		var reg CourseRegistration
+		err := tx.QueryRow(ctx, `
+			SELECT cr.id, cr.courseid, cr.studentid, cr.createdat, `+registrationPassed+`
+			FROM course_registrations cr WHERE cr.courseid = $1 AND cr.studentid = $2
+		`, courseID, studentID).Scan(&reg.ID, &reg.CourseID, &reg.StudentID, &reg.CreatedAt, &reg.Passed)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotRegistered
+		}
+		if err != nil {
+			return nil, err
+		}
+		if reg.Passed {
+			return nil, ErrCoursePassed
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM course_registrations WHERE id = $1`, reg.ID); err != nil {
+			return nil, err
+		}
+		result.Registration = &reg
+
+		if result.Promoted, err = promote(ctx, tx, courseID, capacity); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// FillFromWaitlist popunjava slobodna mjesta sa liste cekanja (npr. nakon
+// povecanja kapaciteta kursa)
+func (r *CourseRegistrationRepository) FillFromWaitlist(ctx context.Context, courseID uuid.UUID) ([]*CourseRegistration, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	capacity, err := lockCourse(ctx, tx, courseID)
+	if err != nil {
+		return nil, err
+	}
+	promoted, err := promote(ctx, tx, courseID, capacity)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return promoted, nil
+}
+
+// promote prebacuje studente sa pocetka liste cekanja na kurs dok ima
+// slobodnih mjesta; pozivalac drzi zakljucan red kursa
+func promote(ctx context.Context, tx pgx.Tx, courseID uuid.UUID, capacity *int) ([]*CourseRegistration, error) {
+	promoted := []*CourseRegistration{}
+	taken, err := seatsTaken(ctx, tx, courseID)
+	if err != nil {
+		return nil, err
+	}
+
+	for capacity == nil || taken < *capacity {
+		var studentID uuid.UUID
+		err := tx.QueryRow(ctx, `
+			DELETE FROM course_waitlist
+			WHERE id = (SELECT id FROM course_waitlist WHERE course_id = $1 ORDER BY created_at, id LIMIT 1)
+			RETURNING student_id
+		`, courseID).Scan(&studentID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		reg, err := insertRegistration(ctx, tx, courseID, studentID)
+		if err != nil {
+			return nil, err
+		}
+		promoted = append(promoted, reg)
+		taken++
+	}
+	return promoted, nil
+}
+
+// WaitlistPosition vraca mjesto studenta na listi cekanja kursa
+func (r *CourseRegistrationRepository) WaitlistPosition(ctx context.Context, courseID, studentID uuid.UUID) (*WaitlistEntry, error) {
+	query := `
+		SELECT id, course_id, student_id, created_at, position
+		FROM (
+			SELECT *, ROW_NUMBER() OVER (ORDER BY created_at, id) AS position
+			FROM course_waitlist WHERE course_id = $1
+		) w
+		WHERE student_id = $2
+	`
+	var e WaitlistEntry
+	err := r.db.QueryRow(ctx, query, courseID, studentID).Scan(&e.ID, &e.CourseID, &e.StudentID, &e.CreatedAt, &e.Position)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotRegistered
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// StudentWaitlist vraca sve liste cekanja na kojima je student, sa mjestom
+func (r *CourseRegistrationRepository) StudentWaitlist(ctx context.Context, studentID uuid.UUID) ([]*WaitlistEntry, error) {
+	query := `
+		SELECT w.id, w.course_id, w.student_id, w.created_at, w.position, c.code, c.name
+		FROM (
+			SELECT *, ROW_NUMBER() OVER (PARTITION BY course_id ORDER BY created_at, id) AS position
+			FROM course_waitlist
+			WHERE course_id IN (SELECT course_id FROM course_waitlist WHERE student_id = $1)
+		) w
+		JOIN courses c ON c.id = w.course_id
+		WHERE w.student_id = $1
+		ORDER BY w.created_at
+	`
+	return r.queryWaitlist(ctx, query, studentID, true)
+}
+
+// CourseWaitlist vraca listu cekanja kursa po redoslijedu
+func (r *CourseRegistrationRepository) CourseWaitlist(ctx context.Context, courseID uuid.UUID) ([]*WaitlistEntry, error) {
+	query := `
+		SELECT w.id, w.course_id, w.student_id, w.created_at,
+			ROW_NUMBER() OVER (ORDER BY w.created_at, w.id), u.fullname, u.email
+		FROM course_waitlist w
+		JOIN users u ON u.id = w.student_id
+		WHERE w.course_id = $1
+		ORDER BY w.created_at, w.id
+	`
+	return r.queryWaitlist(ctx, query, courseID, false)
+}
+
+// queryWaitlist cita stavke liste cekanja; posljednje dvije kolone upita su
+// sifra i naziv kursa (byStudent) ili ime i email studenta
+func (r *CourseRegistrationRepository) queryWaitlist(ctx context.Context, query string, arg uuid.UUID, byStudent bool) ([]*WaitlistEntry, error) {
+	rows, err := r.db.Query(ctx, query, arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*WaitlistEntry{}
+	for rows.Next() {
+		var e WaitlistEntry
+		extra := []any{&e.FullName, &e.Email}
+		if byStudent {
+			extra = []any{&e.CourseCode, &e.CourseName}
+		}
+		if err := rows.Scan(append([]any{&e.ID, &e.CourseID, &e.StudentID, &e.CreatedAt, &e.Position}, extra...)...); err != nil {
+			return nil, err
+		}
+		entries = append(entries, &e)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,167 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrCredentialNotFound = errors.New("credential not found")
+	ErrCredentialExists   = errors.New("student already has an active credential")
+)
+
+// GraduationCredential je izdata diploma; Token je potpisani JWT koji student
+// predaje poslodavcima
+type GraduationCredential struct {
+	ID               uuid.UUID  `json:"id"`
+	StudentID        uuid.UUID  `json:"studentid"`
+	KeyID            string     `json:"kid"`
+	Token            string     `json:"token"`
+	Program          *string    `json:"program"`
+	GraduationDate   time.Time  `json:"graduationdate"`
+	FinalAverage     *float64   `json:"finalaverage"`
+	Ects             int        `json:"ects"`
+	IssuedBy         *uuid.UUID `json:"issuedby"`
+	IssuedAt         time.Time  `json:"issuedat"`
+	RevokedAt        *time.Time `json:"revokedat"`
+	RevokedBy        *uuid.UUID `json:"revokedby"`
+	RevocationReason *string    `json:"revocationreason"`
+}
+
+// CredentialKey je javni kljuc za provjeru diploma (base64url)
+type CredentialKey struct {
+	KeyID     string
+	PublicKey string
+}
+
+// RevokedCredential je stavka javne liste opozvanih diploma
+type RevokedCredential struct {
+	ID        uuid.UUID `json:"id"`
+	RevokedAt time.Time `json:"revokedAt"`
+}
+
+type CredentialRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewCredentialRepository(db *pgxpool.Pool) *CredentialRepository {
+	return &CredentialRepository{db: db}
+}
+
+const credentialColumns = `id, student_id, kid, token, program, graduation_date, final_average, ects,
+	issued_by, issued_at, revoked_at, revoked_by, revocation_reason`
+
+func scanCredential(row pgx.Row) (*GraduationCredential, error) {
+	var c GraduationCredential
+	err := row.Scan(&c.ID, &c.StudentID, &c.KeyID, &c.Token, &c.Program, &c.GraduationDate, &c.FinalAverage, &c.Ects,
+		&c.IssuedBy, &c.IssuedAt, &c.RevokedAt, &c.RevokedBy, &c.RevocationReason)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrCredentialNotFound
+		}
+		return nil, err
+	}
+	return &c, nil
+}
+
+// RegisterKey objavljuje javni kljuc za potpisivanje (ako vec nije objavljen)
+func (r *CredentialRepository) RegisterKey(ctx context.Context, kid, publicKey string) error {
+	const q = `INSERT INTO credential_signing_keys (kid, public_key) VALUES ($1, $2) ON CONFLICT (kid) DO NOTHING`
+	_, err := r.db.Exec(ctx, q, kid, publicKey)
+	return err
+}
+
+// Keys vraca sve javne kljuceve kojima su diplome ikad potpisane
+func (r *CredentialRepository) Keys(ctx context.Context) ([]CredentialKey, error) {
+	rows, err := r.db.Query(ctx, `SELECT kid, public_key FROM credential_signing_keys ORDER BY created_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []CredentialKey{}
+	for rows.Next() {
+		var k CredentialKey
+		if err := rows.Scan(&k.KeyID, &k.PublicKey); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+func (r *CredentialRepository) Create(ctx context.Context, c *GraduationCredential) (*GraduationCredential, error) {
+	q := `
+		INSERT INTO graduation_credentials (id, student_id, kid, token, program, graduation_date, final_average, ects, issued_by, issued_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING ` + credentialColumns
+	created, err := scanCredential(r.db.QueryRow(ctx, q, c.ID, c.StudentID, c.KeyID, c.Token, c.Program,
+		c.GraduationDate, c.FinalAverage, c.Ects, c.IssuedBy, c.IssuedAt))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrCredentialExists
+		}
+		return nil, err
+	}
+	return created, nil
+}
+
+func (r *CredentialRepository) GetByID(ctx context.Context, id uuid.UUID) (*GraduationCredential, error) {
+	q := `SELECT ` + credentialColumns + ` FROM graduation_credentials WHERE id = $1`
+	return scanCredential(r.db.QueryRow(ctx, q, id))
+}
+
+// ListByStudent vraca diplome studenta, najnovija prva
+func (r *CredentialRepository) ListByStudent(ctx context.Context, studentID uuid.UUID) ([]*GraduationCredential, error) {
+	q := `SELECT ` + credentialColumns + ` FROM graduation_credentials WHERE student_id = $1 ORDER BY issued_at DESC`
+	rows, err := r.db.Query(ctx, q, studentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	creds := []*GraduationCredential{}
+	for rows.Next() {
+		c, err := scanCredential(rows)
+		if err != nil {
+			return nil, err
+		}
+		creds = append(creds, c)
+	}
+	return creds, rows.Err()
+}
+
+// Revoke opoziva vazecu diplomu
+func (r *CredentialRepository) Revoke(ctx context.Context, id uuid.UUID, revokedBy *uuid.UUID, reason string) (*GraduationCredential, error) {
+	q := `
+		UPDATE graduation_credentials SET revoked_at = now(), revoked_by = $2, revocation_reason = $3
+		WHERE id = $1 AND revoked_at IS NULL
+		RETURNING ` + credentialColumns
+	return scanCredential(r.db.QueryRow(ctx, q, id, revokedBy, reason))
+}
+
+// Revoked vraca sve opozvane diplome (javna lista za provjeru bez mreze)
+func (r *CredentialRepository) Revoked(ctx context.Context) ([]RevokedCredential, error) {
+	rows, err := r.db.Query(ctx, `SELECT id, revoked_at FROM graduation_credentials WHERE revoked_at IS NOT NULL ORDER BY revoked_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revoked := []RevokedCredential{}
+	for rows.Next() {
+		var c RevokedCredential
+		if err := rows.Scan(&c.ID, &c.RevokedAt); err != nil {
+			return nil, err
+		}
+		revoked = append(revoked, c)
+	}
+	return revoked, rows.Err()
+}
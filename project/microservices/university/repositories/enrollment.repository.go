@@ -0,0 +1,355 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrEnrollmentCourseNotFound  = errors.New("course not found")
+	ErrEnrollmentProgramNotFound = errors.New("program not found")
+	ErrEnrollmentStudentNotFound = errors.New("student not found")
+)
+
+// PrerequisiteCycleError znaci da bi novi preduslovi napravili ciklus;
+// Path su sifre kurseva na ciklusu (prvi i posljednji su isti kurs)
+type PrerequisiteCycleError struct {
+	Path []string
+}
+
+func (e *PrerequisiteCycleError) Error() string {
+	return "prerequisite cycle: " + strings.Join(e.Path, " -> ")
+}
+
+// ProgramCourse je kurs u planu studijskog programa
+type ProgramCourse struct {
+	ProgramID   uuid.UUID `json:"programid"`
+	CourseID    uuid.UUID `json:"courseid"`
+	Code        string    `json:"code"`
+	Name        string    `json:"name"`
+	Ects        string    `json:"ects"`
+	Mandatory   bool      `json:"mandatory"`
+	YearOfStudy *int      `json:"yearofstudy"`
+}
+
+// UnmetRequirement je uslov za upis kursa koji student ne ispunjava
+type UnmetRequirement struct {
+	Type       string     `json:"type"` // program, year, prerequisite
+	Message    string     `json:"message"`
+	CourseID   *uuid.UUID `json:"courseid,omitempty"`
+	CourseCode string     `json:"coursecode,omitempty"`
+}
+
+type EnrollmentRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewEnrollmentRepository(db *pgxpool.Pool) *EnrollmentRepository {
+	return &EnrollmentRepository{db: db}
+}
+
+// Prerequisites vraca direktne preduslove kursa
+func (r *EnrollmentRepository) Prerequisites(ctx context.Context, courseID uuid.UUID) ([]*Course, error) {
+	query := `
+		SELECT c.id, c.code, c.name, c.ects, c.active, c.singleton_id, c.capacity
+		FROM course_prerequisites cp
+		JOIN courses c ON c.id = cp.prerequisite_id
+		WHERE cp.course_id = $1
+		ORDER BY c.code
+	`
+	rows, err := r.db.Query(ctx, query, courseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	courses := make([]*Course, 0)
+	for rows.Next() {
+		var cou Course
+		if err := rows.Scan(&cou.ID, &cou.Code, &cou.Name, &cou.Ects, &cou.Active, &cou.SingletonID, &cou.Capacity); err != nil {
+			return nil, err
+		}
+		courses = append(courses, &cou)
+	}
+	return courses, rows.Err()
+}
+
+// SetPrerequisites zamjenjuje preduslove kursa; odbija izmjenu koja bi
+// napravila ciklus u grafu preduslova
+func (r *EnrollmentRepository) SetPrerequisites(ctx context.Context, courseID uuid.UUID, prerequisites []uuid.UUID) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// istovremene izmjene grafa se serijalizuju kako provjera ciklusa ne bi
+	// radila nad zastarjelim grafom
+	if _, err := tx.Exec(ctx, `LOCK TABLE course_prerequisites IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+		return err
+	}
+
+	var exists bool
+	if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM courses WHERE id = $1)`, courseID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrEnrollmentCourseNotFound
+	}
+
+	graph := map[uuid.UUID][]uuid.UUID{}
+	rows, err := tx.Query(ctx, `SELECT course_id, prerequisite_id FROM course_prerequisites WHERE course_id <> $1`, courseID)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var c, p uuid.UUID
+		if err := rows.Scan(&c, &p); err != nil {
+			rows.Close()
+			return err
+		}
+		graph[c] = append(graph[c], p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	graph[courseID] = prerequisites
+
+	if cycle := findCycle(graph, courseID); cycle != nil {
+		codes, err := courseCodes(ctx, tx, cycle)
+		if err != nil {
+			return err
+		}
+		return &PrerequisiteCycleError{Path: codes}
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM course_prerequisites WHERE course_id = $1`, courseID); err != nil {
+		return err
+	}
+	for _, p := range prerequisites {
+		_, err := tx.Exec(ctx, `INSERT INTO course_prerequisites (course_id, prerequisite_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, courseID, p)
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+				return fmt.Errorf("%w: %s", ErrEnrollmentCourseNotFound, p)
+			}
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// findCycle trazi put od start nazad do start u grafu kurs -> preduslovi;
+// vraca ga kao niz kurseva (start na pocetku i kraju) ili nil
+func findCycle(graph map[uuid.UUID][]uuid.UUID, start uuid.UUID) []uuid.UUID {
+	visited := map[uuid.UUID]bool{}
+	var path []uuid.UUID
+
+	var visit func(id uuid.UUID) bool
+	visit = func(id uuid.UUID) bool {
+		path = append(path, id)
+		for _, next := range graph[id] {
+			if next == start {
+				path = append(path, start)
+				return true
+			}
+			if !visited[next] {
+				visited[next] = true
+				if visit(next) {
+					return true
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		return false
+	}
+
+	if visit(start) {
+		return path
+	}
+	return nil
+}
+
+func courseCodes(ctx context.Context, tx pgx.Tx, ids []uuid.UUID) ([]string, error) {
+	rows, err := tx.Query(ctx, `SELECT id, code FROM courses WHERE id = ANY($1)`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byID := map[uuid.UUID]string{}
+	for rows.Next() {
+		var id uuid.UUID
+		var code string
+		if err := rows.Scan(&id, &code); err != nil {
+			return nil, err
+		}
+		byID[id] = code
+	}
+	codes := make([]string, len(ids))
+	for i, id := range ids {
+		codes[i] = byID[id]
+		if codes[i] == "" {
+			codes[i] = id.String()
+		}
+	}
+	return codes, rows.Err()
+}
+
+// Curriculum vraca plan studijskog programa po godinama studija; kursevi
+// programa koji jos nisu u planu vode se kao obavezni, bez godine studija
+func (r *EnrollmentRepository) Curriculum(ctx context.Context, programID uuid.UUID) ([]*ProgramCourse, error) {
+	query := `
+		SELECT $1::uuid, c.id, c.code, c.name, c.ects, COALESCE(pc.mandatory, TRUE), pc.year_of_study
+		FROM courses c
+		LEFT JOIN program_courses pc ON pc.course_id = c.id AND pc.singleton_id = $1
+		WHERE pc.singleton_id IS NOT NULL OR c.singleton_id = $1
+		ORDER BY pc.year_of_study NULLS LAST, COALESCE(pc.mandatory, TRUE) DESC, c.code
+	`
+	rows, err := r.db.Query(ctx, query, programID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	courses := make([]*ProgramCourse, 0)
+	for rows.Next() {
+		var pc ProgramCourse
+		if err := rows.Scan(&pc.ProgramID, &pc.CourseID, &pc.Code, &pc.Name, &pc.Ects, &pc.Mandatory, &pc.YearOfStudy); err != nil {
+			return nil, err
+		}
+		courses = append(courses, &pc)
+	}
+	return courses, rows.Err()
+}
+
+// SetProgramCourse dodaje kurs u plan programa ili mijenja njegove oznake
+func (r *EnrollmentRepository) SetProgramCourse(ctx context.Context, programID, courseID uuid.UUID, mandatory bool, yearOfStudy *int) error {
+	query := `
+		INSERT INTO program_courses (singleton_id, course_id, mandatory, year_of_study)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (singleton_id, course_id) DO UPDATE
+		SET mandatory = EXCLUDED.mandatory, year_of_study = EXCLUDED.year_of_study
+	`
+	_, err := r.db.Exec(ctx, query, programID, courseID, mandatory, yearOfStudy)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		if pgErr.ConstraintName == "program_courses_singleton_id_fkey" {
+			return ErrEnrollmentProgramNotFound
+		}
+		return ErrEnrollmentCourseNotFound
+	}
+	return err
+}
+
+// RemoveProgramCourse uklanja kurs iz plana programa
+func (r *EnrollmentRepository) RemoveProgramCourse(ctx context.Context, programID, courseID uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM program_courses WHERE singleton_id = $1 AND course_id = $2`, programID, courseID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrEnrollmentCourseNotFound
+	}
+	return nil
+}
+
+// SetYearOfStudy postavlja godinu studija studenta (nil brise)
+func (r *EnrollmentRepository) SetYearOfStudy(ctx context.Context, studentID uuid.UUID, year *int) error {
+	tag, err := r.db.Exec(ctx, `UPDATE users SET year_of_study = $2 WHERE id = $1 AND role = 'student'`, studentID, year)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrEnrollmentStudentNotFound
+	}
+	return nil
+}
+
+// Check vraca uslove za upis kursa koje student ne ispunjava: kurs mora biti
+// u planu studentovog programa, ne smije biti sa vise godine studija, a svi
+// preduslovi moraju biti polozeni
+func (r *EnrollmentRepository) Check(ctx context.Context, studentID, courseID uuid.UUID) ([]UnmetRequirement, error) {
+	unmet := []UnmetRequirement{}
+
+	var programID *uuid.UUID
+	var studentYear *int
+	err := r.db.QueryRow(ctx, `SELECT singleton_id, year_of_study FROM users WHERE id = $1 AND role = 'student'`, studentID).
+		Scan(&programID, &studentYear)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrEnrollmentStudentNotFound
+		}
+		return nil, err
+	}
+
+	if programID == nil {
+		unmet = append(unmet, UnmetRequirement{Type: "program", Message: "you are not enrolled in a study program"})
+	} else {
+		// kurs je u programu ako je u planu programa ili mu program pripada
+		var inProgram bool
+		var courseYear *int
+		query := `
+			SELECT
+				EXISTS (SELECT 1 FROM program_courses WHERE singleton_id = $1 AND course_id = $2)
+				OR EXISTS (SELECT 1 FROM courses WHERE id = $2 AND singleton_id = $1),
+				(SELECT year_of_study FROM program_courses WHERE singleton_id = $1 AND course_id = $2)
+		`
+		if err := r.db.QueryRow(ctx, query, *programID, courseID).Scan(&inProgram, &courseYear); err != nil {
+			return nil, err
+		}
+		if !inProgram {
+			unmet = append(unmet, UnmetRequirement{Type: "program", Message: "course is not part of your study program"})
+		} else if courseYear != nil && studentYear != nil && *courseYear > *studentYear {
+			unmet = append(unmet, UnmetRequirement{
+				Type:    "year",
+				Message: fmt.Sprintf("course is taught in year %d of study, you are in year %d", *courseYear, *studentYear),
+			})
+		}
+	}
+
+	// preduslov je polozen ako je polozen ispit ili je upis kursa oznacen kao polozen
+	query := `
+		SELECT c.id, c.code, c.name
+		FROM course_prerequisites cp
+		JOIN courses c ON c.id = cp.prerequisite_id
+		WHERE cp.course_id = $2
+		AND NOT EXISTS (
+			SELECT 1 FROM exam_registrations er
+			JOIN exams e ON er.examid = e.id
+			WHERE er.studentid = $1 AND er.passed AND e.courseid::uuid = c.id
+		)
+		AND NOT EXISTS (
+			SELECT 1 FROM course_registrations cr
+			WHERE cr.studentid = $1 AND cr.courseid = c.id AND cr.passed
+		)
+		ORDER BY c.code
+	`
+	rows, err := r.db.Query(ctx, query, studentID, courseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id uuid.UUID
+		var code, name string
+		if err := rows.Scan(&id, &code, &name); err != nil {
+			return nil, err
+		}
+		unmet = append(unmet, UnmetRequirement{
+			Type:       "prerequisite",
+			Message:    fmt.Sprintf("prerequisite %s (%s) not passed", code, name),
+			CourseID:   &id,
+			CourseCode: code,
+		})
+	}
+	return unmet, rows.Err()
+}
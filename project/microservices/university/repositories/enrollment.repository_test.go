@@ -0,0 +1,52 @@
+package repositories
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestFindCycle(t *testing.T) {
+	// kursevi A..E sa stalnim ID-jevima, da bi putanje bile citljive
+	ids := map[string]uuid.UUID{}
+	names := map[uuid.UUID]string{}
+	for _, n := range []string{"A", "B", "C", "D", "E"} {
+		id := uuid.NewSHA1(uuid.NameSpaceOID, []byte(n))
+		ids[n], names[id] = id, n
+	}
+
+	tests := []struct {
+		name  string
+		edges map[string][]string // kurs -> preduslovi
+		want  []string
+	}{
+		{"no prerequisites", nil, nil},
+		{"chain", map[string][]string{"A": {"B"}, "B": {"C"}}, nil},
+		{"diamond", map[string][]string{"A": {"B", "C"}, "B": {"D"}, "C": {"D"}}, nil},
+		{"self prerequisite", map[string][]string{"A": {"A"}}, []string{"A", "A"}},
+		{"direct cycle", map[string][]string{"A": {"B"}, "B": {"A"}}, []string{"A", "B", "A"}},
+		{"long cycle", map[string][]string{"A": {"B"}, "B": {"C"}, "C": {"D"}, "D": {"A"}}, []string{"A", "B", "C", "D", "A"}},
+		{"cycle after a dead end", map[string][]string{"A": {"B", "C"}, "B": {"E"}, "C": {"A"}}, []string{"A", "C", "A"}},
+		{"cycle that does not include start", map[string][]string{"A": {"B"}, "B": {"C"}, "C": {"B"}}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			graph := map[uuid.UUID][]uuid.UUID{}
+			for from, to := range tt.edges {
+				for _, n := range to {
+					graph[ids[from]] = append(graph[ids[from]], ids[n])
+				}
+			}
+
+			var got []string
+			for _, id := range findCycle(graph, ids["A"]) {
+				got = append(got, names[id])
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("findCycle = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
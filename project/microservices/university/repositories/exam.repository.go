@@ -15,6 +15,8 @@ type Exam struct {
 	ExamTime    time.Time `json:"examtime" db:"examtime"`
 	CourseID    string    `json:"courseid" db:"courseid"`
 	ProfessorID string    `json:"professorid" db:"professorid"`
+	// ispitni rok kome ispit pripada
+	ExamPeriodID *uuid.UUID `json:"examperiodid" db:"exam_period_id"`
 }
 
 type ExamRepository struct {
@@ -28,9 +30,9 @@ func NewExamRepository(db *pgxpool.Pool) *ExamRepository {
 // Add new exam
 func (r *ExamRepository) Add(ctx context.Context, exam *Exam) (*Exam, error) {
 	query := `
-		INSERT INTO exams (examtime, courseid, professorid)
-		VALUES ($1, $2, $3)
-		RETURNING id, examtime, courseid, professorid
+		INSERT INTO exams (examtime, courseid, professorid, exam_period_id)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, examtime, courseid, professorid, exam_period_id
 	`
 
 	exam.ID = uuid.New()
@@ -40,11 +42,13 @@ func (r *ExamRepository) Add(ctx context.Context, exam *Exam) (*Exam, error) {
 		exam.ExamTime,
 		exam.CourseID,
 		exam.ProfessorID,
+		exam.ExamPeriodID,
 	).Scan(
 		&created.ID,
 		&created.ExamTime,
 		&created.CourseID,
 		&created.ProfessorID,
+		&created.ExamPeriodID,
 	)
 
 	if err != nil {
@@ -60,7 +64,7 @@ func (r *ExamRepository) Add(ctx context.Context, exam *Exam) (*Exam, error) {
 
 // Get exam by ID
 func (r *ExamRepository) GetByID(ctx context.Context, id uuid.UUID) (*Exam, error) {
-	query := `SELECT id, examtime, courseid, professorid FROM exams WHERE id = $1`
+	query := `SELECT id, examtime, courseid, professorid, exam_period_id FROM exams WHERE id = $1`
 
 	var exam Exam
 	err := r.db.QueryRow(ctx, query, id).Scan(
@@ -68,6 +72,7 @@ func (r *ExamRepository) GetByID(ctx context.Context, id uuid.UUID) (*Exam, erro
 		&exam.ExamTime,
 		&exam.CourseID,
 		&exam.ProfessorID,
+		&exam.ExamPeriodID,
 	)
 	if err != nil {
 		return nil, err
@@ -85,7 +90,7 @@ func (r *ExamRepository) GetAll(ctx context.Context, page, limit int) ([]*Exam,
 	}
 	offset := (page - 1) * limit
 
-	query := `SELECT id, examtime, courseid, professorid 
+	query := `SELECT id, examtime, courseid, professorid, exam_period_id
 	          FROM exams 
 	          ORDER BY examtime 
 	          LIMIT $1 OFFSET $2`
@@ -104,6 +109,7 @@ func (r *ExamRepository) GetAll(ctx context.Context, page, limit int) ([]*Exam,
 			&exam.ExamTime,
 			&exam.CourseID,
 			&exam.ProfessorID,
+			&exam.ExamPeriodID,
 		); err != nil {
 			return nil, 0, err
 		}
@@ -123,9 +129,9 @@ func (r *ExamRepository) GetAll(ctx context.Context, page, limit int) ([]*Exam,
 func (r *ExamRepository) Update(ctx context.Context, exam *Exam) (*Exam, error) {
 	query := `
 		UPDATE exams
-		SET examtime = $1, courseid = $2, professorid = $3
-		WHERE id = $4
-		RETURNING id, examtime, courseid, professorid
+		SET examtime = $1, courseid = $2, professorid = $3, exam_period_id = $4
+		WHERE id = $5
+		RETURNING id, examtime, courseid, professorid, exam_period_id
 	`
 
 	var updated Exam
@@ -133,12 +139,14 @@ func (r *ExamRepository) Update(ctx context.Context, exam *Exam) (*Exam, error)
 		exam.ExamTime,
 		exam.CourseID,
 		exam.ProfessorID,
+		exam.ExamPeriodID,
 		exam.ID,
 	).Scan(
 		&updated.ID,
 		&updated.ExamTime,
 		&updated.CourseID,
 		&updated.ProfessorID,
+		&updated.ExamPeriodID,
 	)
 	if err != nil {
 		return nil, err
@@ -146,6 +154,36 @@ func (r *ExamRepository) Update(ctx context.Context, exam *Exam) (*Exam, error)
 	return &updated, nil
 }
 
+// Get exams of an exam period
+func (r *ExamRepository) GetByPeriod(ctx context.Context, periodID uuid.UUID) ([]*Exam, error) {
+	query := `SELECT id, examtime, courseid, professorid, exam_period_id
+	          FROM exams
+	          WHERE exam_period_id = $1
+	          ORDER BY examtime`
+
+	rows, err := r.db.Query(ctx, query, periodID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	exams := make([]*Exam, 0)
+	for rows.Next() {
+		var exam Exam
+		if err := rows.Scan(
+			&exam.ID,
+			&exam.ExamTime,
+			&exam.CourseID,
+			&exam.ProfessorID,
+			&exam.ExamPeriodID,
+		); err != nil {
+			return nil, err
+		}
+		exams = append(exams, &exam)
+	}
+	return exams, rows.Err()
+}
+
 // Delete exam
 func (r *ExamRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	query := `DELETE FROM exams WHERE id = $1`
@@ -19,8 +19,15 @@ type ExamRegistration struct {
 	CreatedAt time.Time `json:"createdat" db:"createdat"`
 	Grade     *int      `json:"grade,omitempty" db:"grade"`
 	Passed    bool      `json:"passed" db:"passed"`
+	// student nije izasao na ispit (ishod bez ocjene)
+	Absent bool `json:"absent" db:"absent"`
 }
 
+var (
+	ErrExamRegistrationNotFound = errors.New("exam registration not found")
+	ErrExamOutcomeRecorded      = errors.New("grade or absence already recorded for this exam")
+)
+
 type ExamRegistrationRepository struct {
 	db *pgxpool.Pool
 }
@@ -51,12 +58,12 @@ func (r *ExamRegistrationRepository) Register(ctx context.Context, examID uuid.U
 	ins := `
     INSERT INTO exam_registrations (id, examid, studentid, createdat, grade, passed)
     VALUES ($1, $2, $3, $4, $5, $6)
-    RETURNING id, examid, studentid, createdat, grade, passed
+    RETURNING id, examid, studentid, createdat, grade, passed, absent
 `
 
 	if err := r.db.QueryRow(ctx, ins,
 		reg.ID, reg.ExamID, reg.StudentID, reg.CreatedAt, reg.Grade, reg.Passed,
-	).Scan(&reg.ID, &reg.ExamID, &reg.StudentID, &reg.CreatedAt, &reg.Grade, &reg.Passed); err != nil {
+	).Scan(&reg.ID, &reg.ExamID, &reg.StudentID, &reg.CreatedAt, &reg.Grade, &reg.Passed, &reg.Absent); err != nil {
 		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23505" {
 			return nil, fmt.Errorf("student already registered for this exam")
 		}
@@ -68,7 +75,7 @@ func (r *ExamRegistrationRepository) Register(ctx context.Context, examID uuid.U
 
 func (r *ExamRegistrationRepository) GetByID(ctx context.Context, id uuid.UUID) (*ExamRegistration, error) {
 	query := `
-		SELECT id, examid, studentid, createdat, grade, passed
+		SELECT id, examid, studentid, createdat, grade, passed, absent
 		FROM exam_registrations
 		WHERE id = $1
 	`
@@ -81,6 +88,7 @@ func (r *ExamRegistrationRepository) GetByID(ctx context.Context, id uuid.UUID)
 		&reg.CreatedAt,
 		&reg.Grade,
 		&reg.Passed,
+		&reg.Absent,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -94,7 +102,7 @@ func (r *ExamRegistrationRepository) GetByID(ctx context.Context, id uuid.UUID)
 
 func (r *ExamRegistrationRepository) GetByExamID(ctx context.Context, id uuid.UUID) ([]*ExamRegistration, error) {
 	query := `
-		SELECT id, examid, studentid, createdat, grade, passed
+		SELECT id, examid, studentid, createdat, grade, passed, absent
 		FROM exam_registrations
 		WHERE examid = $1
 	`
@@ -108,7 +116,7 @@ func (r *ExamRegistrationRepository) GetByExamID(ctx context.Context, id uuid.UU
 	var regs []*ExamRegistration
 	for rows.Next() {
 		var reg ExamRegistration
-		if err := rows.Scan(&reg.ID, &reg.ExamID, &reg.StudentID, &reg.CreatedAt, &reg.Grade, &reg.Passed); err != nil {
+		if err := rows.Scan(&reg.ID, &reg.ExamID, &reg.StudentID, &reg.CreatedAt, &reg.Grade, &reg.Passed, &reg.Absent); err != nil {
 			return nil, err
 		}
 		regs = append(regs, &reg)
@@ -123,7 +131,7 @@ func (r *ExamRegistrationRepository) GetByExamID(ctx context.Context, id uuid.UU
 
 func (r *ExamRegistrationRepository) GetByStudentIDAndExamID(ctx context.Context, studentID, examID uuid.UUID) (*ExamRegistration, error) {
 	query := `
-		SELECT id, examid, studentid, createdat, grade, passed
+		SELECT id, examid, studentid, createdat, grade, passed, absent
 		FROM exam_registrations
 		WHERE studentid = $1 AND examid = $2
 	`
@@ -136,6 +144,7 @@ func (r *ExamRegistrationRepository) GetByStudentIDAndExamID(ctx context.Context
 		&reg.CreatedAt,
 		&reg.Grade,
 		&reg.Passed,
+		&reg.Absent,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -159,7 +168,7 @@ func (r *ExamRegistrationRepository) GetByStudentEmail(ctx context.Context, emai
 	}
 
 	query := `
-	SELECT id, examid, studentid, createdat, grade, passed
+	SELECT id, examid, studentid, createdat, grade, passed, absent
 	FROM exam_registrations
 	WHERE studentid = $1
 	ORDER BY createdat DESC
@@ -174,7 +183,7 @@ func (r *ExamRegistrationRepository) GetByStudentEmail(ctx context.Context, emai
 	var regs []*ExamRegistration
 	for rows.Next() {
 		var reg ExamRegistration
-		if err := rows.Scan(&reg.ID, &reg.ExamID, &reg.StudentID, &reg.CreatedAt, &reg.Grade); err != nil {
+		if err := rows.Scan(&reg.ID, &reg.ExamID, &reg.StudentID, &reg.CreatedAt, &reg.Grade, &reg.Passed, &reg.Absent); err != nil {
 			return nil, err
 		}
 		regs = append(regs, &reg)
@@ -186,19 +195,23 @@ func (r *ExamRegistrationRepository) GetByStudentEmail(ctx context.Context, emai
 func (r *ExamRegistrationRepository) EnterGrade(ctx context.Context, examID, studentID uuid.UUID, grade int) (*ExamRegistration, error) {
 
 	var existingGrade *int
+	var absent bool
 	checkQuery := `
-        SELECT grade
+        SELECT grade, absent
         FROM exam_registrations
         WHERE examid = $1 AND studentid = $2
     `
-	if err := r.db.QueryRow(ctx, checkQuery, examID, studentID).Scan(&existingGrade); err != nil {
+	if err := r.db.QueryRow(ctx, checkQuery, examID, studentID).Scan(&existingGrade, &absent); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, fmt.Errorf("registration not found for exam %s and student %s", examID, studentID)
 		}
 		return nil, err
 	}
 	if existingGrade != nil {
-		return nil, fmt.Errorf("Ocena je vec upisana za ovaj ispit")
+		return nil, fmt.Errorf("Ocena je vec upisana za ovaj ispit, izmjena ide preko zahtjeva za izmjenu ocjene")
+	}
+	if absent {
+		return nil, fmt.Errorf("Student je oznacen kao odsutan sa ovog ispita")
 	}
 
 	query := `
@@ -206,7 +219,7 @@ func (r *ExamRegistrationRepository) EnterGrade(ctx context.Context, examID, stu
         SET grade = $1,
             passed = CASE WHEN $1 >= 6 THEN TRUE ELSE FALSE END
         WHERE examid = $2 AND studentid = $3
-        RETURNING id, examid, studentid, createdat, grade, passed
+        RETURNING id, examid, studentid, createdat, grade, passed, absent
     `
 
 	var reg ExamRegistration
@@ -217,6 +230,7 @@ func (r *ExamRegistrationRepository) EnterGrade(ctx context.Context, examID, stu
 		&reg.CreatedAt,
 		&reg.Grade,
 		&reg.Passed,
+		&reg.Absent,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -274,3 +288,72 @@ func (r *ExamRegistrationRepository) EnterGrade(ctx context.Context, examID, stu
 
 	return &reg, nil
 }
+
+// Withdraw brise prijavu ispita dok za nju nije upisan ishod (ocjena ili izostanak)
+func (r *ExamRegistrationRepository) Withdraw(ctx context.Context, examID, studentID uuid.UUID) (*ExamRegistration, error) {
+	query := `
+		DELETE FROM exam_registrations
+		WHERE examid = $1 AND studentid = $2 AND grade IS NULL AND NOT absent
+		RETURNING id, examid, studentid, createdat, grade, passed, absent
+	`
+
+	var reg ExamRegistration
+	err := r.db.QueryRow(ctx, query, examID, studentID).Scan(
+		&reg.ID,
+		&reg.ExamID,
+		&reg.StudentID,
+		&reg.CreatedAt,
+		&reg.Grade,
+		&reg.Passed,
+		&reg.Absent,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, r.outcomeErr(ctx, examID, studentID)
+		}
+		return nil, err
+	}
+
+	return &reg, nil
+}
+
+// MarkAbsent upisuje izostanak sa ispita; ne mijenja ESPB ni status studenta
+func (r *ExamRegistrationRepository) MarkAbsent(ctx context.Context, examID, studentID uuid.UUID) (*ExamRegistration, error) {
+	query := `
+		UPDATE exam_registrations
+		SET absent = TRUE, passed = FALSE
+		WHERE examid = $1 AND studentid = $2 AND grade IS NULL AND NOT absent
+		RETURNING id, examid, studentid, createdat, grade, passed, absent
+	`
+
+	var reg ExamRegistration
+	err := r.db.QueryRow(ctx, query, examID, studentID).Scan(
+		&reg.ID,
+		&reg.ExamID,
+		&reg.StudentID,
+		&reg.CreatedAt,
+		&reg.Grade,
+		&reg.Passed,
+		&reg.Absent,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, r.outcomeErr(ctx, examID, studentID)
+		}
+		return nil, err
+	}
+
+	return &reg, nil
+}
+
+// razlikuje nepostojecu prijavu od prijave koja vec ima ishod
+func (r *ExamRegistrationRepository) outcomeErr(ctx context.Context, examID, studentID uuid.UUID) error {
+	existing, err := r.GetByStudentIDAndExamID(ctx, studentID, examID)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return ErrExamRegistrationNotFound
+	}
+	return ErrExamOutcomeRecorded
+}
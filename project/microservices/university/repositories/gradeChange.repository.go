@@ -0,0 +1,296 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type GradeChangeStatus string
+
+const (
+	GradeChangePending  GradeChangeStatus = "PENDING"
+	GradeChangeApproved GradeChangeStatus = "APPROVED"
+	GradeChangeRejected GradeChangeStatus = "REJECTED"
+)
+
+var (
+	ErrGradeChangeNotFound   = errors.New("grade change request not found")
+	ErrGradeChangeNotGraded  = errors.New("exam registration has no grade or absence to change")
+	ErrGradeChangeSameGrade  = errors.New("new grade is the same as the current grade")
+	ErrGradeChangePending    = errors.New("a grade change request is already pending for this registration")
+	ErrGradeChangeDecided    = errors.New("grade change request has already been decided")
+	ErrGradeChangeStale      = errors.New("grade has changed since the request was made")
+	ErrGradeChangeCredential = errors.New("student has an active graduation credential, revoke it first")
+)
+
+// GradeChange je zahtjev za izmjenu upisane ocjene; OldGrade je nil kada se
+// ispravlja oznaka izostanka
+type GradeChange struct {
+	ID             uuid.UUID         `json:"id"`
+	RegistrationID uuid.UUID         `json:"registrationid"`
+	ExamID         uuid.UUID         `json:"examid"`
+	StudentID      uuid.UUID         `json:"studentid"`
+	OldGrade       *int              `json:"oldgrade"`
+	NewGrade       int               `json:"newgrade"`
+	Reason         string            `json:"reason"`
+	Status         GradeChangeStatus `json:"status"`
+	RequestedBy    *uuid.UUID        `json:"requestedby"`
+	RequestedAt    time.Time         `json:"requestedat"`
+	DecidedBy      *uuid.UUID        `json:"decidedby"`
+	DecidedAt      *time.Time        `json:"decidedat"`
+	DecisionNote   *string           `json:"decisionnote"`
+}
+
+// GradeChangeResult je stanje prijave i studenta nakon odobrene izmjene
+type GradeChangeResult struct {
+	Request      *GradeChange      `json:"request"`
+	Registration *ExamRegistration `json:"registration"`
+	StudentEcts  int               `json:"studentects"`
+	Status       StudentStatus     `json:"studentstatus"`
+}
+
+// GradeChangeFilter suzava listu zahtjeva; prazna polja se ne primjenjuju
+type GradeChangeFilter struct {
+	Status    GradeChangeStatus
+	ExamID    *uuid.UUID
+	StudentID *uuid.UUID
+}
+
+type GradeChangeRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewGradeChangeRepository(db *pgxpool.Pool) *GradeChangeRepository {
+	return &GradeChangeRepository{db: db}
+}
+
+const gradeChangeSelect = `
+	SELECT g.id, g.registration_id, er.examid, er.studentid, g.old_grade, g.new_grade, g.reason, g.status,
+		g.requested_by, g.requested_at, g.decided_by, g.decided_at, g.decision_note
+	FROM grade_change_requests g
+	JOIN exam_registrations er ON er.id = g.registration_id
+`
+
+func scanGradeChange(row pgx.Row) (*GradeChange, error) {
+	var g GradeChange
+	err := row.Scan(&g.ID, &g.RegistrationID, &g.ExamID, &g.StudentID, &g.OldGrade, &g.NewGrade, &g.Reason, &g.Status,
+		&g.RequestedBy, &g.RequestedAt, &g.DecidedBy, &g.DecidedAt, &g.DecisionNote)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrGradeChangeNotFound
+		}
+		return nil, err
+	}
+	return &g, nil
+}
+
+// Create otvara zahtjev za izmjenu ocjene; trenutna ocjena (ili izostanak) se
+// pamti kao stara
+func (r *GradeChangeRepository) Create(ctx context.Context, examID, studentID uuid.UUID, newGrade int, reason string, requestedBy *uuid.UUID) (*GradeChange, error) {
+	var regID uuid.UUID
+	var grade *int
+	var absent bool
+	err := r.db.QueryRow(ctx, `SELECT id, grade, absent FROM exam_registrations WHERE examid = $1 AND studentid = $2`, examID, studentID).
+		Scan(&regID, &grade, &absent)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrExamRegistrationNotFound
+		}
+		return nil, err
+	}
+	if grade == nil && !absent {
+		return nil, ErrGradeChangeNotGraded
+	}
+	if grade != nil && *grade == newGrade {
+		return nil, ErrGradeChangeSameGrade
+	}
+
+	var id uuid.UUID
+	err = r.db.QueryRow(ctx, `
+		INSERT INTO grade_change_requests (registration_id, old_grade, new_grade, reason, requested_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`, regID, grade, newGrade, reason, requestedBy).Scan(&id)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrGradeChangePending
+		}
+		return nil, err
+	}
+	return r.GetByID(ctx, id)
+}
+
+func (r *GradeChangeRepository) GetByID(ctx context.Context, id uuid.UUID) (*GradeChange, error) {
+	return scanGradeChange(r.db.QueryRow(ctx, gradeChangeSelect+` WHERE g.id = $1`, id))
+}
+
+// List vraca zahtjeve (i istoriju izmjena) od najnovijeg
+func (r *GradeChangeRepository) List(ctx context.Context, f GradeChangeFilter) ([]*GradeChange, error) {
+	query := gradeChangeSelect + `
+		WHERE ($1 = '' OR g.status = $1)
+		  AND ($2::uuid IS NULL OR er.examid = $2)
+		  AND ($3::uuid IS NULL OR er.studentid = $3)
+		ORDER BY g.requested_at DESC, g.id
+	`
+	rows, err := r.db.Query(ctx, query, string(f.Status), f.ExamID, f.StudentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []*GradeChange{}
+	for rows.Next() {
+		g, err := scanGradeChange(rows)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, g)
+	}
+	return changes, rows.Err()
+}
+
+// Approve upisuje novu ocjenu (i brise oznaku izostanka) i u istoj transakciji usaglasava passed, ESPB
+// studenta (ponovo sabrane iz polozenih predmeta, kao u prepisu) i status
+// diplomiranja
+func (r *GradeChangeRepository) Approve(ctx context.Context, id uuid.UUID, decidedBy *uuid.UUID, note *string) (*GradeChangeResult, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	g, err := scanGradeChange(tx.QueryRow(ctx, gradeChangeSelect+` WHERE g.id = $1 FOR UPDATE OF g`, id))
+	if err != nil {
+		return nil, err
+	}
+	if g.Status != GradeChangePending {
+		return nil, ErrGradeChangeDecided
+	}
+
+	var grade *int
+	var absent bool
+	err = tx.QueryRow(ctx, `SELECT grade, absent FROM exam_registrations WHERE id = $1 FOR UPDATE`, g.RegistrationID).Scan(&grade, &absent)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case g.OldGrade == nil && (grade != nil || !absent),
+		g.OldGrade != nil && (grade == nil || *grade != *g.OldGrade):
+		return nil, ErrGradeChangeStale
+	}
+
+	reg := &ExamRegistration{}
+	err = tx.QueryRow(ctx, `
+		UPDATE exam_registrations
+		SET grade = $1,
+		    passed = CASE WHEN $1 >= 6 THEN TRUE ELSE FALSE END,
+		    absent = FALSE
+		WHERE id = $2
+		RETURNING id, examid, studentid, createdat, grade, passed, absent
+	`, g.NewGrade, g.RegistrationID).Scan(&reg.ID, &reg.ExamID, &reg.StudentID, &reg.CreatedAt, &reg.Grade, &reg.Passed, &reg.Absent)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &GradeChangeResult{Registration: reg}
+	var status *string
+	var required *int
+	err = tx.QueryRow(ctx, `
+		SELECT u.status,
+			(SELECT p.ects::int
+			 FROM exams e
+			 JOIN courses c ON e.courseid::uuid = c.id
+			 JOIN singleton p ON p.id = COALESCE(u.singleton_id, c.singleton_id)
+			 WHERE e.id = $2)
+		FROM users u
+		WHERE u.id = $1
+		FOR UPDATE OF u
+	`, g.StudentID, g.ExamID).Scan(&status, &required)
+	if err != nil {
+		return nil, err
+	}
+	// predmet se racuna jednom bez obzira na broj polozenih rokova, pa ispravka
+	// jednog roka ne mijenja ESPB ako je predmet polozen i u drugom roku
+	err = tx.QueryRow(ctx, `
+		UPDATE users
+		SET ects = (SELECT COALESCE(SUM(p.ects), 0) FROM (`+passedCourses+`) p)::text
+		WHERE id = $1
+		RETURNING ects::int
+	`, g.StudentID).Scan(&result.StudentEcts)
+	if err != nil {
+		return nil, err
+	}
+
+	// status diplomiranja se racuna iz novog zbira ESPB
+	if status != nil {
+		result.Status = StudentStatus(*status)
+	}
+	graduated := result.Status == StudentGraduated
+	switch {
+	case required != nil && result.StudentEcts >= *required && !graduated:
+		result.Status = StudentGraduated
+	case graduated && (required == nil || result.StudentEcts < *required):
+		// diploma izdata na osnovu stare ocjene mora prvo biti opozvana
+		var active bool
+		err := tx.QueryRow(ctx, `
+			SELECT EXISTS (SELECT 1 FROM graduation_credentials WHERE student_id = $1 AND revoked_at IS NULL)
+		`, g.StudentID).Scan(&active)
+		if err != nil {
+			return nil, err
+		}
+		if active {
+			return nil, ErrGradeChangeCredential
+		}
+		result.Status = StudentActive
+	}
+	if result.Status != "" && (status == nil || string(result.Status) != *status) {
+		if _, err := tx.Exec(ctx, `UPDATE users SET status = $1 WHERE id = $2`, string(result.Status), g.StudentID); err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE grade_change_requests
+		SET status = 'APPROVED', decided_by = $2, decided_at = now(), decision_note = $3
+		WHERE id = $1
+	`, id, decidedBy, note)
+	if err != nil {
+		return nil, err
+	}
+	if result.Request, err = scanGradeChange(tx.QueryRow(ctx, gradeChangeSelect+` WHERE g.id = $1`, id)); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Reject zatvara zahtjev bez izmjene ocjene
+func (r *GradeChangeRepository) Reject(ctx context.Context, id uuid.UUID, decidedBy *uuid.UUID, note string) (*GradeChange, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE grade_change_requests
+		SET status = 'REJECTED', decided_by = $2, decided_at = now(), decision_note = $3
+		WHERE id = $1 AND status = 'PENDING'
+	`, id, decidedBy, note)
+	if err != nil {
+		return nil, err
+	}
+
+	g, err := r.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, ErrGradeChangeDecided
+	}
+	return g, nil
+}
@@ -0,0 +1,110 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrImportConflict = errors.New("a student with the same email or index number was created during import")
+
+// ImportStudent je validan red iz fajla za masovni upis studenata
+type ImportStudent struct {
+	FullName  string
+	Email     string
+	IndexNo   string
+	ProgramID uuid.UUID
+	Password  string
+	// hash pozivnog tokena (jednokratan link za postavljanje lozinke), opciono
+	InviteHash      string
+	InviteExpiresAt time.Time
+}
+
+// ExistingEmails vraca email adrese (mala slova) koje vec koristi neki nalog
+func (r *StudentRepository) ExistingEmails(ctx context.Context, emails []string) (map[string]bool, error) {
+	const q = `SELECT lower(email) FROM users WHERE lower(email) = ANY($1)`
+	return r.existing(ctx, q, emails)
+}
+
+// ExistingIndexNumbers vraca brojeve indeksa koje vec ima neki student
+func (r *StudentRepository) ExistingIndexNumbers(ctx context.Context, indices []string) (map[string]bool, error) {
+	const q = `SELECT indexno FROM users WHERE role = 'student' AND indexno = ANY($1)`
+	return r.existing(ctx, q, indices)
+}
+
+func (r *StudentRepository) existing(ctx context.Context, q string, values []string) (map[string]bool, error) {
+	found := map[string]bool{}
+	if len(values) == 0 {
+		return found, nil
+	}
+	rows, err := r.db.Query(ctx, q, values)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		found[v] = true
+	}
+	return found, rows.Err()
+}
+
+// Import upisuje sve studente u jednoj transakciji (ili nijednog) i vraca
+// njihove ID-jeve redom; uz pozivni token se pravi i token za postavljanje lozinke
+func (r *StudentRepository) Import(ctx context.Context, studs []ImportStudent) ([]uuid.UUID, error) {
+	hashes := make([][]byte, len(studs))
+	for i, s := range studs {
+		hash, err := bcrypt.GenerateFromPassword([]byte(s.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+		hashes[i] = hash
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	const ins = `
+		INSERT INTO users (fullname, email, password, status, indexno, role, singleton_id)
+		VALUES ($1, $2, $3, $4, $5, 'student', $6)
+		RETURNING id
+	`
+	const invite = `
+		INSERT INTO password_reset_tokens (id, user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`
+	ids := make([]uuid.UUID, len(studs))
+	for i, s := range studs {
+		// auth servis trazi nalog po emailu malim slovima
+		email := strings.ToLower(strings.TrimSpace(s.Email))
+		if err := tx.QueryRow(ctx, ins, s.FullName, email, hashes[i], StudentActive, s.IndexNo, s.ProgramID).Scan(&ids[i]); err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+				return nil, ErrImportConflict
+			}
+			return nil, err
+		}
+		if s.InviteHash == "" {
+			continue
+		}
+		if _, err := tx.Exec(ctx, invite, uuid.New(), ids[i], s.InviteHash, s.InviteExpiresAt); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
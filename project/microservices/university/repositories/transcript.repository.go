@@ -0,0 +1,210 @@
+package repositories
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var (
+	ErrTranscriptStudentNotFound = errors.New("student not found")
+	ErrTranscriptNotFound        = errors.New("transcript not found")
+)
+
+// TranscriptEntry je polozen predmet; RunningEcts je zbir ESPB zakljucno sa njim
+type TranscriptEntry struct {
+	CourseCode  string    `json:"coursecode"`
+	CourseName  string    `json:"coursename"`
+	Ects        int       `json:"ects"`
+	Grade       int       `json:"grade"`
+	Date        time.Time `json:"date"`
+	RunningEcts int       `json:"runningects"`
+}
+
+// Transcript je prepis ocjena studenta; prosjek je ponderisan brojem ESPB
+type Transcript struct {
+	StudentID       uuid.UUID         `json:"studentid"`
+	FullName        string            `json:"fullname"`
+	IndexNo         *string           `json:"indexno"`
+	Program         *string           `json:"program"`
+	Entries         []TranscriptEntry `json:"entries"`
+	TotalEcts       int               `json:"totalects"`
+	WeightedAverage *float64          `json:"weightedaverage"`
+
+	VerificationCode string    `json:"verificationcode,omitempty"`
+	ContentHash      string    `json:"contenthash,omitempty"`
+	IssuedAt         time.Time `json:"issuedat"`
+}
+
+type TranscriptRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewTranscriptRepository(db *pgxpool.Pool) *TranscriptRepository {
+	return &TranscriptRepository{db: db}
+}
+
+// passedCourses su polozeni predmeti studenta ($1), svaki jednom; za predmet
+// polozen vise puta vazi posljednji polozeni rok
+const passedCourses = `
+	SELECT DISTINCT ON (c.id) c.code, c.name, c.ects::int AS ects, er.grade, e.examtime
+	FROM exam_registrations er
+	JOIN exams e ON er.examid = e.id
+	JOIN courses c ON e.courseid::uuid = c.id
+	WHERE er.studentid = $1 AND er.passed AND er.grade IS NOT NULL
+	ORDER BY c.id, e.examtime DESC
+`
+
+// Assemble sklapa prepis od polozenih ispita studenta; za predmet polozen
+// vise puta (npr. ponistena ocjena) vazi posljednji polozeni rok
+func (r *TranscriptRepository) Assemble(ctx context.Context, studentID uuid.UUID) (*Transcript, error) {
+	t := Transcript{StudentID: studentID, Entries: []TranscriptEntry{}}
+
+	const student = `
+		SELECT u.fullname, u.indexno, s.name
+		FROM users u
+		LEFT JOIN singleton s ON u.singleton_id = s.id
+		WHERE u.id = $1 AND u.role = 'student'
+	`
+	if err := r.db.QueryRow(ctx, student, studentID).Scan(&t.FullName, &t.IndexNo, &t.Program); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrTranscriptStudentNotFound
+		}
+		return nil, err
+	}
+
+	rows, err := r.db.Query(ctx, passedCourses, studentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var e TranscriptEntry
+		if err := rows.Scan(&e.CourseCode, &e.CourseName, &e.Ects, &e.Grade, &e.Date); err != nil {
+			return nil, err
+		}
+		t.Entries = append(t.Entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(t.Entries, func(i, j int) bool {
+		if t.Entries[i].Date.Equal(t.Entries[j].Date) {
+			return t.Entries[i].CourseCode < t.Entries[j].CourseCode
+		}
+		return t.Entries[i].Date.Before(t.Entries[j].Date)
+	})
+
+	weighted := 0
+	for i := range t.Entries {
+		e := &t.Entries[i]
+		t.TotalEcts += e.Ects
+		weighted += e.Grade * e.Ects
+		e.RunningEcts = t.TotalEcts
+	}
+	if t.TotalEcts > 0 {
+		avg := math.Round(float64(weighted)/float64(t.TotalEcts)*100) / 100
+		t.WeightedAverage = &avg
+	}
+	return &t, nil
+}
+
+// Issue biljezi izdati prepis i dodjeljuje mu verifikacioni kod; ako se
+// sadrzaj nije promijenio od posljednjeg izdavanja, vraca se postojeci kod
+func (r *TranscriptRepository) Issue(ctx context.Context, t *Transcript, issuedBy *uuid.UUID) error {
+	content, err := json.Marshal(struct {
+		StudentID       uuid.UUID         `json:"studentid"`
+		Entries         []TranscriptEntry `json:"entries"`
+		TotalEcts       int               `json:"totalects"`
+		WeightedAverage *float64          `json:"weightedaverage"`
+	}{t.StudentID, t.Entries, t.TotalEcts, t.WeightedAverage})
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(content)
+	t.ContentHash = hex.EncodeToString(sum[:])
+
+	const latest = `
+		SELECT verification_code, issued_at FROM transcripts
+		WHERE student_id = $1 AND content_hash = $2
+		ORDER BY issued_at DESC LIMIT 1
+	`
+	err = r.db.QueryRow(ctx, latest, t.StudentID, t.ContentHash).Scan(&t.VerificationCode, &t.IssuedAt)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+
+	const ins = `
+		INSERT INTO transcripts (id, student_id, verification_code, content_hash, document, issued_by, issued_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	for attempt := 0; ; attempt++ {
+		code, err := newVerificationCode()
+		if err != nil {
+			return err
+		}
+		t.VerificationCode = code
+		t.IssuedAt = time.Now().UTC().Truncate(time.Second)
+
+		doc, err := json.Marshal(t)
+		if err != nil {
+			return err
+		}
+		_, err = r.db.Exec(ctx, ins, uuid.New(), t.StudentID, code, t.ContentHash, doc, issuedBy, t.IssuedAt)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" && attempt < 3 {
+			continue // kolizija koda
+		}
+		return err
+	}
+}
+
+// GetByCode vraca prepis sacuvan pri izdavanju
+func (r *TranscriptRepository) GetByCode(ctx context.Context, code string) (*Transcript, error) {
+	var doc []byte
+	err := r.db.QueryRow(ctx, `SELECT document FROM transcripts WHERE verification_code = $1`, code).Scan(&doc)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrTranscriptNotFound
+		}
+		return nil, err
+	}
+	var t Transcript
+	if err := json.Unmarshal(doc, &t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// newVerificationCode pravi kod oblika XXXX-XXXX-XXXX (bez slicnih znakova)
+func newVerificationCode() (string, error) {
+	const alphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	b := make([]byte, 0, 14)
+	for i := 0; i < 12; i++ {
+		if i > 0 && i%4 == 0 {
+			b = append(b, '-')
+		}
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(alphabet))))
+		if err != nil {
+			return "", err
+		}
+		b = append(b, alphabet[n.Int64()])
+	}
+	return string(b), nil
+}
@@ -0,0 +1,10 @@
+-- brojac neuspjesnih prijava po nalogu (kind = 'account', key = email)
+-- i po IP adresi (kind = 'ip', key = adresa)
+CREATE TABLE IF NOT EXISTS login_throttles (
+    kind TEXT NOT NULL,
+    key TEXT NOT NULL,
+    failures INT NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    locked_until TIMESTAMPTZ NULL,
+    PRIMARY KEY (kind, key)
+);
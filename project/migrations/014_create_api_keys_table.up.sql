@@ -0,0 +1,27 @@
+-- API kljucevi poslodavaca za integraciju sa berzom poslova (employmentOffice);
+-- cuva se samo hash kljuca, a prefix sluzi za prepoznavanje kljuca u listi
+CREATE TABLE IF NOT EXISTS api_keys (
+    id UUID PRIMARY KEY,
+    employer_id UUID NOT NULL,
+    name TEXT NOT NULL,
+    prefix TEXT NOT NULL UNIQUE,
+    key_hash TEXT NOT NULL UNIQUE,
+    permissions TEXT[] NOT NULL,
+    created_by UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NULL,
+    revoked_at TIMESTAMPTZ NULL,
+    rotated_from UUID NULL REFERENCES api_keys(id) ON DELETE SET NULL,
+    last_used_at TIMESTAMPTZ NULL,
+    usage_count BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_employer_id ON api_keys(employer_id);
+
+-- dnevni brojac zahtjeva po kljucu
+CREATE TABLE IF NOT EXISTS api_key_usage (
+    key_id UUID NOT NULL REFERENCES api_keys(id) ON DELETE CASCADE,
+    day DATE NOT NULL,
+    requests BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (key_id, day)
+);
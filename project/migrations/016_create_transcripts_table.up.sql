@@ -0,0 +1,13 @@
+-- izdati prepisi ocjena; verifikacioni kod sa dokumenta vodi do sacuvanog
+-- sadrzaja, pa treca strana (npr. poslodavac) moze provjeriti autenticnost
+CREATE TABLE IF NOT EXISTS transcripts (
+    id UUID PRIMARY KEY,
+    student_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    verification_code TEXT NOT NULL UNIQUE,
+    content_hash TEXT NOT NULL,
+    document JSONB NOT NULL,
+    issued_by UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+    issued_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_transcripts_student_id ON transcripts(student_id, issued_at DESC);
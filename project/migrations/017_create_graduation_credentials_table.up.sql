@@ -0,0 +1,28 @@
+-- javni kljucevi kojima univerzitet potpisuje diplome; stari kljucevi ostaju
+-- objavljeni kako bi ranije izdate diplome i dalje prolazile provjeru
+CREATE TABLE IF NOT EXISTS credential_signing_keys (
+    kid TEXT PRIMARY KEY,
+    public_key TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- izdate diplome (potpisani kredencijali o zavrsenim studijama)
+CREATE TABLE IF NOT EXISTS graduation_credentials (
+    id UUID PRIMARY KEY,
+    student_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kid TEXT NOT NULL REFERENCES credential_signing_keys(kid),
+    token TEXT NOT NULL,
+    program TEXT NULL,
+    graduation_date DATE NOT NULL,
+    final_average DOUBLE PRECISION NULL,
+    ects INT NOT NULL,
+    issued_by UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+    issued_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    revoked_at TIMESTAMPTZ NULL,
+    revoked_by UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+    revocation_reason TEXT NULL
+);
+
+-- student ima najvise jednu vazecu diplomu
+CREATE UNIQUE INDEX IF NOT EXISTS idx_graduation_credentials_active
+    ON graduation_credentials(student_id) WHERE revoked_at IS NULL;
@@ -0,0 +1,44 @@
+-- akademski kalendar: skolske godine, semestri sa rokom za upis kurseva i
+-- ispitni rokovi (npr. januarski, junski, septembarski) sa rokom za prijavu ispita
+CREATE TABLE IF NOT EXISTS academic_years (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL UNIQUE,
+    starts_on DATE NOT NULL,
+    ends_on DATE NOT NULL,
+    CHECK (starts_on < ends_on)
+);
+
+CREATE TABLE IF NOT EXISTS semesters (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    academic_year_id UUID NOT NULL REFERENCES academic_years(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    starts_on DATE NOT NULL,
+    ends_on DATE NOT NULL,
+    registration_opens_at TIMESTAMPTZ NOT NULL,
+    registration_closes_at TIMESTAMPTZ NOT NULL,
+    UNIQUE (academic_year_id, name),
+    CHECK (starts_on < ends_on),
+    CHECK (registration_opens_at < registration_closes_at)
+);
+
+CREATE TABLE IF NOT EXISTS exam_periods (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    academic_year_id UUID NOT NULL REFERENCES academic_years(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    starts_on DATE NOT NULL,
+    ends_on DATE NOT NULL,
+    registration_opens_at TIMESTAMPTZ NOT NULL,
+    registration_closes_at TIMESTAMPTZ NOT NULL,
+    UNIQUE (academic_year_id, name),
+    CHECK (starts_on <= ends_on),
+    CHECK (registration_opens_at < registration_closes_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_semesters_registration ON semesters(registration_opens_at, registration_closes_at);
+CREATE INDEX IF NOT EXISTS idx_exam_periods_dates ON exam_periods(starts_on, ends_on);
+
+-- ispit pripada ispitnom roku; stari ispiti ostaju bez roka
+ALTER TABLE exams
+ADD COLUMN IF NOT EXISTS exam_period_id UUID NULL REFERENCES exam_periods(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_exams_exam_period_id ON exams(exam_period_id);
@@ -0,0 +1,27 @@
+-- preduslovi kurseva (usmjereni graf bez ciklusa; ciklusi se odbijaju u servisu)
+CREATE TABLE IF NOT EXISTS course_prerequisites (
+    course_id UUID NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+    prerequisite_id UUID NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+    PRIMARY KEY (course_id, prerequisite_id),
+    CHECK (course_id <> prerequisite_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_course_prerequisites_prerequisite_id ON course_prerequisites(prerequisite_id);
+
+-- plan studijskog programa: obavezni/izborni kursevi i godina studija
+CREATE TABLE IF NOT EXISTS program_courses (
+    singleton_id UUID NOT NULL REFERENCES singleton(id) ON DELETE CASCADE,
+    course_id UUID NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+    mandatory BOOLEAN NOT NULL DEFAULT TRUE,
+    year_of_study SMALLINT NULL CHECK (year_of_study BETWEEN 1 AND 6),
+    PRIMARY KEY (singleton_id, course_id)
+);
+
+-- postojeci kursevi ulaze u plan svog programa kao obavezni, bez godine studija
+INSERT INTO program_courses (singleton_id, course_id)
+SELECT singleton_id, id FROM courses WHERE singleton_id IS NOT NULL
+ON CONFLICT DO NOTHING;
+
+-- godina studija studenta (NULL = nije poznata, bez ogranicenja po godini)
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS year_of_study SMALLINT NULL CHECK (year_of_study BETWEEN 1 AND 6);
@@ -0,0 +1,15 @@
+-- kapacitet kursa (NULL = bez ogranicenja) i lista cekanja kada je kurs popunjen
+ALTER TABLE courses
+ADD COLUMN IF NOT EXISTS capacity INT NULL CHECK (capacity >= 0);
+
+CREATE TABLE IF NOT EXISTS course_waitlist (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    course_id UUID NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+    student_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (course_id, student_id)
+);
+
+-- redoslijed na listi cekanja je redoslijed prijave
+CREATE INDEX IF NOT EXISTS idx_course_waitlist_course_id ON course_waitlist(course_id, created_at, id);
+
@@ -0,0 +1,3 @@
+-- izostanak sa ispita je poseban ishod prijave (bez ocjene), razlicit od pada
+ALTER TABLE exam_registrations
+ADD COLUMN IF NOT EXISTS absent BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,22 @@
+-- zahtjevi za izmjenu upisane ocjene; odobrava ih administrator fakulteta, a
+-- odobreni i odbijeni zahtjevi ostaju kao istorija izmjena ocjene
+CREATE TABLE IF NOT EXISTS grade_change_requests (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    registration_id UUID NOT NULL REFERENCES exam_registrations(id) ON DELETE CASCADE,
+    old_grade INT NOT NULL,
+    new_grade INT NOT NULL CHECK (new_grade BETWEEN 1 AND 10),
+    reason TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'PENDING' CHECK (status IN ('PENDING', 'APPROVED', 'REJECTED')),
+    requested_by UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+    requested_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    decided_by UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+    decided_at TIMESTAMPTZ NULL,
+    decision_note TEXT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_grade_change_requests_registration_id
+    ON grade_change_requests(registration_id, requested_at);
+
+-- za jednu prijavu moze postojati najvise jedan zahtjev na cekanju
+CREATE UNIQUE INDEX IF NOT EXISTS idx_grade_change_requests_pending
+    ON grade_change_requests(registration_id) WHERE status = 'PENDING';
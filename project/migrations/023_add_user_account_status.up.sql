@@ -0,0 +1,19 @@
+-- stanje naloga (prijava, deaktivacija, potvrda email adrese) ima svoju kolonu;
+-- users.status ostaje zivotni ciklus studenta koji vodi univerzitet (ACTIVE, GRADUATED)
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS account_status TEXT NOT NULL DEFAULT 'active'
+    CHECK (account_status IN ('active', 'disabled', 'pending_verification'));
+
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS account_status_changed_at TIMESTAMPTZ NULL;
+
+-- stanja koja je auth servis do sada upisivao u users.status
+UPDATE users
+SET account_status = status,
+    account_status_changed_at = status_changed_at
+WHERE status IN ('disabled', 'pending_verification');
+
+-- studenti kojima je auth prepisao status vracaju se u zivotni ciklus studenta
+UPDATE users
+SET status = 'ACTIVE'
+WHERE role = 'student' AND status IN ('active', 'disabled', 'pending_verification');
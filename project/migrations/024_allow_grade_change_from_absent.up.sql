@@ -0,0 +1,3 @@
+-- zahtjevom za izmjenu ocjene se ispravlja i pogresna oznaka izostanka;
+-- stara ocjena je tada NULL
+ALTER TABLE grade_change_requests ALTER COLUMN old_grade DROP NOT NULL;